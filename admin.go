@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/umateedev/assessment/attachment"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/expense"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/seed"
+)
+
+var log = logging.For("main")
+
+var commands = map[string]struct {
+	usage string
+	run   func(args []string) error
+}{
+	"serve":   {"[--migrate=false]", serve},
+	"migrate": {"", migrateCommand},
+	"seed":    {"[--count N] [--months N] [--seed N]", seedCommand},
+	"user":    {"create|reset-password USERNAME [--password P] | create-api-key USERNAME --name N", userCommand},
+	"purge":   {"--before YYYY-MM-DD | --all [--dry-run]", purgeCommand},
+}
+
+// main runs the subcommand named by the first argument, serve by default.
+func main() {
+	slog.SetDefault(logging.For(""))
+
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	err := cmd.run(args)
+	database.Close()
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		log.Error("Command failed", "command", name, "error", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: assessment COMMAND [ARGS]")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "Every command also takes --config FILE, --listen, --database-url and --auth.")
+}
+
+func migrateCommand(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	loader := configFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := loader.load()
+	if err != nil {
+		return err
+	}
+	cfg.Log.apply()
+
+	if err := connect(cfg, true); err != nil {
+		return err
+	}
+	log.Info("Database schema is up to date")
+	return nil
+}
+
+func seedCommand(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	loader := configFlags(fs)
+	count := fs.Int("count", 500, "number of expenses to create")
+	months := fs.Int("months", 6, "spread the expenses over this many months before now")
+	seedValue := fs.Int64("seed", time.Now().UnixNano(), "random seed, for repeatable data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errors.New("count must be positive")
+	}
+
+	cfg, err := loader.load()
+	if err != nil {
+		return err
+	}
+	cfg.Log.apply()
+	if err := connect(cfg, true); err != nil {
+		return err
+	}
+
+	closeCache, err := cfg.Cache.shared()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+
+	expenses := seed.Generate(rand.New(rand.NewSource(*seedValue)), *count, *months, time.Now())
+	if err := seed.Insert(context.Background(), expenses); err != nil {
+		return err
+	}
+	log.Info("Created expenses", "count", len(expenses), "seed", *seedValue)
+	return nil
+}
+
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user create|reset-password USERNAME [--password P] | create-api-key USERNAME --name N")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("user "+action, flag.ContinueOnError)
+	loader := configFlags(fs)
+	password := fs.String("password", "", "password, read from stdin when omitted")
+	keyName := fs.String("name", "", "what the API key is for")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: user %s USERNAME [--password P]", action)
+	}
+	username := fs.Arg(0)
+
+	if len(*password) == 0 && action != "create-api-key" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && len(line) == 0 {
+			return errors.New("no password given")
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	cfg, err := loader.load()
+	if err != nil {
+		return err
+	}
+	cfg.Log.apply()
+	if err := connect(cfg, true); err != nil {
+		return err
+	}
+
+	switch action {
+	case "create":
+		id, err := auth.CreateUser(context.Background(), username, *password)
+		if err != nil {
+			return err
+		}
+		log.Info("Created user", "username", username, "id", id)
+	case "reset-password":
+		if err := auth.ResetPassword(context.Background(), username, *password); err != nil {
+			return err
+		}
+		log.Info("Reset password", "username", username)
+	case "create-api-key":
+		if len(*keyName) == 0 {
+			return errors.New("user create-api-key: --name is required")
+		}
+		key, err := auth.CreateAPIKey(context.Background(), username, *keyName)
+		if err != nil {
+			return err
+		}
+		log.Info("Created API key", "username", username, "name", *keyName)
+		// The key is only shown here, so print it rather than log it.
+		fmt.Println(key)
+	default:
+		return fmt.Errorf("unknown user command %q", action)
+	}
+	return nil
+}
+
+func purgeCommand(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	loader := configFlags(fs)
+	before := fs.String("before", "", "delete expenses created before this date (YYYY-MM-DD, UTC)")
+	all := fs.Bool("all", false, "delete every expense")
+	dryRun := fs.Bool("dry-run", false, "only count the expenses that would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var t time.Time
+	switch {
+	case *all && len(*before) > 0:
+		return errors.New("use either --before or --all")
+	case len(*before) > 0:
+		var err error
+		if t, err = time.Parse("2006-01-02", *before); err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+	case !*all:
+		return errors.New("purge needs --before YYYY-MM-DD or --all")
+	}
+
+	cfg, err := loader.load()
+	if err != nil {
+		return err
+	}
+	cfg.Log.apply()
+	if err := connect(cfg, true); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		var n int
+		query, qargs := "SELECT count(*) FROM expenses", []interface{}{}
+		if !t.IsZero() {
+			query, qargs = query+" WHERE created_at < $1", []interface{}{t}
+		}
+		if err := database.Db.QueryRowContext(context.Background(), query, qargs...).Scan(&n); err != nil {
+			return err
+		}
+		log.Info("Would delete expenses", "count", n)
+		return nil
+	}
+
+	if err := attachment.InitStorage(cfg.Attachments.attachment()); err != nil {
+		return err
+	}
+	closeCache, err := cfg.Cache.shared()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+	n, err := expense.PurgeBefore(context.Background(), t)
+	if err != nil {
+		return fmt.Errorf("deleted %d expenses, then: %w", n, err)
+	}
+	log.Info("Deleted expenses", "count", n)
+	return nil
+}
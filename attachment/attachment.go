@@ -0,0 +1,29 @@
+package attachment
+
+import (
+	"time"
+
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("attachment")
+
+type Attachment struct {
+	Id          int       `json:"id"`
+	ExpenseId   int       `json:"expense_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Sha256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Files are stored once per content hash, so attachments with identical
+// content share a blob and a thumbnail.
+func blobKey(sha string) string {
+	return "blobs/" + sha
+}
+
+func thumbnailKey(sha string) string {
+	return "thumbnails/" + sha + ".png"
+}
@@ -0,0 +1,88 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func DeleteAttachmentHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	var sha string
+	err := database.Db.QueryRowContext(ctx, "DELETE FROM attachments WHERE expense_id=$1 AND id=$2 RETURNING sha256", c.Param("id"), c.Param("attachmentId")).Scan(&sha)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "attachment not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	RemoveUnreferenced(ctx, []string{sha})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Purge deletes every attachment of an expense as part of tx, which must
+// happen before the expense row is deleted, and returns their hashes. Pass
+// them to RemoveUnreferenced once tx has committed, so that no blob is lost
+// if it rolls back.
+func Purge(ctx context.Context, tx *sql.Tx, expenseId int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "DELETE FROM attachments WHERE expense_id=$1 RETURNING sha256", expenseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var sha string
+		if err := rows.Scan(&sha); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, sha)
+	}
+	return hashes, rows.Err()
+}
+
+// RemoveUnreferenced deletes the blobs and thumbnails of hashes no attachment
+// uses any more. Failures are logged rather than returned since the rows are
+// already gone and a leftover blob is harmless.
+func RemoveUnreferenced(ctx context.Context, hashes []string) {
+	for _, sha := range hashes {
+		err := database.InTx(ctx, func(tx *sql.Tx) error {
+			if err := lockBlob(ctx, tx, sha); err != nil {
+				return err
+			}
+			var used bool
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM attachments WHERE sha256=$1)", sha).Scan(&used)
+			if err != nil || used {
+				return err
+			}
+
+			for _, key := range []string{blobKey(sha), thumbnailKey(sha)} {
+				if err := Store.Delete(ctx, key); err != nil {
+					log.ErrorContext(ctx, "Delete blob failed", "key", key, "error", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.ErrorContext(ctx, "Check attachment usage failed", "sha256", sha, "error", err)
+		}
+	}
+}
+
+// lockBlob holds, until tx ends, a lock on the blob of sha, which uploads
+// take while they store it and add a reference, and RemoveUnreferenced
+// while it checks for references and deletes it. Otherwise a blob could be
+// deleted just before an upload that found it stored refers to it.
+func lockBlob(ctx context.Context, tx *sql.Tx, sha string) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", sha)
+	return err
+}
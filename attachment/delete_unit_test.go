@@ -0,0 +1,44 @@
+//go:build unit
+
+package attachment
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestRemoveUnreferenced_DeletesOnlyUnusedBlobsUnderLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	Store = NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+	for _, sha := range []string{"used", "unused"} {
+		if err := Store.Put(ctx, blobKey(sha), strings.NewReader(sha), int64(len(sha)), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sha := range []string{"used", "unused"} {
+		mock.ExpectBegin()
+		mock.ExpectExec("pg_advisory_xact_lock").WithArgs(sha).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(sha).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(sha == "used"))
+		mock.ExpectCommit()
+	}
+
+	RemoveUnreferenced(ctx, []string{"used", "unused"})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	stored, _ := Store.Exists(ctx, blobKey("used"))
+	assert.True(t, stored)
+	stored, _ = Store.Exists(ctx, blobKey("unused"))
+	assert.False(t, stored)
+}
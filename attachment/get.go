@@ -0,0 +1,129 @@
+package attachment
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func GetAllAttachmentHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, selectAttachment+" WHERE expense_id=$1 ORDER BY id", c.Param("id"))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return c.JSON(http.StatusOK, attachments)
+}
+
+func getAttachment(c echo.Context) (Attachment, error) {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+	return scanAttachment(database.Db.QueryRowContext(ctx, selectAttachment+" WHERE expense_id=$1 AND id=$2", c.Param("id"), c.Param("attachmentId")))
+}
+
+// DownloadAttachmentHandler serves the file with support for Range and
+// conditional requests.
+func DownloadAttachmentHandler(c echo.Context) error {
+	a, err := getAttachment(c)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "attachment not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	obj, err := Store.Open(c.Request().Context(), blobKey(a.Sha256))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer obj.Close()
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentType, a.ContentType)
+	h.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	h.Set("ETag", fmt.Sprintf("%q", a.Sha256))
+	http.ServeContent(c.Response(), c.Request(), a.Filename, a.CreatedAt, obj)
+	return nil
+}
+
+// ThumbnailHandler serves a PNG preview of an image attachment. Thumbnails
+// are generated on first request and kept in storage next to the blob.
+func ThumbnailHandler(c echo.Context) error {
+	a, err := getAttachment(c)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "attachment not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	if !hasThumbnail(a.ContentType) {
+		return problem.NotFound(c, "no thumbnail for "+a.ContentType)
+	}
+
+	ctx := c.Request().Context()
+	obj, err := Store.Open(ctx, thumbnailKey(a.Sha256))
+	if err == ErrNotFound {
+		obj, err = generateThumbnail(c, a)
+	}
+	if err == errTooManyPixels {
+		return problem.NotFound(c, "no thumbnail for images over "+strconv.Itoa(maxPixels)+" pixels")
+	}
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer obj.Close()
+
+	c.Response().Header().Set(echo.HeaderContentType, "image/png")
+	c.Response().Header().Set("ETag", fmt.Sprintf("%q", a.Sha256+"-thumbnail"))
+	http.ServeContent(c.Response(), c.Request(), "", a.CreatedAt, obj)
+	return nil
+}
+
+func generateThumbnail(c echo.Context, a Attachment) (io.ReadSeekCloser, error) {
+	ctx := c.Request().Context()
+	src, err := Store.Open(ctx, blobKey(a.Sha256))
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := thumbnail(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Store.Put(ctx, thumbnailKey(a.Sha256), bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		log.ErrorContext(ctx, "Store thumbnail failed", "sha256", a.Sha256, "error", err)
+	}
+	return readSeekNopCloser{bytes.NewReader(data)}, nil
+}
+
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error { return nil }
@@ -0,0 +1,44 @@
+//go:build unit
+
+package attachment
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage_PutOpenDelete(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	err := s.Put(ctx, "blobs/abc", strings.NewReader("receipt"), 7, "application/pdf")
+	assert.NoError(t, err)
+
+	exists, err := s.Exists(ctx, "blobs/abc")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	obj, err := s.Open(ctx, "blobs/abc")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(obj)
+		obj.Close()
+		assert.Equal(t, "receipt", string(data))
+	}
+
+	assert.NoError(t, s.Delete(ctx, "blobs/abc"))
+	_, err = s.Open(ctx, "blobs/abc")
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, s.Delete(ctx, "blobs/abc"))
+}
+
+func TestLocalStorage_RejectsPathTraversal(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+
+	err := s.Put(context.Background(), "../outside", strings.NewReader("x"), 1, "text/plain")
+
+	assert.Error(t, err)
+}
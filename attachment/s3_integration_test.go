@@ -0,0 +1,50 @@
+//go:build integration
+
+package attachment
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestS3Storage_MinIO runs against a local MinIO, for example:
+//
+//	docker run -p 9000:9000 -e MINIO_ROOT_USER=minio -e MINIO_ROOT_PASSWORD=minio123 minio/minio server /data
+//
+// with S3_ENDPOINT=http://localhost:9000, S3_BUCKET, S3_ACCESS_KEY and
+// S3_SECRET_KEY set and the bucket already created.
+func TestS3Storage_MinIO(t *testing.T) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if len(endpoint) == 0 {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+
+	s := NewS3Storage(S3Config{
+		Endpoint:  endpoint,
+		Region:    os.Getenv("S3_REGION"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+	})
+	ctx := context.Background()
+
+	err := s.Put(ctx, "blobs/integration-test", strings.NewReader("0123456789"), 10, "text/plain")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Delete(ctx, "blobs/integration-test")
+
+	obj, err := s.Open(ctx, "blobs/integration-test")
+	if assert.NoError(t, err) {
+		obj.Seek(4, io.SeekStart)
+		data, err := io.ReadAll(obj)
+		assert.NoError(t, err)
+		assert.Equal(t, "456789", string(data))
+		obj.Close()
+	}
+}
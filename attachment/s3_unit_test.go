@@ -0,0 +1,90 @@
+//go:build unit
+
+package attachment
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal in-memory stand-in that checks requests are signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/20230101/us-east-1/s3/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[r.URL.Path]
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path], _ = io.ReadAll(r.Body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead, http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if rng := r.Header.Get("Range"); len(rng) > 0 {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			data = data[start:]
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	}
+}
+
+func TestS3Storage_PutOpenSeekDelete(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	defer server.Close()
+
+	s := NewS3Storage(S3Config{Endpoint: server.URL, Bucket: "receipts", AccessKey: "key", SecretKey: "secret"})
+	s.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	assert.NoError(t, s.Put(ctx, "blobs/abc", strings.NewReader("0123456789"), 10, "application/pdf"))
+
+	exists, err := s.Exists(ctx, "blobs/abc")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	obj, err := s.Open(ctx, "blobs/abc")
+	if assert.NoError(t, err) {
+		size, _ := obj.Seek(0, io.SeekEnd)
+		assert.Equal(t, int64(10), size)
+
+		obj.Seek(6, io.SeekStart)
+		data, err := io.ReadAll(obj)
+		assert.NoError(t, err)
+		assert.Equal(t, "6789", string(data))
+		obj.Close()
+	}
+
+	assert.NoError(t, s.Delete(ctx, "blobs/abc"))
+	exists, err = s.Exists(ctx, "blobs/abc")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,60 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage is a flat key/value blob store.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns a seekable reader so downloads can serve byte ranges.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var Store Storage
+
+// MaxSize is the largest accepted upload in bytes.
+var MaxSize int64 = 10 << 20
+
+// Storage backends.
+const (
+	Local = "local"
+	S3    = "s3"
+)
+
+// Config selects the backend and the upload size limit.
+type Config struct {
+	Storage string
+	// Dir is where the local backend keeps blobs.
+	Dir     string
+	MaxSize int64
+	S3      S3Config
+}
+
+// InitStorage sets up the backend cfg selects, local by default.
+func InitStorage(cfg Config) error {
+	if cfg.MaxSize > 0 {
+		MaxSize = cfg.MaxSize
+	}
+
+	switch cfg.Storage {
+	case "", Local:
+		dir := cfg.Dir
+		if len(dir) == 0 {
+			dir = "attachments"
+		}
+		Store = NewLocalStorage(dir)
+	case S3:
+		Store = NewS3Storage(cfg.S3)
+	default:
+		return fmt.Errorf("unknown attachment storage %q", cfg.Storage)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build unit
+
+package attachment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnail_KeepsAspectRatio(t *testing.T) {
+	data, err := thumbnail(bytes.NewReader(pngImage(1024, 512)))
+
+	if assert.NoError(t, err) {
+		img, err := png.Decode(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 128), img.Bounds())
+	}
+}
+
+func TestThumbnail_ReturnError_WhenNotImage(t *testing.T) {
+	_, err := thumbnail(bytes.NewReader([]byte("%PDF-1.4")))
+
+	assert.Error(t, err)
+}
+
+func TestThumbnail_RefusesTooManyPixelsBeforeDecoding(t *testing.T) {
+	// A 1x1 PNG whose header claims 100000x100000 pixels.
+	data := pngImage(1, 1)
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := thumbnail(bytes.NewReader(data))
+
+	assert.Equal(t, errTooManyPixels, err)
+}
@@ -0,0 +1,144 @@
+package attachment
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// allowedTypes are the sniffed content types accepted as receipts.
+var allowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+const selectAttachment = "SELECT id, expense_id, filename, content_type, size, sha256, created_at FROM attachments"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttachment(s scanner) (Attachment, error) {
+	a := Attachment{}
+	err := s.Scan(&a.Id, &a.ExpenseId, &a.Filename, &a.ContentType, &a.Size, &a.Sha256, &a.CreatedAt)
+	return a, err
+}
+
+// UploadAttachmentHandler stores the multipart "file" field of the request
+// against an expense. The content type is sniffed rather than trusted and
+// uploading the same content twice to an expense returns the existing
+// attachment.
+func UploadAttachmentHandler(c echo.Context) error {
+	id := c.Param("id")
+	if len(id) == 0 {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	// The upload itself is not bounded by the query timeout, only the
+	// lookups before it and the insert after it.
+	lookupCtx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	var exists bool
+	err := database.Db.QueryRowContext(lookupCtx, "SELECT EXISTS (SELECT 1 FROM expenses WHERE id=$1)", id).Scan(&exists)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	if !exists {
+		return problem.NotFound(c, "expense not found")
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, MaxSize+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return problem.Write(c, problem.New(c, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "file too large"))
+		}
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request, missing file")
+	}
+	if fh.Size > MaxSize {
+		return problem.Write(c, problem.New(c, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "file too large"))
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, can't read file")
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return problem.BadRequest(c, "Invalid request, can't read file")
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !allowedTypes[contentType] {
+		return problem.Write(c, problem.New(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "unsupported file type "+contentType))
+	}
+
+	h := sha256.New()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return problem.Internal(c, err)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return problem.Internal(c, err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	a, err := scanAttachment(database.Db.QueryRowContext(lookupCtx, selectAttachment+" WHERE expense_id=$1 AND sha256=$2", id, sum))
+	switch err {
+	case nil:
+		return c.JSON(http.StatusOK, a)
+	case sql.ErrNoRows:
+	default:
+		return problem.Internal(c, err)
+	}
+
+	// The transaction is bounded by the request rather than the query
+	// timeout, since it spans storing the blob.
+	ctx := req.Context()
+	a = Attachment{Filename: filepath.Base(fh.Filename), ContentType: contentType, Size: fh.Size, Sha256: sum}
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if err := lockBlob(ctx, tx, sum); err != nil {
+			return err
+		}
+		stored, err := Store.Exists(ctx, blobKey(sum))
+		if err != nil {
+			return err
+		}
+		if !stored {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			if err := Store.Put(ctx, blobKey(sum), f, fh.Size, contentType); err != nil {
+				return err
+			}
+		}
+
+		insertCtx, cancel := database.Context(ctx)
+		defer cancel()
+		row := tx.QueryRowContext(insertCtx, "INSERT INTO attachments (expense_id, filename, content_type, size, sha256) VALUES ($1, $2, $3, $4, $5) RETURNING id, expense_id, created_at",
+			id, a.Filename, a.ContentType, a.Size, a.Sha256)
+		return row.Scan(&a.Id, &a.ExpenseId, &a.CreatedAt)
+	})
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, a)
+}
@@ -0,0 +1,141 @@
+//go:build unit
+
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func multipartBody(t *testing.T, filename string, data []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+	return &body, w.FormDataContentType()
+}
+
+func pngImage(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+func uploadContext(t *testing.T, filename string, data []byte) (echo.Context, *httptest.ResponseRecorder) {
+	body, contentType := multipartBody(t, filename, data)
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set(echo.HeaderContentType, contentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath("/expenses/:id/attachments")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	return c, rec
+}
+
+func TestUploadAttachment_ReturnUnsupportedMediaType_WhenNotImageOrPdf(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	c, rec := uploadContext(t, "receipt.pdf", []byte("#!/bin/sh\necho hello"))
+
+	err = UploadAttachmentHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	}
+}
+
+func TestUploadAttachment_ReturnTooLarge_WhenOverLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	defer func(old int64) { MaxSize = old }(MaxSize)
+	MaxSize = 16
+	c, rec := uploadContext(t, "receipt.png", pngImage(10, 10))
+
+	err = UploadAttachmentHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
+
+func TestUploadAttachment_ReturnCreated_AndStoresBlob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	Store = NewLocalStorage(t.TempDir())
+
+	data := pngImage(10, 10)
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT (.+) FROM attachments WHERE expense_id=\\$1 AND sha256=\\$2").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO attachments").
+		WithArgs("1", "receipt.png", "image/png", int64(len(data)), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "created_at"}).AddRow(1, 1, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)))
+	mock.ExpectCommit()
+	c, rec := uploadContext(t, "receipt.png", data)
+
+	err = UploadAttachmentHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), "\"content_type\":\"image/png\"")
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		sum := sha256.Sum256(data)
+		stored, _ := Store.Exists(context.Background(), blobKey(hex.EncodeToString(sum[:])))
+		assert.True(t, stored)
+	}
+}
+
+func TestUploadAttachment_ReturnNotFound_WhenExpenseMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	c, rec := uploadContext(t, "receipt.png", pngImage(10, 10))
+
+	err = UploadAttachmentHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/problem"
+)
+
+// admins holds the usernames allowed on admin routes. It is replaced as a
+// whole when the config is reloaded.
+var admins atomic.Pointer[map[string]bool]
+
+// SetAdmins replaces the usernames allowed on admin routes.
+func SetAdmins(usernames []string) {
+	m := make(map[string]bool, len(usernames))
+	for _, u := range usernames {
+		m[u] = true
+	}
+	admins.Store(&m)
+}
+
+// IsAdmin reports whether the request authenticated as an admin. Requests
+// without a user, such as every request when authentication is off, never
+// are.
+func IsAdmin(c echo.Context) bool {
+	m := admins.Load()
+	u := User(c)
+	return m != nil && len(u) > 0 && (*m)[u]
+}
+
+// RequireAdmin rejects requests that did not authenticate as an admin
+// with 403. It must run after authentication.
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return problem.Write(c, problem.New(c, http.StatusForbidden, problem.CodeForbidden, "admin access required"))
+		}
+		return next(c)
+	}
+}
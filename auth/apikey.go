@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"github.com/umateedev/assessment/database"
+)
+
+// APIKeyHeader carries an API key instead of a username and password.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix marks keys so that they are easy to spot in leaked text.
+const apiKeyPrefix = "exp_"
+
+// hashAPIKey is what is stored of a key. Keys are random, so a fast hash
+// is enough, unlike passwords.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey creates a key that acts as username and returns it. Only
+// its hash is stored, so it cannot be shown again.
+func CreateAPIKey(ctx context.Context, username, name string) (string, error) {
+	if len(name) == 0 {
+		return "", errors.New("name is required")
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(b)
+
+	res, err := database.Db.ExecContext(ctx, "INSERT INTO api_keys (user_id, name, key_hash) SELECT id, $2, $3 FROM users WHERE username = $1",
+		username, name, hashAPIKey(key))
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", err
+	} else if n == 0 {
+		return "", ErrUserNotFound
+	}
+	return key, nil
+}
+
+// VerifyAPIKey returns the user and id of key, or ok false if there is no
+// such key.
+func VerifyAPIKey(ctx context.Context, key string) (username string, id int, ok bool, err error) {
+	err = database.Db.QueryRowContext(ctx, "SELECT u.username, k.id FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = $1",
+		hashAPIKey(key)).Scan(&username, &id)
+	switch err {
+	case nil:
+		return username, id, true, nil
+	case sql.ErrNoRows:
+		return "", 0, false, nil
+	default:
+		return "", 0, false, err
+	}
+}
@@ -0,0 +1,62 @@
+//go:build unit
+
+package auth
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKey_StoresOnlyTheHash(t *testing.T) {
+	mock := mockDb(t)
+	hash := &capture{}
+	mock.ExpectExec("INSERT INTO api_keys").WithArgs("alice", "ci", hash).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	key, err := CreateAPIKey(context.Background(), "alice", "ci")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.Equal(t, hashAPIKey(key), hash.value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// capture matches any argument and keeps it.
+type capture struct{ value interface{} }
+
+func (c *capture) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func TestCreateAPIKey_ReturnsUserNotFound(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectExec("INSERT INTO api_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := CreateAPIKey(context.Background(), "nobody", "ci")
+
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectQuery("SELECT u.username, k.id FROM api_keys").WithArgs(hashAPIKey("exp_good")).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id"}).AddRow("alice", 3))
+	mock.ExpectQuery("SELECT u.username, k.id FROM api_keys").WithArgs(hashAPIKey("exp_bad")).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id"}))
+
+	username, id, ok, err := VerifyAPIKey(context.Background(), "exp_good")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, 3, id)
+
+	_, _, ok, err = VerifyAPIKey(context.Background(), "exp_bad")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "github.com/labstack/echo/v4"
+
+const (
+	userKey   = "user"
+	apiKeyKey = "api_key"
+)
+
+// SetUser records the authenticated username on the request context.
+func SetUser(c echo.Context, username string) {
+	c.Set(userKey, username)
+}
+
+// User returns the authenticated username, or "" when the route is not
+// behind authentication.
+func User(c echo.Context) string {
+	u, _ := c.Get(userKey).(string)
+	return u
+}
+
+// SetAPIKey records the id of the API key the request authenticated with.
+func SetAPIKey(c echo.Context, id int) {
+	c.Set(apiKeyKey, id)
+}
+
+// APIKey returns the id of the API key the request authenticated with, or
+// 0 when it used a password or no authentication.
+func APIKey(c echo.Context) int {
+	id, _ := c.Get(apiKeyKey).(int)
+	return id
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+
+	"github.com/umateedev/assessment/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Demo credentials accepted until the first user is created, so a fresh
+// install can be tried without running the user command.
+const (
+	demoUsername = "November 10"
+	demoPassword = "2009"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// dummyHash is compared against when the username is unknown so that a
+// failed login takes as long whether or not the user exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+func hash(password string) (string, error) {
+	if len(password) < 8 {
+		return "", errors.New("password must be at least 8 characters")
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(h), err
+}
+
+func CreateUser(ctx context.Context, username, password string) (int, error) {
+	if len(username) == 0 {
+		return 0, errors.New("username is required")
+	}
+	h, err := hash(password)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = database.Db.QueryRowContext(ctx, "INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id", username, h).Scan(&id)
+	return id, err
+}
+
+func ResetPassword(ctx context.Context, username, password string) error {
+	h, err := hash(password)
+	if err != nil {
+		return err
+	}
+
+	res, err := database.Db.ExecContext(ctx, "UPDATE users SET password_hash = $1 WHERE username = $2", h, username)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Verify checks a username and password against the users table. While the
+// table is empty only the demo credentials are accepted.
+func Verify(ctx context.Context, username, password string) (bool, error) {
+	var h string
+	err := database.Db.QueryRowContext(ctx, "SELECT password_hash FROM users WHERE username = $1", username).Scan(&h)
+	switch err {
+	case nil:
+		return bcrypt.CompareHashAndPassword([]byte(h), []byte(password)) == nil, nil
+	case sql.ErrNoRows:
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	default:
+		return false, err
+	}
+
+	var any bool
+	if err := database.Db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&any); err != nil {
+		return false, err
+	}
+	if any {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(username), []byte(demoUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(demoPassword)) == 1, nil
+}
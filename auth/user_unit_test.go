@@ -0,0 +1,79 @@
+//go:build unit
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func mockDb(t *testing.T) sqlmock.Sqlmock {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.Db = db
+	return mock
+}
+
+func TestVerify_AcceptsDemoCredentialsWithoutUsers(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectQuery("SELECT password_hash FROM users").WithArgs(demoUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}))
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	ok, err := Verify(context.Background(), demoUsername, demoPassword)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerify_RejectsDemoCredentialsOnceUsersExist(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectQuery("SELECT password_hash FROM users").WithArgs(demoUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}))
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	ok, err := Verify(context.Background(), demoUsername, demoPassword)
+
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerify_ChecksPasswordHash(t *testing.T) {
+	h, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+
+	for password, want := range map[string]bool{"correct horse": true, "wrong horse": false} {
+		mock := mockDb(t)
+		mock.ExpectQuery("SELECT password_hash FROM users").WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(string(h)))
+
+		ok, err := Verify(context.Background(), "alice", password)
+
+		assert.NoError(t, err)
+		assert.Equal(t, want, ok, password)
+	}
+}
+
+func TestCreateUser_RejectsShortPassword(t *testing.T) {
+	_, err := CreateUser(context.Background(), "alice", "short")
+
+	assert.Error(t, err)
+}
+
+func TestResetPassword_ReturnUserNotFound(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectExec("UPDATE users SET password_hash = \\$1 WHERE username = \\$2").
+		WithArgs(sqlmock.AnyArg(), "alice").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := ResetPassword(context.Background(), "alice", "a new password")
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
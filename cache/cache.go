@@ -0,0 +1,165 @@
+// Package cache keeps expense read responses so that dashboards polling
+// the API do not hit the database every time.
+//
+// Entries are never found stale after a write: every key embeds version
+// counters that writers increment, so a write makes the old entries
+// unreachable and they age out. Counters outlive the entries they guard,
+// which is what makes it safe for them to expire too.
+//
+// With the memory backend each instance has its own entries and counters,
+// so a write on one instance is only seen by the others after TTL. Run
+// several instances with the redis backend instead.
+package cache
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("cache")
+
+// Backend stores entries and version counters.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Counters returns the value of each counter, 0 for missing ones.
+	Counters(ctx context.Context, keys ...string) ([]int64, error)
+	// Incr increments a counter and keeps it for at least ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) error
+}
+
+type Config struct {
+	// TTL bounds how long an entry is kept.
+	TTL time.Duration
+	// MaxEntryBytes skips caching larger responses; 0 has no limit.
+	MaxEntryBytes int
+}
+
+var (
+	backend Backend
+	config  Config
+)
+
+// Setup turns caching on with b, or off with nil. Call it before serving.
+func Setup(b Backend, cfg Config) {
+	backend, config = b, cfg
+}
+
+// Enabled reports whether Setup was given a backend.
+func Enabled() bool {
+	return backend != nil
+}
+
+// Stats counts cache use since start.
+type Stats struct {
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Stores        int64   `json:"stores"`
+	Invalidations int64   `json:"invalidations"`
+	Errors        int64   `json:"errors"`
+}
+
+var hits, misses, stores, invalidations, errs atomic.Int64
+
+func CurrentStats() Stats {
+	s := Stats{
+		Hits:          hits.Load(),
+		Misses:        misses.Load(),
+		Stores:        stores.Load(),
+		Invalidations: invalidations.Load(),
+		Errors:        errs.Load(),
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+// StatsHandler reports cache hits and misses.
+func StatsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, CurrentStats())
+}
+
+// failed counts and logs a backend error. Errors never fail a request;
+// the response is built from the database instead.
+func failed(ctx context.Context, op string, err error) {
+	errs.Add(1)
+	log.WarnContext(ctx, "Cache backend failed", "op", op, "error", err)
+}
+
+func get(ctx context.Context, key string) ([]byte, bool) {
+	b, ok, err := backend.Get(ctx, key)
+	if err != nil {
+		failed(ctx, "get", err)
+	}
+	if ok {
+		hits.Add(1)
+	} else {
+		misses.Add(1)
+	}
+	return b, ok
+}
+
+func set(ctx context.Context, key string, body []byte) {
+	if config.MaxEntryBytes > 0 && len(body) > config.MaxEntryBytes {
+		return
+	}
+	if err := backend.Set(ctx, key, body, config.TTL); err != nil {
+		failed(ctx, "set", err)
+		return
+	}
+	stores.Add(1)
+}
+
+func counters(ctx context.Context, keys ...string) ([]int64, bool) {
+	n, err := backend.Counters(ctx, keys...)
+	if err != nil {
+		failed(ctx, "counters", err)
+		return nil, false
+	}
+	return n, true
+}
+
+// bump increments counters, keeping them for twice the entry TTL so that
+// no entry made under an expired counter's old value is still around.
+func bump(ctx context.Context, keys ...string) {
+	if backend == nil {
+		return
+	}
+	for _, k := range keys {
+		if err := backend.Incr(ctx, k, 2*config.TTL); err != nil {
+			failed(ctx, "incr", err)
+		}
+	}
+	invalidations.Add(1)
+}
+
+// Write sends a cached or freshly built JSON body.
+func Write(c echo.Context, body []byte, hit bool) error {
+	Headers(c, hit)
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// Headers sets the caching headers of a response. The body is per user and
+// may change at any time, so shared caches must not keep it and browsers
+// must revalidate.
+func Headers(c echo.Context, hit bool) {
+	h := c.Response().Header()
+	h.Set("Cache-Control", "private, no-cache")
+	h.Add(echo.HeaderVary, echo.HeaderAuthorization)
+	h.Add(echo.HeaderVary, auth.APIKeyHeader)
+	if Enabled() {
+		if hit {
+			h.Set("X-Cache", "HIT")
+		} else {
+			h.Set("X-Cache", "MISS")
+		}
+	}
+}
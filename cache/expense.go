@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// Counter keys. allKey changes with any write that may touch many
+// expenses, listKey with any write at all, and itemKey(id) with a write to
+// that expense.
+const (
+	prefix  = "cache:expenses:"
+	allKey  = prefix + "all"
+	listKey = prefix + "lists"
+)
+
+func itemKey(id int) string {
+	return prefix + "item:" + strconv.Itoa(id)
+}
+
+// Entry names one cached response. Look it up before reading the
+// database and store the response under the same Entry, so that a write
+// in between leaves the stored response unreachable. The zero Entry is
+// never cached.
+type Entry struct {
+	key string
+}
+
+func (e Entry) Get(ctx context.Context) ([]byte, bool) {
+	if len(e.key) == 0 {
+		return nil, false
+	}
+	return get(ctx, e.key)
+}
+
+func (e Entry) Store(ctx context.Context, body []byte) {
+	if len(e.key) > 0 {
+		set(ctx, e.key, body)
+	}
+}
+
+// Buffer returns a Buffer that stores into e.
+func (e Entry) Buffer() *Buffer {
+	return &Buffer{entry: e}
+}
+
+// Buffer collects a response while it is streamed, so that it can be
+// stored once complete. It stops collecting past the largest body that
+// would be stored.
+type Buffer struct {
+	entry Entry
+	buf   bytes.Buffer
+	over  bool
+}
+
+// Write never fails.
+func (b *Buffer) Write(p []byte) (int, error) {
+	if len(b.entry.key) == 0 || b.over {
+		return len(p), nil
+	}
+	if config.MaxEntryBytes > 0 && b.buf.Len()+len(p) > config.MaxEntryBytes {
+		b.over = true
+		b.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Store stores what was written, unless it grew too large.
+func (b *Buffer) Store(ctx context.Context) {
+	if !b.over {
+		b.entry.Store(ctx, b.buf.Bytes())
+	}
+}
+
+// ItemEntry is the entry of GET /expenses/:id.
+func ItemEntry(ctx context.Context, id int) Entry {
+	if backend == nil {
+		return Entry{}
+	}
+	v, ok := counters(ctx, allKey, itemKey(id))
+	if !ok {
+		return Entry{}
+	}
+	return Entry{fmt.Sprintf("%sentry:%d:%d:item:%d", prefix, v[0], v[1], id)}
+}
+
+// ListEntry is the entry of GET /expenses for user and the encoded query
+// string. Both are hashed so that keys stay short and do not hold
+// usernames or filter values.
+func ListEntry(ctx context.Context, user, query string) Entry {
+	if backend == nil {
+		return Entry{}
+	}
+	v, ok := counters(ctx, allKey, listKey)
+	if !ok {
+		return Entry{}
+	}
+	sum := sha256.Sum256([]byte(user + "\x00" + query))
+	return Entry{fmt.Sprintf("%sentry:%d:%d:list:%s", prefix, v[0], v[1], hex.EncodeToString(sum[:16]))}
+}
+
+// ExpenseCreated drops cached lists.
+func ExpenseCreated(ctx context.Context) {
+	bump(ctx, listKey)
+}
+
+// ExpenseChanged drops cached lists and the cached expense id, after it
+// was updated or deleted.
+func ExpenseChanged(ctx context.Context, id int) {
+	bump(ctx, itemKey(id), listKey)
+}
+
+// ExpensesChanged drops everything, after a write that may touch any
+// number of expenses such as a tag rename or a rule being applied.
+func ExpensesChanged(ctx context.Context) {
+	bump(ctx, allKey)
+}
@@ -0,0 +1,137 @@
+//go:build unit
+
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T, cfg Config) {
+	Setup(NewLRU(100), cfg)
+	t.Cleanup(func() { Setup(nil, Config{}) })
+}
+
+// stored stores a body under the entry from lookup, runs change and reports
+// whether the body can still be found.
+func stored(ctx context.Context, lookup func() Entry, change func()) bool {
+	lookup().Store(ctx, []byte("body"))
+	change()
+	_, ok := lookup().Get(ctx)
+	return ok
+}
+
+func TestInvalidation(t *testing.T) {
+	setup(t, Config{TTL: time.Minute})
+	ctx := context.Background()
+	item := func() Entry { return ItemEntry(ctx, 1) }
+	other := func() Entry { return ItemEntry(ctx, 2) }
+	list := func() Entry { return ListEntry(ctx, "alice", "filter=amount>10") }
+	none := func() {}
+
+	assert.True(t, stored(ctx, item, none))
+	assert.True(t, stored(ctx, list, none))
+
+	assert.True(t, stored(ctx, item, func() { ExpenseCreated(ctx) }), "a new expense leaves others cached")
+	assert.False(t, stored(ctx, list, func() { ExpenseCreated(ctx) }))
+
+	assert.False(t, stored(ctx, item, func() { ExpenseChanged(ctx, 1) }))
+	assert.True(t, stored(ctx, other, func() { ExpenseChanged(ctx, 1) }))
+	assert.False(t, stored(ctx, list, func() { ExpenseChanged(ctx, 1) }))
+
+	assert.False(t, stored(ctx, item, func() { ExpensesChanged(ctx) }))
+	assert.False(t, stored(ctx, list, func() { ExpensesChanged(ctx) }))
+}
+
+func TestListEntry_PerUserAndQuery(t *testing.T) {
+	setup(t, Config{TTL: time.Minute})
+	ctx := context.Background()
+
+	ListEntry(ctx, "alice", "limit=10").Store(ctx, []byte("body"))
+
+	_, ok := ListEntry(ctx, "alice", "limit=10").Get(ctx)
+	assert.True(t, ok)
+	_, ok = ListEntry(ctx, "bob", "limit=10").Get(ctx)
+	assert.False(t, ok)
+	_, ok = ListEntry(ctx, "alice", "limit=20").Get(ctx)
+	assert.False(t, ok)
+}
+
+func TestEntry_SkipsLargeBodies(t *testing.T) {
+	setup(t, Config{TTL: time.Minute, MaxEntryBytes: 4})
+	ctx := context.Background()
+
+	ItemEntry(ctx, 1).Store(ctx, []byte("12345"))
+
+	_, ok := ItemEntry(ctx, 1).Get(ctx)
+	assert.False(t, ok)
+}
+
+func TestEntry_Disabled(t *testing.T) {
+	ctx := context.Background()
+	e := ItemEntry(ctx, 1)
+	e.Store(ctx, []byte("body"))
+	ExpenseChanged(ctx, 1)
+
+	_, ok := e.Get(ctx)
+	assert.False(t, ok)
+}
+
+func TestWrite(t *testing.T) {
+	setup(t, Config{TTL: time.Minute})
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	err := Write(c, []byte(`[]`), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, []string{"Authorization", "X-API-Key"}, rec.Header().Values("Vary"))
+	assert.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `[]`, rec.Body.String())
+}
+
+func TestCurrentStats(t *testing.T) {
+	setup(t, Config{TTL: time.Minute})
+	ctx := context.Background()
+	before := CurrentStats()
+
+	ItemEntry(ctx, 1).Get(ctx)
+	ItemEntry(ctx, 1).Store(ctx, []byte("body"))
+	ItemEntry(ctx, 1).Get(ctx)
+	ExpenseChanged(ctx, 1)
+
+	s := CurrentStats()
+	assert.Equal(t, int64(1), s.Hits-before.Hits)
+	assert.Equal(t, int64(1), s.Misses-before.Misses)
+	assert.Equal(t, int64(1), s.Stores-before.Stores)
+	assert.Equal(t, int64(1), s.Invalidations-before.Invalidations)
+}
+
+func TestBuffer(t *testing.T) {
+	setup(t, Config{TTL: time.Minute, MaxEntryBytes: 4})
+	ctx := context.Background()
+
+	small := ItemEntry(ctx, 1).Buffer()
+	small.Write([]byte("12"))
+	small.Write([]byte("34"))
+	small.Store(ctx)
+	large := ItemEntry(ctx, 2).Buffer()
+	large.Write([]byte("123"))
+	large.Write([]byte("45"))
+	large.Store(ctx)
+
+	body, ok := ItemEntry(ctx, 1).Get(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "1234", string(body))
+	_, ok = ItemEntry(ctx, 2).Get(ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Backend holding at most a fixed number of entries,
+// evicting the least recently used. Counters are kept apart from entries
+// so that eviction never resets them.
+type LRU struct {
+	mu       sync.Mutex
+	max      int
+	order    *list.List
+	entries  map[string]*list.Element
+	counters map[string]counter
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+type counter struct {
+	n       int64
+	expires time.Time
+}
+
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{
+		max:      maxEntries,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+		counters: map[string]counter{},
+		now:      time.Now,
+	}
+}
+
+func (l *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if l.now().After(e.expires) {
+		l.order.Remove(el)
+		delete(l.entries, key)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (l *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expires := l.now().Add(ttl)
+	if el, ok := l.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expires = value, expires
+		l.order.MoveToFront(el)
+		return nil
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key, value, expires})
+	for l.order.Len() > l.max {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (l *LRU) Counters(_ context.Context, keys ...string) ([]int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	out := make([]int64, len(keys))
+	for i, k := range keys {
+		if c, ok := l.counters[k]; ok && now.Before(c.expires) {
+			out[i] = c.n
+		}
+	}
+	return out, nil
+}
+
+func (l *LRU) Incr(_ context.Context, key string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	c := l.counters[key]
+	if now.After(c.expires) {
+		// Entries keyed by the versions an expired counter went through
+		// may still be cached, so it must not count through them again.
+		// Starting from the time puts it past all of them.
+		c.n = max(c.n, now.UnixNano())
+	}
+	l.counters[key] = counter{c.n + 1, now.Add(ttl)}
+
+	// Every expense that changes gets a counter; drop the expired ones
+	// once there are more of them than entries.
+	if len(l.counters) > l.max {
+		for k, c := range l.counters {
+			if now.After(c.expires) {
+				delete(l.counters, k)
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet
+// evicted.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,83 @@
+//go:build unit
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	l := NewLRU(2)
+	ctx := context.Background()
+
+	l.Set(ctx, "a", []byte("1"), time.Minute)
+	l.Set(ctx, "b", []byte("2"), time.Minute)
+	l.Get(ctx, "a")
+	l.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, ok, _ := l.Get(ctx, "b")
+	assert.False(t, ok)
+	v, ok, _ := l.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(v))
+	assert.Equal(t, 2, l.Len())
+}
+
+func TestLRU_ExpiresEntries(t *testing.T) {
+	l := NewLRU(10)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Set(context.Background(), "a", []byte("1"), time.Minute)
+	now = now.Add(time.Minute + time.Second)
+
+	_, ok, _ := l.Get(context.Background(), "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestLRU_Counters(t *testing.T) {
+	l := NewLRU(1)
+	ctx := context.Background()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	start := now.UnixNano()
+
+	l.Incr(ctx, "a", time.Minute)
+	l.Incr(ctx, "a", time.Minute)
+	l.Incr(ctx, "b", time.Second)
+	n, err := l.Counters(ctx, "a", "b", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{start + 2, start + 1, 0}, n)
+
+	l.Set(ctx, "x", nil, time.Minute)
+	l.Set(ctx, "y", nil, time.Minute)
+	n, _ = l.Counters(ctx, "a")
+	assert.Equal(t, []int64{start + 2}, n, "evicting entries keeps counters")
+
+	now = now.Add(2 * time.Second)
+	l.Incr(ctx, "c", time.Minute)
+	n, _ = l.Counters(ctx, "a", "b", "c")
+	assert.Equal(t, []int64{start + 2, 0, now.UnixNano() + 1}, n)
+	assert.NotContains(t, l.counters, "b", "expired counters are dropped")
+}
+
+func TestLRU_Incr_NeverRepeatsAVersion(t *testing.T) {
+	l := NewLRU(10)
+	ctx := context.Background()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Incr(ctx, "a", time.Second)
+	l.Incr(ctx, "a", time.Second)
+	before, _ := l.Counters(ctx, "a")
+
+	now = now.Add(2 * time.Second)
+	l.Incr(ctx, "a", time.Second)
+	after, _ := l.Counters(ctx, "a")
+	assert.Greater(t, after[0], before[0])
+}
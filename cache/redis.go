@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/umateedev/assessment/resp"
+)
+
+// Redis is a Backend shared by every instance through a server speaking
+// the Redis protocol. Entries and counters expire on the server.
+type Redis struct {
+	pool *resp.Pool
+}
+
+// NewRedis connects lazily to the server at url, in the form
+// redis://[user:password@]host[:port][/db].
+func NewRedis(url string) (*Redis, error) {
+	pool, err := resp.NewPool(url, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{pool: pool}, nil
+}
+
+func (r *Redis) Close() {
+	r.pool.Close()
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.pool.Do(ctx, []string{"GET", key})
+	if err != nil {
+		return nil, false, err
+	}
+	b, ok := reply.([]byte)
+	return b, ok, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.pool.Do(ctx, []string{"SET", key, string(value), "PX", millis(ttl)})
+	return err
+}
+
+func (r *Redis) Counters(ctx context.Context, keys ...string) ([]int64, error) {
+	reply, err := r.pool.Do(ctx, append([]string{"MGET"}, keys...))
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+	out := make([]int64, len(keys))
+	for i := range out {
+		if i >= len(items) {
+			break
+		}
+		if b, ok := items[i].([]byte); ok {
+			out[i], _ = strconv.ParseInt(string(b), 10, 64)
+		}
+	}
+	return out, nil
+}
+
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) error {
+	// See LRU.Incr for why a missing counter starts at the time.
+	_, err := r.pool.Do(ctx,
+		[]string{"MULTI"},
+		[]string{"SET", key, strconv.FormatInt(time.Now().UnixNano(), 10), "NX", "PX", millis(ttl)},
+		[]string{"INCR", key},
+		[]string{"PEXPIRE", key, millis(ttl)},
+		[]string{"EXEC"})
+	return err
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
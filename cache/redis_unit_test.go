@@ -0,0 +1,39 @@
+//go:build unit
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/resp/resptest"
+)
+
+func TestRedis(t *testing.T) {
+	srv := resptest.NewServer(t, "s3cret")
+	r, err := NewRedis(srv.URL())
+	assert.NoError(t, err)
+	defer r.Close()
+	ctx := context.Background()
+
+	_, ok, err := r.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, r.Set(ctx, "a", []byte(`{"id":1}`), time.Minute))
+	v, ok, err := r.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"id":1}`, string(v))
+
+	start := time.Now().UnixNano()
+	assert.NoError(t, r.Incr(ctx, "n", time.Minute))
+	first, _ := r.Counters(ctx, "n")
+	assert.Greater(t, first[0], start, "a missing counter starts at the time")
+	assert.NoError(t, r.Incr(ctx, "n", time.Minute))
+	n, err := r.Counters(ctx, "n", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{first[0] + 1, 0}, n)
+}
@@ -0,0 +1,166 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
+)
+
+// Budget is the monthly spend of a user allowed in a category, including
+// all of its descendants. Spent covers the user's expenses in the current
+// calendar month; with authentication off both are shared.
+type Budget struct {
+	CategoryId int     `json:"category_id"`
+	Amount     float64 `json:"amount"`
+	Spent      float64 `json:"spent"`
+}
+
+// Exceeded is a budget that an expense took over its amount, in the month
+// of the expense.
+type Exceeded struct {
+	CategoryId int     `json:"category_id"`
+	Name       string  `json:"name"`
+	Amount     float64 `json:"amount"`
+	Spent      float64 `json:"spent"`
+	Month      string  `json:"month"`
+	ExpenseId  int     `json:"expense_id"`
+}
+
+// selectSpent sums the expenses of the owner of budget b in its category
+// and the descendants in the current month.
+const selectSpent = `SELECT COALESCE(sum(e.amount), 0)
+FROM category_paths p JOIN expenses e ON e.category_id = p.descendant
+WHERE p.ancestor = b.category_id AND COALESCE(e.owner, '') = b.owner
+AND date_trunc('month', e.created_at) = date_trunc('month', now())`
+
+func GetBudgetHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	b := Budget{CategoryId: id}
+	err = database.Db.QueryRowContext(ctx, "SELECT amount, ("+selectSpent+") FROM budgets b WHERE category_id = $1 AND owner = $2", id, auth.User(c)).Scan(&b.Amount, &b.Spent)
+	switch err {
+	case nil:
+		return c.JSON(http.StatusOK, b)
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "budget not found")
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+// PutBudgetHandler sets the monthly budget of a category.
+func PutBudgetHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	b := Budget{}
+	err = c.Bind(&b)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+	if b.Amount <= 0 {
+		return problem.Invalid(c, problem.FieldError{Field: "amount", Message: "must be greater than 0"})
+	}
+	b.CategoryId = id
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.Db.QueryRowContext(ctx, `INSERT INTO budgets AS b (category_id, amount, owner) VALUES ($1, $2, $3)
+	ON CONFLICT (owner, category_id) DO UPDATE SET amount = EXCLUDED.amount
+	RETURNING (`+selectSpent+`)`, id, b.Amount, auth.User(c)).Scan(&b.Spent)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return problem.Write(c, problem.New(c, http.StatusNotFound, problem.CodeCategoryNotFound, "category not found"))
+	}
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusOK, b)
+}
+
+func DeleteBudgetHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	res, err := database.Db.ExecContext(ctx, "DELETE FROM budgets WHERE category_id = $1 AND owner = $2", c.Param("id"), auth.User(c))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return problem.Internal(c, err)
+	} else if n == 0 {
+		return problem.NotFound(c, "budget not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// BudgetsExceeded returns the budgets of the owner of expense id, on its
+// category and the ancestors, that the expense takes over their amount:
+// those within budget before the expense was written and over it after.
+// oldCategoryId and oldAmount are the expense before an update, nil and 0
+// for a new one.
+func BudgetsExceeded(ctx context.Context, tx *sql.Tx, id int, oldCategoryId *int, oldAmount float64) ([]Exceeded, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT b.category_id, c.name, b.amount, sum(e.amount), to_char(x.created_at, 'YYYY-MM')
+	FROM expenses x
+	JOIN category_paths up ON up.descendant = x.category_id
+	JOIN budgets b ON b.category_id = up.ancestor AND b.owner = COALESCE(x.owner, '')
+	JOIN categories c ON c.id = b.category_id
+	JOIN category_paths down ON down.ancestor = b.category_id
+	JOIN expenses e ON e.category_id = down.descendant AND COALESCE(e.owner, '') = b.owner
+		AND date_trunc('month', e.created_at) = date_trunc('month', x.created_at)
+	WHERE x.id = $1
+	GROUP BY b.category_id, c.name, b.amount, x.amount, x.created_at
+	HAVING sum(e.amount) > b.amount AND sum(e.amount) - x.amount + CASE
+		WHEN EXISTS (SELECT 1 FROM category_paths o WHERE o.ancestor = b.category_id AND o.descendant = $2::integer) THEN $3::float
+		ELSE 0 END <= b.amount
+	ORDER BY b.category_id`, id, oldCategoryId, oldAmount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceeded []Exceeded
+	for rows.Next() {
+		ex := Exceeded{ExpenseId: id}
+		if err := rows.Scan(&ex.CategoryId, &ex.Name, &ex.Amount, &ex.Spent, &ex.Month); err != nil {
+			return nil, err
+		}
+		exceeded = append(exceeded, ex)
+	}
+	return exceeded, rows.Err()
+}
+
+// NotifyBudgetsExceeded queues, as part of tx, a budget.exceeded webhook
+// event of owner for every budget that expense id takes over; see
+// BudgetsExceeded.
+func NotifyBudgetsExceeded(ctx context.Context, tx *sql.Tx, owner string, id int, oldCategoryId *int, oldAmount float64) error {
+	exceeded, err := BudgetsExceeded(ctx, tx, id, oldCategoryId, oldAmount)
+	if err != nil {
+		return err
+	}
+	for _, ex := range exceeded {
+		if err := webhook.Enqueue(ctx, tx, owner, webhook.BudgetExceeded, ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
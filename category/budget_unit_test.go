@@ -0,0 +1,98 @@
+//go:build unit
+
+package category
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+)
+
+func putBudget(t *testing.T, body string) (*httptest.ResponseRecorder, error) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+	auth.SetUser(c, "alice")
+	return rec, PutBudgetHandler(c)
+}
+
+func TestPutBudget_ReturnUnprocessableEntity_WhenAmountNotPositive(t *testing.T) {
+	rec, err := putBudget(t, `{"amount": 0}`)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestPutBudget_ReturnSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("INSERT INTO budgets").
+		WithArgs(2, 500.0, "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"spent"}).AddRow(120.5))
+
+	rec, err := putBudget(t, `{"amount": 500}`)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"category_id":2,"amount":500,"spent":120.5}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestPutBudget_ReturnNotFound_WhenCategoryMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("INSERT INTO budgets").WillReturnError(&pq.Error{Code: "23503"})
+
+	rec, err := putBudget(t, `{"amount": 500}`)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "category_not_found")
+	}
+}
+
+func TestBudgetsExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM expenses x").
+		WithArgs(42, 7, 90.0).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "name", "amount", "spent", "month"}).
+			AddRow(1, "Food", 300.0, 310.0, "2023-05"))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	old := 7
+
+	exceeded, err := BudgetsExceeded(context.Background(), tx, 42, &old, 90)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Exceeded{{CategoryId: 1, Name: "Food", Amount: 300, Spent: 310, Month: "2023-05", ExpenseId: 42}}, exceeded)
+}
@@ -0,0 +1,44 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("category")
+
+// Errors returned from inside transactions, turned into responses once the
+// transaction is over.
+var (
+	errNotFound       = errors.New("category not found")
+	errParentNotFound = errors.New("parent category not found")
+	errCycle          = errors.New("can't move a category below itself")
+)
+
+type Category struct {
+	Id       int      `json:"id"`
+	Name     string   `json:"name"`
+	ParentId *int     `json:"parent_id"`
+	Path     []string `json:"path,omitempty"`
+}
+
+// Summary is the spend of a category including all of its descendants.
+type Summary struct {
+	Id       int     `json:"id"`
+	Name     string  `json:"name"`
+	ParentId *int    `json:"parent_id"`
+	Total    float64 `json:"total"`
+	Count    int     `json:"count"`
+}
+
+// lockTree holds, until tx ends, the lock that every change to
+// category_paths takes first. Under READ COMMITTED two moves that each
+// pass the cycle check could otherwise together put categories below each
+// other, and a create or delete could copy paths a move is rewriting.
+func lockTree(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended('category_paths', 0))")
+	return err
+}
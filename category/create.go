@@ -0,0 +1,68 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func CreateCategoryHandler(c echo.Context) error {
+	cat := Category{}
+	err := c.Bind(&cat)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	cat.Name = strings.TrimSpace(cat.Name)
+	if len(cat.Name) == 0 {
+		return problem.Invalid(c, problem.FieldError{Field: "name", Message: "is required"})
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if err := lockTree(ctx, tx); err != nil {
+			return err
+		}
+		if cat.ParentId != nil {
+			ok, err := exists(ctx, tx, *cat.ParentId)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errParentNotFound
+			}
+		}
+
+		err := tx.QueryRowContext(ctx, "INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING id", cat.Name, cat.ParentId).Scan(&cat.Id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO category_paths (ancestor, descendant, depth)
+		SELECT ancestor, $1, depth + 1 FROM category_paths WHERE descendant = $2
+		UNION ALL SELECT $1, $1, 0`, cat.Id, cat.ParentId)
+		return err
+	})
+	switch err {
+	case nil:
+		return c.JSON(http.StatusCreated, cat)
+	case errParentNotFound:
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeCategoryNotFound, err.Error()))
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+func exists(ctx context.Context, tx *sql.Tx, id int) (bool, error) {
+	var ok bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1)", id).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,59 @@
+//go:build unit
+
+package category
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestCreateCategory_ReturnUnprocessableEntity_WhenNameMissing(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"name": "  "}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CreateCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestCreateCategory_ReturnSuccess_WithClosurePaths(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"name": "Coffee", "parent_id": 2}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO categories").WithArgs("Coffee", 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO category_paths").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err = CreateCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "{\"id\":3,\"name\":\"Coffee\",\"parent_id\":2}", strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,78 @@
+package category
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
+)
+
+// DeleteCategoryHandler removes a category. Its children are reparented to
+// its parent and its expenses move to the parent category.
+func DeleteCategoryHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if err := lockTree(ctx, tx); err != nil {
+			return err
+		}
+		var parent *int
+		err := tx.QueryRowContext(ctx, "SELECT parent_id FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&parent)
+		if err == sql.ErrNoRows {
+			return errNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, s := range deleteStatements {
+			if _, err := tx.ExecContext(ctx, s, id); err != nil {
+				return err
+			}
+		}
+		// A budget of the parent already covered the moved expenses, so
+		// none is exceeded by the move.
+		moved, err := outbox.AddFrom(ctx, tx, outbox.ExpenseUpdated, moveExpenses, id)
+		if err != nil {
+			return err
+		}
+		if err := webhook.EnqueueChanged(ctx, tx, webhook.ExpenseUpdated, moved); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+		return err
+	})
+	switch err {
+	case nil:
+		cache.ExpensesChanged(ctx)
+		return c.NoContent(http.StatusNoContent)
+	case errNotFound:
+		return problem.NotFound(c, err.Error())
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+var deleteStatements = []string{
+	// Descendants are now one level closer to the ancestors of id.
+	`UPDATE category_paths SET depth = depth - 1
+	WHERE ancestor IN (SELECT ancestor FROM category_paths WHERE descendant = $1 AND depth > 0)
+	AND descendant IN (SELECT descendant FROM category_paths WHERE ancestor = $1 AND depth > 0)`,
+	"DELETE FROM category_paths WHERE ancestor = $1 OR descendant = $1",
+	"UPDATE categories SET parent_id = (SELECT parent_id FROM categories WHERE id = $1) WHERE parent_id = $1",
+}
+
+// moveExpenses hands the expenses of the deleted category to its parent.
+const moveExpenses = "UPDATE expenses SET category_id = (SELECT parent_id FROM categories WHERE id = $1) WHERE category_id = $1" + outbox.Returning
@@ -0,0 +1,53 @@
+//go:build unit
+
+package category
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+// changedColumns are the columns outbox.AddFrom reads back.
+var changedColumns = []string{"id", "owner", "amount", "category_id", "data"}
+
+func TestDeleteCategory_RecordsMovedExpensesInOutbox(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT parent_id FROM categories").WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+	mock.ExpectExec("UPDATE category_paths SET depth").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM category_paths").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE categories SET parent_id").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE expenses SET category_id (.+)INSERT INTO outbox").WithArgs(3, "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(7, "alice", 50.0, 1, `{"id":7}`).AddRow(8, "bob", 20.0, 1, `{"id":8}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "bob").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM categories").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = DeleteCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
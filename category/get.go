@@ -0,0 +1,59 @@
+package category
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func GetAllCategoryHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, "SELECT id, name, parent_id FROM categories ORDER BY id")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		cat := Category{}
+		if err := rows.Scan(&cat.Id, &cat.Name, &cat.ParentId); err != nil {
+			return problem.Internal(c, err)
+		}
+		categories = append(categories, cat)
+	}
+
+	return c.JSON(http.StatusOK, categories)
+}
+
+// GetCategoryByIdHandler returns the category with the names of its
+// ancestors from the root down, e.g. ["Food", "Restaurants", "Coffee"].
+func GetCategoryByIdHandler(c echo.Context) error {
+	id := c.Param("id")
+	if len(id) == 0 {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	cat := Category{}
+	row := database.Db.QueryRowContext(ctx, `SELECT c.id, c.name, c.parent_id,
+	ARRAY(SELECT a.name FROM category_paths p JOIN categories a ON a.id = p.ancestor WHERE p.descendant = c.id ORDER BY p.depth DESC)
+	FROM categories c WHERE c.id = $1`, id)
+	err := row.Scan(&cat.Id, &cat.Name, &cat.ParentId, pq.Array(&cat.Path))
+	switch err {
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "category not found")
+	case nil:
+		return c.JSON(http.StatusOK, cat)
+	default:
+		return problem.Internal(c, err)
+	}
+}
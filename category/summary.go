@@ -0,0 +1,90 @@
+package category
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/filter"
+	"github.com/umateedev/assessment/problem"
+)
+
+// selectSummary rolls the spend of every descendant up to each category.
+const selectSummary = `SELECT c.id, c.name, c.parent_id, COALESCE(sum(e.amount), 0), count(e.id)
+FROM categories c
+JOIN category_paths p ON p.ancestor = c.id
+LEFT JOIN expenses e ON e.category_id = p.descendant`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSummary(s scanner) (Summary, error) {
+	sum := Summary{}
+	err := s.Scan(&sum.Id, &sum.Name, &sum.ParentId, &sum.Total, &sum.Count)
+	return sum, err
+}
+
+// summaryQuery restricts the rolled-up expenses to those matching the filter
+// expression in ?filter=, if any. The parameters in args come first.
+func summaryQuery(c echo.Context, args []interface{}) (string, []interface{}, error) {
+	query := selectSummary
+	if expr := c.QueryParam("filter"); len(expr) > 0 {
+		n, err := filter.Parse(expr)
+		if err != nil {
+			return "", nil, err
+		}
+		var where string
+		where, args = filter.Compile(n, "e", args)
+		query += " AND " + where
+	}
+	return query, args, nil
+}
+
+func GetAllSummaryHandler(c echo.Context) error {
+	query, args, err := summaryQuery(c, nil)
+	if err != nil {
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeInvalidFilter, err.Error()))
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, query+" GROUP BY c.id ORDER BY c.id", args...)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	summaries := []Summary{}
+	for rows.Next() {
+		sum, err := scanSummary(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		summaries = append(summaries, sum)
+	}
+
+	return c.JSON(http.StatusOK, summaries)
+}
+
+func GetSummaryByIdHandler(c echo.Context) error {
+	query, args, err := summaryQuery(c, []interface{}{c.Param("id")})
+	if err != nil {
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeInvalidFilter, err.Error()))
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	sum, err := scanSummary(database.Db.QueryRowContext(ctx, query+" WHERE c.id = $1 GROUP BY c.id", args...))
+	switch err {
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "category not found")
+	case nil:
+		return c.JSON(http.StatusOK, sum)
+	default:
+		return problem.Internal(c, err)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build unit
+
+package category
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestGetAllSummary_ReturnSuccess(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/categories/summary", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM categories c JOIN category_paths p ON p.ancestor = c.id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "sum", "count"}).
+			AddRow(1, "Food", nil, 300.0, 3).
+			AddRow(2, "Coffee", 1, 100.0, 1))
+
+	err = GetAllSummaryHandler(c)
+
+	expected := "[{\"id\":1,\"name\":\"Food\",\"parent_id\":null,\"total\":300,\"count\":3},{\"id\":2,\"name\":\"Coffee\",\"parent_id\":1,\"total\":100,\"count\":1}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
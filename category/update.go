@@ -0,0 +1,115 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// UpdateCategoryHandler renames a category and moves it, together with its
+// subtree, under parent_id. A null parent_id makes it a root.
+func UpdateCategoryHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	cat := Category{}
+	err = c.Bind(&cat)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+	cat.Id = id
+	cat.Name = strings.TrimSpace(cat.Name)
+	if len(cat.Name) == 0 {
+		return problem.Invalid(c, problem.FieldError{Field: "name", Message: "is required"})
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if err := lockTree(ctx, tx); err != nil {
+			return err
+		}
+		var oldParent *int
+		err := tx.QueryRowContext(ctx, "SELECT parent_id FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&oldParent)
+		if err == sql.ErrNoRows {
+			return errNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !sameParent(oldParent, cat.ParentId) {
+			if cat.ParentId != nil {
+				var cycle, ok bool
+				err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM categories WHERE id = $2),
+				EXISTS (SELECT 1 FROM category_paths WHERE ancestor = $1 AND descendant = $2)`, id, *cat.ParentId).Scan(&ok, &cycle)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errParentNotFound
+				}
+				if cycle {
+					return errCycle
+				}
+			}
+
+			if err := move(ctx, tx, id, cat.ParentId); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3", cat.Name, cat.ParentId, id)
+		return err
+	})
+	switch err {
+	case nil:
+		// Filters such as category:N match the subtree of N, so a move
+		// changes which expenses cached lists hold.
+		cache.ExpensesChanged(ctx)
+		return c.JSON(http.StatusOK, cat)
+	case errNotFound:
+		return problem.NotFound(c, err.Error())
+	case errParentNotFound:
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeCategoryNotFound, err.Error()))
+	case errCycle:
+		return problem.BadRequest(c, "Invalid request, "+err.Error())
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+// move detaches the subtree rooted at id from its old ancestors and attaches
+// it below parent.
+func move(ctx context.Context, tx *sql.Tx, id int, parent *int) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM category_paths
+	WHERE descendant IN (SELECT descendant FROM category_paths WHERE ancestor = $1)
+	AND ancestor NOT IN (SELECT descendant FROM category_paths WHERE ancestor = $1)`, id)
+	if err != nil || parent == nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO category_paths (ancestor, descendant, depth)
+	SELECT super.ancestor, sub.descendant, super.depth + sub.depth + 1
+	FROM category_paths super CROSS JOIN category_paths sub
+	WHERE super.descendant = $2 AND sub.ancestor = $1`, id, *parent)
+	return err
+}
+
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
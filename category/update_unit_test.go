@@ -0,0 +1,116 @@
+//go:build unit
+
+package category
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+)
+
+func updateContext(body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	return c, rec
+}
+
+func TestUpdateCategory_ReturnBadRequest_WhenMovingBelowItself(t *testing.T) {
+	c, rec := updateContext(`{"name": "Food", "parent_id": 3}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT parent_id FROM categories").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(1, 3).WillReturnRows(sqlmock.NewRows([]string{"ok", "cycle"}).AddRow(true, true))
+	mock.ExpectRollback()
+
+	err = UpdateCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestUpdateCategory_MovesSubtree(t *testing.T) {
+	c, rec := updateContext(`{"name": "Coffee", "parent_id": 5}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT parent_id FROM categories").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(2))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(1, 5).WillReturnRows(sqlmock.NewRows([]string{"ok", "cycle"}).AddRow(true, false))
+	mock.ExpectExec("DELETE FROM category_paths").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO category_paths").WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE categories SET name").WithArgs("Coffee", 5, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = UpdateCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestUpdateCategory_DropsCachedExpenses(t *testing.T) {
+	cache.Setup(cache.NewLRU(10), cache.Config{TTL: time.Minute})
+	defer cache.Setup(nil, cache.Config{})
+	ctx := context.Background()
+	cache.ListEntry(ctx, "alice", "filter=category:5").Store(ctx, []byte("[]"))
+	cache.ItemEntry(ctx, 7).Store(ctx, []byte("{}"))
+
+	c, rec := updateContext(`{"name": "Coffee", "parent_id": 5}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT parent_id FROM categories").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(2))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(1, 5).WillReturnRows(sqlmock.NewRows([]string{"ok", "cycle"}).AddRow(true, false))
+	mock.ExpectExec("DELETE FROM category_paths").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO category_paths").WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE categories SET name").WithArgs("Coffee", 5, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = UpdateCategoryHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		_, ok := cache.ListEntry(ctx, "alice", "filter=category:5").Get(ctx)
+		assert.False(t, ok)
+		_, ok = cache.ItemEntry(ctx, 7).Get(ctx)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,26 @@
+package client
+
+import "net/http"
+
+// Auth adds credentials to every request. Implement it, or use AuthFunc, for
+// schemes not provided here.
+type Auth interface {
+	Apply(req *http.Request)
+}
+
+type AuthFunc func(req *http.Request)
+
+func (f AuthFunc) Apply(req *http.Request) { f(req) }
+
+func BasicAuth(username, password string) Auth {
+	return AuthFunc(func(req *http.Request) { req.SetBasicAuth(username, password) })
+}
+
+func BearerToken(token string) Auth {
+	return AuthFunc(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) })
+}
+
+// APIKey sends key in the given header, e.g. X-API-Key.
+func APIKey(header, key string) Auth {
+	return AuthFunc(func(req *http.Request) { req.Header.Set(header, key) })
+}
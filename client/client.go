@@ -0,0 +1,157 @@
+// Package client is a Go client for the Expenses API.
+//
+//	c := client.New("http://localhost:2565", client.WithAuth(client.BasicAuth("user", "pass")))
+//	e, err := c.CreateExpense(ctx, client.Expense{Title: "tea", Amount: 20})
+//
+// Failed calls return *Error, decoded from the server's problem+json body.
+// Idempotent calls (GET and PUT) are retried with exponential backoff on
+// network errors, 429 and 5xx responses other than 500.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       Auth
+	maxRetries int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient, e.g. to set a timeout or proxy.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.httpClient = h }
+}
+
+func WithAuth(a Auth) Option {
+	return func(c *Client) { c.auth = a }
+}
+
+// WithRetry sets how often idempotent calls are retried and the delay before
+// the first retry, which doubles on every attempt. The default is 3 retries
+// starting at 200ms; 0 disables retries.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = maxRetries, backoff }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do sends the request and decodes a 2xx JSON response into out, if out is
+// not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if method == http.MethodGet || method == http.MethodPut {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, u, body)
+		if err == nil && res.StatusCode/100 == 2 {
+			defer res.Body.Close()
+			if out == nil || res.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			return json.NewDecoder(res.Body).Decode(out)
+		}
+
+		var wait time.Duration
+		if err == nil {
+			err = decodeError(res)
+			if !retryable(res.StatusCode) {
+				return err
+			}
+			wait = retryAfter(res)
+		}
+		if ctx.Err() != nil || attempt >= retries {
+			return err
+		}
+
+		if wait == 0 {
+			wait = c.backoff << attempt
+			wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth != nil {
+		c.auth.Apply(req)
+	}
+	return c.httpClient.Do(req)
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads a Retry-After header given in seconds.
+func retryAfter(res *http.Response) time.Duration {
+	s, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+func expensePath(id int) string {
+	return fmt.Sprintf("/expenses/%d", id)
+}
@@ -0,0 +1,137 @@
+//go:build unit
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateExpense(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "November 10", user)
+		assert.Equal(t, "2009", pass)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/expenses", r.URL.Path)
+
+		e := Expense{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		e.Id = 7
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(e)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithAuth(BasicAuth("November 10", "2009")))
+	e, err := c.CreateExpense(context.Background(), Expense{Title: "tea", Amount: 20, Tags: []string{"beverage"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Expense{Id: 7, Title: "tea", Amount: 20, Tags: []string{"beverage"}}, e)
+}
+
+func TestGetExpense_ReturnTypedError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"type":"/problems/not_found","title":"Not Found","status":404,"detail":"expense not found","code":"not_found","request_id":"r1"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithAuth(BearerToken("secret")))
+	_, err := c.GetExpense(context.Background(), 1)
+
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, "not_found", apiErr.Code)
+	assert.Equal(t, "r1", apiErr.RequestId)
+	assert.Equal(t, "expenses api: 404 not_found: expense not found", err.Error())
+}
+
+func TestGetExpense_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(Expense{Id: 1, Title: "tea"})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetry(3, time.Millisecond))
+	e, err := c.GetExpense(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, e.Id)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestCreateExpense_DoesNotRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetry(3, time.Millisecond))
+	_, err := c.CreateExpense(context.Background(), Expense{Title: "tea", Amount: 20})
+
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "http_503", apiErr.Code)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestUpdateExpense_ReturnFieldErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "k", r.Header.Get("X-API-Key"))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"status":422,"code":"validation_failed","errors":[{"field":"amount","message":"must be greater than 0"}]}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithAuth(APIKey("X-API-Key", "k")))
+	_, err := c.UpdateExpense(context.Background(), 1, Expense{Title: "tea"})
+
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []FieldError{{Field: "amount", Message: "must be greater than 0"}}, apiErr.Errors)
+}
+
+func TestListExpenses_IteratesPages(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("after") {
+		case "":
+			json.NewEncoder(w).Encode([]Expense{{Id: 1}, {Id: 2}})
+		case "2":
+			json.NewEncoder(w).Encode([]Expense{{Id: 5}})
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	it := c.ListExpenses(context.Background(), ListOptions{Filter: "tag:food", PageSize: 2})
+
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Expense().Id)
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []int{1, 2, 5}, ids)
+	assert.Equal(t, []string{"filter=tag%3Afood&limit=2", "after=2&filter=tag%3Afood&limit=2"}, queries)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error is a non-2xx response, decoded from its RFC 7807 problem body when
+// there is one.
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Code       string       `json:"code"`
+	RequestId  string       `json:"request_id"`
+	TraceId    string       `json:"trace_id"`
+	Errors     []FieldError `json:"errors"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("expenses api: %d %s", e.StatusCode, e.Code)
+	if len(e.Detail) > 0 {
+		msg += ": " + e.Detail
+	}
+	for _, f := range e.Errors {
+		msg += fmt.Sprintf("; %s %s", f.Field, f.Message)
+	}
+	return msg
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+func decodeError(res *http.Response) error {
+	defer res.Body.Close()
+
+	e := &Error{}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if json.Unmarshal(body, e) != nil || len(e.Code) == 0 {
+		e = &Error{Title: http.StatusText(res.StatusCode), Detail: string(body)}
+	}
+	e.StatusCode = res.StatusCode
+	if len(e.Code) == 0 {
+		e.Code = "http_" + fmt.Sprint(res.StatusCode)
+	}
+	if len(e.RequestId) == 0 {
+		e.RequestId = res.Header.Get("X-Request-Id")
+	}
+	return e
+}
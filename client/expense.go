@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type Expense struct {
+	Id         int      `json:"id,omitempty"`
+	Title      string   `json:"title"`
+	Amount     float64  `json:"amount"`
+	Note       string   `json:"note"`
+	Tags       []string `json:"tags"`
+	CategoryId *int     `json:"category_id,omitempty"`
+	RuleIds    []int64  `json:"rule_ids,omitempty"`
+}
+
+func (c *Client) CreateExpense(ctx context.Context, e Expense) (Expense, error) {
+	var out Expense
+	err := c.do(ctx, http.MethodPost, "/expenses", nil, e, &out)
+	return out, err
+}
+
+func (c *Client) GetExpense(ctx context.Context, id int) (Expense, error) {
+	var out Expense
+	err := c.do(ctx, http.MethodGet, expensePath(id), nil, nil, &out)
+	return out, err
+}
+
+func (c *Client) UpdateExpense(ctx context.Context, id int, e Expense) (Expense, error) {
+	var out Expense
+	err := c.do(ctx, http.MethodPut, expensePath(id), nil, e, &out)
+	return out, err
+}
+
+type ListOptions struct {
+	// Filter is a filter expression such as "tag:food amount>100".
+	Filter string
+	// PageSize is how many expenses are fetched per request, 100 by default.
+	PageSize int
+}
+
+// ListExpenses returns an iterator over every matching expense, fetching
+// pages as needed:
+//
+//	it := c.ListExpenses(ctx, client.ListOptions{Filter: "tag:food"})
+//	for it.Next() {
+//		fmt.Println(it.Expense().Title)
+//	}
+//	if err := it.Err(); err != nil { ... }
+func (c *Client) ListExpenses(ctx context.Context, opts ListOptions) *ExpenseIterator {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 100
+	}
+	return &ExpenseIterator{ctx: ctx, client: c, opts: opts}
+}
+
+type ExpenseIterator struct {
+	ctx    context.Context
+	client *Client
+	opts   ListOptions
+
+	page []Expense
+	i    int
+	last int
+	done bool
+	err  error
+}
+
+// Next advances to the next expense and reports whether there is one.
+func (it *ExpenseIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.i+1 < len(it.page) {
+		it.i++
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(it.opts.PageSize)}}
+	if len(it.opts.Filter) > 0 {
+		query.Set("filter", it.opts.Filter)
+	}
+	if it.last > 0 {
+		query.Set("after", strconv.Itoa(it.last))
+	}
+
+	var page []Expense
+	if it.err = it.client.do(it.ctx, http.MethodGet, "/expenses", query, nil, &page); it.err != nil {
+		return false
+	}
+	it.page, it.i = page, 0
+	it.done = len(page) < it.opts.PageSize
+	if len(page) == 0 {
+		return false
+	}
+	it.last = page[len(page)-1].Id
+	return true
+}
+
+// Expense returns the current expense.
+func (it *ExpenseIterator) Expense() Expense {
+	return it.page[it.i]
+}
+
+func (it *ExpenseIterator) Err() error {
+	return it.err
+}
+
+func (c *Client) DeleteExpense(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, expensePath(id), nil, nil, nil)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func completionCommand(c *cli, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: expensectl completion bash|zsh|fish")
+	}
+
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	words := strings.Join(names, " ")
+
+	switch args[0] {
+	case "bash":
+		fmt.Fprintf(c.stdout, bashCompletion, words)
+	case "zsh":
+		fmt.Fprintf(c.stdout, "#compdef expensectl\nautoload -U +X bashcompinit && bashcompinit\n"+bashCompletion, words)
+	case "fish":
+		fmt.Fprintf(c.stdout, fishCompletion, words, words)
+	default:
+		return fmt.Errorf("unsupported shell %q", args[0])
+	}
+	return nil
+}
+
+// Profiles are completed by asking expensectl itself, so new ones show up
+// without regenerating the script.
+const bashCompletion = `_expensectl() {
+	local cur prev
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+	--profile)
+		COMPREPLY=($(compgen -W "$(expensectl __profiles 2>/dev/null)" -- "$cur"))
+		return ;;
+	-o|--format)
+		COMPREPLY=($(compgen -W "table json csv" -- "$cur"))
+		return ;;
+	--by)
+		COMPREPLY=($(compgen -W "tag category" -- "$cur"))
+		return ;;
+	completion)
+		COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+		return ;;
+	import|--file)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return ;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "--profile -o --server --user --password --token --api-key --filter --title --amount --note --tag --category --format --file --by --default" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -W "%s" -- "$cur"))
+}
+complete -F _expensectl expensectl
+`
+
+const fishCompletion = `complete -c expensectl -f
+complete -c expensectl -n "not __fish_seen_subcommand_from %s" -a "%s"
+complete -c expensectl -l profile -x -a "(expensectl __profiles 2>/dev/null)"
+complete -c expensectl -s o -x -a "table json csv"
+complete -c expensectl -l by -x -a "tag category"
+complete -c expensectl -l format -x -a "csv json"
+complete -c expensectl -l filter -x
+complete -c expensectl -l server -x
+`
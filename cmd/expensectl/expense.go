@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/umateedev/assessment/client"
+)
+
+// expenseFlags registers the fields shared by add and edit.
+type expenseFlags struct {
+	title    string
+	amount   float64
+	note     string
+	tags     stringsFlag
+	category int
+}
+
+func (f *expenseFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.title, "title", "", "title")
+	fs.Float64Var(&f.amount, "amount", 0, "amount in the base currency")
+	fs.StringVar(&f.note, "note", "", "note")
+	fs.Var(&f.tags, "tag", "tag, may be repeated")
+	fs.IntVar(&f.category, "category", 0, "category id")
+}
+
+// apply copies the flags that were set on the command line to e.
+func (f *expenseFlags) apply(fs *flag.FlagSet, e *client.Expense) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "title":
+			e.Title = f.title
+		case "amount":
+			e.Amount = f.amount
+		case "note":
+			e.Note = f.note
+		case "tag":
+			e.Tags = f.tags
+		case "category":
+			id := f.category
+			e.CategoryId = &id
+		}
+	})
+}
+
+func addCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "add")
+	f := &expenseFlags{}
+	f.register(fs)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	e := client.Expense{Tags: []string{}}
+	f.apply(fs, &e)
+	e, err = api.CreateExpense(context.Background(), e)
+	if err != nil {
+		return err
+	}
+	return c.printExpenses([]client.Expense{e}, true)
+}
+
+func getCommand(c *cli, args []string) error {
+	ids, err := parseIds(newFlagSet(c, "get"), args)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	var expenses []client.Expense
+	for _, id := range ids {
+		e, err := api.GetExpense(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		expenses = append(expenses, e)
+	}
+	return c.printExpenses(expenses, len(ids) == 1)
+}
+
+func listCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "list")
+	expr := fs.String("filter", "", "filter expression, e.g. \"tag:food amount>100\"")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	expenses, err := c.list(*expr)
+	if err != nil {
+		return err
+	}
+	return c.printExpenses(expenses, false)
+}
+
+func (c *cli) list(expr string) ([]client.Expense, error) {
+	api, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	expenses := []client.Expense{}
+	it := api.ListExpenses(context.Background(), client.ListOptions{Filter: expr})
+	for it.Next() {
+		expenses = append(expenses, it.Expense())
+	}
+	return expenses, it.Err()
+}
+
+func editCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "edit")
+	f := &expenseFlags{}
+	f.register(fs)
+	ids, err := parseIds(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return errors.New("edit takes exactly one id")
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	e, err := api.GetExpense(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	f.apply(fs, &e)
+	e, err = api.UpdateExpense(ctx, ids[0], e)
+	if err != nil {
+		return err
+	}
+	return c.printExpenses([]client.Expense{e}, true)
+}
+
+func rmCommand(c *cli, args []string) error {
+	ids, err := parseIds(newFlagSet(c, "rm"), args)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := api.DeleteExpense(context.Background(), id); err != nil {
+			return fmt.Errorf("expense %d: %w", id, err)
+		}
+		fmt.Fprintf(c.stderr, "deleted expense %d\n", id)
+	}
+	return nil
+}
+
+func parseIds(fs *flag.FlagSet, args []string) ([]int, error) {
+	positional, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) == 0 {
+		return nil, errors.New("missing expense id")
+	}
+
+	var ids []int
+	for _, s := range positional {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expense id %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func loginCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "login")
+	name := fs.String("profile", c.profile, "profile name")
+	makeDefault := fs.Bool("default", false, "use this profile when --profile is not given")
+	p := Profile{}
+	fs.StringVar(&p.Server, "server", c.overrides.Server, "server URL")
+	fs.StringVar(&p.Username, "user", c.overrides.Username, "basic auth username")
+	fs.StringVar(&p.Password, "password", c.overrides.Password, "basic auth password, read from stdin when omitted")
+	fs.StringVar(&p.Token, "token", c.overrides.Token, "bearer token")
+	fs.StringVar(&p.APIKey, "api-key", c.overrides.APIKey, "API key")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if len(*name) == 0 {
+		return errors.New("missing --profile")
+	}
+	if len(p.Server) == 0 {
+		return errors.New("missing --server")
+	}
+	if len(p.Username) > 0 && len(p.Password) == 0 {
+		fmt.Fprint(c.stderr, "Password: ")
+		line, err := bufio.NewReader(c.stdin).ReadString('\n')
+		if err != nil && len(line) == 0 {
+			return errors.New("no password given")
+		}
+		p.Password = strings.TrimRight(line, "\r\n")
+	}
+
+	c.config.Profiles[*name] = &p
+	if *makeDefault || len(c.config.Default) == 0 {
+		c.config.Default = *name
+	}
+	if err := c.config.save(c.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "saved profile %s to %s\n", *name, c.configPath)
+	return nil
+}
@@ -0,0 +1,212 @@
+// Command expensectl manages expenses from the terminal.
+//
+//	expensectl login --profile local --server http://localhost:2565 --user "November 10"
+//	expensectl add --title "tea" --amount 20 --tag beverage
+//	expensectl list --filter "tag:food amount>100" -o csv
+//	expensectl report --by tag
+//
+// Run expensectl help for every command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/umateedev/assessment/client"
+)
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	config     *Config
+	profile    string
+	output     string
+	overrides  Profile
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"add", "--title T --amount N [--note N] [--tag T]... [--category ID]", "Create an expense", addCommand},
+		{"get", "ID...", "Show expenses", getCommand},
+		{"list", "[--filter EXPR]", "List expenses", listCommand},
+		{"edit", "ID [--title T] [--amount N] [--note N] [--tag T]... [--category ID]", "Change an expense", editCommand},
+		{"rm", "ID...", "Delete expenses and their attachments", rmCommand},
+		{"import", "FILE|- [--format csv|json]", "Create expenses from a CSV or JSON file", importCommand},
+		{"export", "[--filter EXPR] [--format csv|json] [--file FILE]", "Write expenses as CSV or JSON", exportCommand},
+		{"report", "[--filter EXPR] [--by tag|category]", "Total spending per tag or category", reportCommand},
+		{"login", "--profile NAME --server URL [--user U] [--password P] [--token T] [--api-key K] [--default]", "Save a server profile", loginCommand},
+		{"completion", "bash|zsh|fish", "Print a shell completion script", completionCommand},
+		{"help", "", "Show this help", helpCommand},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("expensectl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { c.usage() }
+	fs.StringVar(&c.profile, "profile", "", "profile from the config file")
+	fs.StringVar(&c.output, "o", "table", "output format: table, json or csv")
+	fs.StringVar(&c.overrides.Server, "server", "", "server URL, overrides the profile")
+	fs.StringVar(&c.overrides.Username, "user", "", "basic auth username, overrides the profile")
+	fs.StringVar(&c.overrides.Password, "password", "", "basic auth password, overrides the profile")
+	fs.StringVar(&c.overrides.Token, "token", "", "bearer token, overrides the profile")
+	fs.StringVar(&c.overrides.APIKey, "api-key", "", "API key, overrides the profile")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		c.usage()
+		return 2
+	}
+	switch c.output {
+	case "table", "json", "csv":
+	default:
+		fmt.Fprintf(stderr, "expensectl: unknown output format %q\n", c.output)
+		return 2
+	}
+
+	var err error
+	if c.configPath, err = configPath(); err == nil {
+		c.config, err = loadConfig(c.configPath)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "expensectl:", err)
+		return 1
+	}
+
+	if rest[0] == "__profiles" {
+		fmt.Fprintln(stdout, strings.Join(c.config.names(), "\n"))
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name != rest[0] {
+			continue
+		}
+		err := cmd.run(c, rest[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "expensectl:", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "expensectl: unknown command %q\n", rest[0])
+	c.usage()
+	return 2
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: expensectl [--profile NAME] [-o table|json|csv] COMMAND [ARGS]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+func helpCommand(c *cli, args []string) error {
+	c.usage()
+	fmt.Fprintln(c.stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  expensectl %s %s\n", cmd.name, cmd.args)
+	}
+	return nil
+}
+
+// client builds an API client from the selected profile and the global flags.
+func (c *cli) client() (*client.Client, error) {
+	p, err := c.config.profile(c.profile)
+	if err != nil {
+		return nil, err
+	}
+	merged := *p
+	for _, o := range []struct {
+		dst *string
+		src string
+	}{
+		{&merged.Server, c.overrides.Server},
+		{&merged.Username, c.overrides.Username},
+		{&merged.Password, c.overrides.Password},
+		{&merged.Token, c.overrides.Token},
+		{&merged.APIKey, c.overrides.APIKey},
+	} {
+		if len(o.src) > 0 {
+			*o.dst = o.src
+		}
+	}
+	if len(merged.Server) == 0 {
+		return nil, errors.New("no server configured, run expensectl login or pass --server")
+	}
+
+	var opts []client.Option
+	switch {
+	case len(merged.Token) > 0:
+		opts = append(opts, client.WithAuth(client.BearerToken(merged.Token)))
+	case len(merged.APIKey) > 0:
+		opts = append(opts, client.WithAuth(client.APIKey("X-API-Key", merged.APIKey)))
+	case len(merged.Username) > 0:
+		opts = append(opts, client.WithAuth(client.BasicAuth(merged.Username, merged.Password)))
+	}
+	return client.New(merged.Server, opts...), nil
+}
+
+// parse parses flags that may appear before, between or after positional
+// arguments, and returns the positional ones.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(c *cli, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("expensectl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.output, "o", c.output, "output format: table, json or csv")
+	return fs
+}
+
+// stringsFlag collects a repeated flag such as --tag a --tag b.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
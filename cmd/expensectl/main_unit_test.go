@@ -0,0 +1,139 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/client"
+)
+
+// fakeServer keeps expenses in memory and serves the /expenses endpoints.
+func fakeServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	expenses := map[int]client.Expense{}
+	next := 1
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if user, pass, _ := r.BasicAuth(); user != "November 10" || pass != "2009" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/expenses/"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/expenses":
+			e := client.Expense{}
+			json.NewDecoder(r.Body).Decode(&e)
+			e.Id = next
+			next++
+			expenses[e.Id] = e
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(e)
+		case r.Method == http.MethodGet && r.URL.Path == "/expenses":
+			after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			page := []client.Expense{}
+			for i := after + 1; i < next && len(page) < limit; i++ {
+				if e, ok := expenses[i]; ok {
+					page = append(page, e)
+				}
+			}
+			json.NewEncoder(w).Encode(page)
+		case r.Method == http.MethodPut:
+			e := client.Expense{}
+			json.NewDecoder(r.Body).Decode(&e)
+			e.Id = id
+			expenses[id] = e
+			json.NewEncoder(w).Encode(e)
+		case r.Method == http.MethodDelete:
+			delete(expenses, id)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet:
+			e, ok := expenses[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"status":404,"code":"not_found","detail":"expense not found"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(e)
+		}
+	}))
+}
+
+func expensectl(t *testing.T, stdin string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestExpensectl(t *testing.T) {
+	srv := fakeServer(t)
+	defer srv.Close()
+	config := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("EXPENSECTL_CONFIG", config)
+
+	_, _, code := expensectl(t, "2009\n", "login", "--profile", "local", "--server", srv.URL, "--user", "November 10")
+	assert.Equal(t, 0, code)
+	info, err := os.Stat(config)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	out, _, code := expensectl(t, "", "-o", "json", "add", "--title", "tea", "--amount", "20", "--tag", "beverage")
+	assert.Equal(t, 0, code)
+	assert.JSONEq(t, `{"id": 1, "title": "tea", "amount": 20, "note": "", "tags": ["beverage"]}`, out)
+
+	_, stderr, code := expensectl(t, "title,amount,tags\nnoodles,60,food\nsteak,450,food;dinner\n", "import", "--format", "csv", "-")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "imported 2 of 2 expenses")
+
+	_, _, code = expensectl(t, "", "edit", "2", "--amount", "65")
+	assert.Equal(t, 0, code)
+
+	out, _, code = expensectl(t, "", "export")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "id,title,amount,note,tags,category_id\n1,tea,20.00,,beverage,\n2,noodles,65.00,,food,\n3,steak,450.00,,food;dinner,\n", out)
+
+	out, _, code = expensectl(t, "", "report", "-o", "csv")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "tag,count,total\nfood,2,515.00\ndinner,1,450.00\nbeverage,1,20.00\n", out)
+
+	_, _, code = expensectl(t, "", "rm", "1")
+	assert.Equal(t, 0, code)
+
+	_, stderr, code = expensectl(t, "", "get", "1")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "expensectl: expenses api: 404 not_found: expense not found\n", stderr)
+
+	out, _, code = expensectl(t, "", "list")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ID  TITLE    AMOUNT  NOTE  TAGS         CATEGORY\n2   noodles  65.00         food         \n3   steak    450.00        food;dinner  \n", out)
+}
+
+func TestExpensectl_RequiresServer(t *testing.T) {
+	t.Setenv("EXPENSECTL_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	_, stderr, code := expensectl(t, "", "list")
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no server configured")
+}
+
+func TestReadCSV_RejectsInvalidAmount(t *testing.T) {
+	_, err := readCSV(strings.NewReader("title,amount\ntea,abc\n"))
+
+	assert.EqualError(t, err, `csv line 2: invalid amount "abc"`)
+}
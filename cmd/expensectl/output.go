@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/umateedev/assessment/client"
+)
+
+var expenseHeader = []string{"id", "title", "amount", "note", "tags", "category_id"}
+
+// printExpenses writes expenses in the format chosen with -o. single prints a
+// JSON object rather than an array.
+func (c *cli) printExpenses(expenses []client.Expense, single bool) error {
+	switch c.output {
+	case "json":
+		if single && len(expenses) == 1 {
+			return writeJSON(c.stdout, expenses[0])
+		}
+		return writeJSON(c.stdout, expenses)
+	case "csv":
+		return writeCSV(c.stdout, expenses)
+	case "table":
+	default:
+		return fmt.Errorf("unknown output format %q", c.output)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tAMOUNT\tNOTE\tTAGS\tCATEGORY")
+	for _, e := range expenses {
+		row := expenseRow(e)
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func expenseRow(e client.Expense) []string {
+	category := ""
+	if e.CategoryId != nil {
+		category = strconv.Itoa(*e.CategoryId)
+	}
+	return []string{
+		strconv.Itoa(e.Id),
+		e.Title,
+		strconv.FormatFloat(e.Amount, 'f', 2, 64),
+		e.Note,
+		strings.Join(e.Tags, ";"),
+		category,
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeCSV writes expenses in the layout import reads back.
+func writeCSV(w io.Writer, expenses []client.Expense) error {
+	cw := csv.NewWriter(w)
+	cw.Write(expenseHeader)
+	for _, e := range expenses {
+		cw.Write(expenseRow(e))
+	}
+	cw.Flush()
+	return cw.Error()
+}
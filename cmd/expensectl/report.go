@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/umateedev/assessment/client"
+)
+
+type reportRow struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+}
+
+func reportCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "report")
+	expr := fs.String("filter", "", "filter expression")
+	by := fs.String("by", "tag", "tag or category")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *by != "tag" && *by != "category" {
+		return fmt.Errorf("unknown grouping %q, use tag or category", *by)
+	}
+
+	expenses, err := c.list(*expr)
+	if err != nil {
+		return err
+	}
+	rows := report(expenses, *by)
+
+	switch c.output {
+	case "json":
+		return writeJSON(c.stdout, rows)
+	case "csv":
+		cw := csv.NewWriter(c.stdout)
+		cw.Write([]string{*by, "count", "total"})
+		for _, r := range rows {
+			cw.Write([]string{r.Key, strconv.Itoa(r.Count), strconv.FormatFloat(r.Total, 'f', 2, 64)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+	default:
+		return fmt.Errorf("unknown output format %q", c.output)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tCOUNT\tTOTAL\t\n", map[string]string{"tag": "TAG", "category": "CATEGORY"}[*by])
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t\n", r.Key, r.Count, r.Total)
+	}
+	total := 0.0
+	for _, e := range expenses {
+		total += e.Amount
+	}
+	fmt.Fprintf(w, "ALL\t%d\t%.2f\t\n", len(expenses), total)
+	return w.Flush()
+}
+
+// report totals expenses per tag or category, largest first. An expense with
+// several tags counts towards each of them; untagged and uncategorized
+// expenses are grouped under "-".
+func report(expenses []client.Expense, by string) []reportRow {
+	groups := map[string]*reportRow{}
+	add := func(key string, amount float64) {
+		r, ok := groups[key]
+		if !ok {
+			r = &reportRow{Key: key}
+			groups[key] = r
+		}
+		r.Count++
+		r.Total += amount
+	}
+
+	for _, e := range expenses {
+		switch {
+		case by == "category" && e.CategoryId != nil:
+			add(strconv.Itoa(*e.CategoryId), e.Amount)
+		case by == "tag" && len(e.Tags) > 0:
+			for _, t := range e.Tags {
+				add(t, e.Amount)
+			}
+		default:
+			add("-", e.Amount)
+		}
+	}
+
+	rows := []reportRow{}
+	for _, r := range groups {
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Total != rows[j].Total {
+			return rows[i].Total > rows[j].Total
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/umateedev/assessment/client"
+)
+
+func importCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "import")
+	format := fs.String("format", "", "csv or json, by default taken from the file extension")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("import takes one file, or - for stdin")
+	}
+
+	var r io.Reader = c.stdin
+	if files[0] != "-" {
+		f, err := os.Open(files[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		if len(*format) == 0 {
+			*format = strings.TrimPrefix(filepath.Ext(files[0]), ".")
+		}
+	}
+
+	var expenses []client.Expense
+	switch strings.ToLower(*format) {
+	case "csv":
+		expenses, err = readCSV(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&expenses)
+	default:
+		return errors.New("unknown import format, pass --format csv or --format json")
+	}
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, e := range expenses {
+		e.Id = 0
+		if e.Tags == nil {
+			e.Tags = []string{}
+		}
+		if _, err := api.CreateExpense(context.Background(), e); err != nil {
+			fmt.Fprintf(c.stderr, "record %d: %v\n", i+1, err)
+			failed++
+		}
+	}
+
+	fmt.Fprintf(c.stderr, "imported %d of %d expenses\n", len(expenses)-failed, len(expenses))
+	if failed > 0 {
+		return fmt.Errorf("%d expenses failed to import", failed)
+	}
+	return nil
+}
+
+// readCSV reads the layout written by export. Columns are matched by the
+// header, so id and category_id may be left out; tags are separated by ';'.
+func readCSV(r io.Reader) ([]client.Expense, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"title", "amount"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("csv: missing %s column", required)
+		}
+	}
+
+	var expenses []client.Expense
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return expenses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(name string) string {
+			if i, ok := col[name]; ok {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		e := client.Expense{Title: get("title"), Note: get("note"), Tags: []string{}}
+		if e.Amount, err = strconv.ParseFloat(get("amount"), 64); err != nil {
+			return nil, fmt.Errorf("csv line %d: invalid amount %q", line, get("amount"))
+		}
+		for _, t := range strings.Split(get("tags"), ";") {
+			if t = strings.TrimSpace(t); len(t) > 0 {
+				e.Tags = append(e.Tags, t)
+			}
+		}
+		if s := get("category_id"); len(s) > 0 {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid category_id %q", line, s)
+			}
+			e.CategoryId = &id
+		}
+		expenses = append(expenses, e)
+	}
+}
+
+func exportCommand(c *cli, args []string) error {
+	fs := newFlagSet(c, "export")
+	expr := fs.String("filter", "", "filter expression")
+	format := fs.String("format", "csv", "csv or json")
+	file := fs.String("file", "", "write to FILE instead of stdout")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	expenses, err := c.list(*expr)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = c.stdout
+	if len(*file) > 0 {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		return writeCSV(w, expenses)
+	case "json":
+		return writeJSON(w, expenses)
+	}
+	return fmt.Errorf("unknown export format %q", *format)
+}
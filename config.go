@@ -0,0 +1,796 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/attachment"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/nats"
+	"github.com/umateedev/assessment/openapi"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/ratelimit"
+	"github.com/umateedev/assessment/resp"
+	"github.com/umateedev/assessment/telemetry"
+	"github.com/umateedev/assessment/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the configuration shared by every subcommand. It is layered:
+// defaults, then the YAML file named by --config or CONFIG_FILE, then
+// environment variables, then flags.
+//
+// Fields tagged reload:"true" are applied on SIGHUP; changing any other
+// field needs a restart.
+type config struct {
+	Listen            string           `yaml:"listen" env:"PORT" flag:"listen"`
+	TrustedProxies    []string         `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	Database          databaseConfig   `yaml:"database"`
+	Auth              authConfig       `yaml:"auth"`
+	CORS              corsConfig       `yaml:"cors"`
+	RateLimit         rateLimitConfig  `yaml:"rate_limit"`
+	Cache             cacheConfig      `yaml:"cache"`
+	Outbox            outboxConfig     `yaml:"outbox"`
+	Attachments       attachmentConfig `yaml:"attachments"`
+	Tags              tagConfig        `yaml:"tags"`
+	RefreshInterval   time.Duration    `yaml:"refresh_interval" env:"REFRESH_INTERVAL"`
+	Validation        validationConfig `yaml:"validation"`
+	Currency          currencyConfig   `yaml:"currency"`
+	Timeouts          timeoutConfig    `yaml:"timeouts"`
+	OpenAPIValidation string           `yaml:"openapi_validation" env:"OPENAPI_VALIDATION" reload:"true"`
+	Features          featureConfig    `yaml:"features"`
+	Tracing           tracingConfig    `yaml:"tracing"`
+	Log               logConfig        `yaml:"log"`
+}
+
+// ipExtractor returns how the client address is found, which rate limits
+// key anonymous requests by. It is the peer address unless that is one of
+// TrustedProxies, in which case X-Forwarded-For is followed back to the
+// first address that is not.
+func (c config) ipExtractor() (echo.IPExtractor, error) {
+	if len(c.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	opts := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range c.TrustedProxies {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a CIDR range", p)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
+
+type databaseConfig struct {
+	URL             string        `yaml:"url" env:"DATABASE_URL" flag:"database-url" redact:"true"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// ConnectTimeout is how long startup waits for the database to answer.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	QueryTimeout   time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+}
+
+// Auth modes.
+const (
+	authBasic = "basic"
+	// authNone turns authentication off; meant for local development only.
+	authNone = "none"
+)
+
+type authConfig struct {
+	Mode string `yaml:"mode" env:"AUTH_MODE" flag:"auth"`
+	// Admins are the usernames allowed on /admin routes. With mode none
+	// nobody is.
+	Admins []string `yaml:"admins" env:"AUTH_ADMINS" reload:"true"`
+}
+
+// corsConfig enables CORS for the listed origins; empty leaves it off.
+type corsConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" env:"CORS_ALLOW_ORIGINS" reload:"true"`
+}
+
+// Rate limit backends.
+const (
+	rateLimitMemory = "memory"
+	rateLimitRedis  = "redis"
+)
+
+// rateLimitConfig limits requests per API key or user, or per IP without
+// authentication, in each route group; a zero RPS leaves it off. Groups
+// overrides the limit of a route group as "group=rps" or "group=rps:burst".
+// AuthRPS limits every IP in front of authentication, whatever RPS is, so
+// that passwords cannot be guessed at full speed.
+type rateLimitConfig struct {
+	RPS       float64  `yaml:"rps" env:"RATE_LIMIT_RPS" reload:"true"`
+	Burst     int      `yaml:"burst" env:"RATE_LIMIT_BURST" reload:"true"`
+	AuthRPS   float64  `yaml:"auth_rps" env:"RATE_LIMIT_AUTH_RPS" reload:"true"`
+	AuthBurst int      `yaml:"auth_burst" env:"RATE_LIMIT_AUTH_BURST" reload:"true"`
+	Groups    []string `yaml:"groups" env:"RATE_LIMIT_GROUPS" reload:"true"`
+	Backend   string   `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	RedisURL  string   `yaml:"redis_url" env:"RATE_LIMIT_REDIS_URL" redact:"true"`
+}
+
+func (r rateLimitConfig) limits() (ratelimit.Limits, error) {
+	limits := ratelimit.Limits{Default: limit(r.RPS, r.Burst), Groups: map[string]ratelimit.Limit{
+		ratelimit.AuthGroup: limit(r.AuthRPS, r.AuthBurst),
+	}}
+	for _, s := range r.Groups {
+		group, value, ok := strings.Cut(s, "=")
+		rps, burst, _ := strings.Cut(value, ":")
+		rate, err := strconv.ParseFloat(rps, 64)
+		b := 0
+		if err == nil && len(burst) > 0 {
+			b, err = strconv.Atoi(burst)
+		}
+		if !ok || len(group) == 0 || err != nil || rate < 0 || b < 0 {
+			return limits, fmt.Errorf("%q is not group=rps or group=rps:burst", s)
+		}
+		limits.Groups[group] = limit(rate, b)
+	}
+	return limits, nil
+}
+
+// limit defaults burst to one second's worth of requests.
+func limit(rps float64, burst int) ratelimit.Limit {
+	if burst == 0 {
+		burst = int(math.Ceil(rps))
+	}
+	return ratelimit.Limit{Rate: rps, Burst: burst}
+}
+
+// store opens the configured backend.
+func (r rateLimitConfig) store() (ratelimit.Store, error) {
+	if r.Backend == rateLimitRedis {
+		return ratelimit.NewRedisStore(r.RedisURL)
+	}
+	return ratelimit.NewMemoryStore(), nil
+}
+
+// Cache backends.
+const (
+	cacheOff    = "off"
+	cacheMemory = "memory"
+	cacheRedis  = "redis"
+)
+
+// cacheConfig caches expense reads; see package cache. Use the redis
+// backend when running more than one instance.
+type cacheConfig struct {
+	Backend       string        `yaml:"backend" env:"CACHE_BACKEND"`
+	TTL           time.Duration `yaml:"ttl" env:"CACHE_TTL"`
+	MaxEntries    int           `yaml:"max_entries" env:"CACHE_MAX_ENTRIES"`
+	MaxEntryBytes int           `yaml:"max_entry_bytes" env:"CACHE_MAX_ENTRY_BYTES"`
+	RedisURL      string        `yaml:"redis_url" env:"CACHE_REDIS_URL" redact:"true"`
+}
+
+// setup opens the configured backend and hands it to package cache. The
+// returned func closes it.
+func (c cacheConfig) setup() (func(), error) {
+	var b cache.Backend
+	closeFn := func() {}
+	switch c.Backend {
+	case cacheMemory:
+		b = cache.NewLRU(c.MaxEntries)
+	case cacheRedis:
+		r, err := cache.NewRedis(c.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		b, closeFn = r, r.Close
+	}
+	cache.Setup(b, cache.Config{TTL: c.TTL, MaxEntryBytes: c.MaxEntryBytes})
+	return closeFn, nil
+}
+
+// shared sets up the cache for a command run next to the server, so that
+// its writes reach the server's cache. Only the redis backend is shared;
+// the server's memory cache catches up within the TTL.
+func (c cacheConfig) shared() (func(), error) {
+	if c.Backend != cacheRedis {
+		return func() {}, nil
+	}
+	return c.setup()
+}
+
+// Outbox publishers.
+const (
+	outboxMemory = "memory"
+	outboxFile   = "file"
+	outboxNATS   = "nats"
+)
+
+// outboxConfig publishes expense changes recorded in the outbox; see
+// package outbox.
+type outboxConfig struct {
+	Publisher  string        `yaml:"publisher" env:"OUTBOX_PUBLISHER"`
+	MemorySize int           `yaml:"memory_size" env:"OUTBOX_MEMORY_SIZE"`
+	File       string        `yaml:"file" env:"OUTBOX_FILE"`
+	NATSURL    string        `yaml:"nats_url" env:"OUTBOX_NATS_URL" redact:"true"`
+	Subject    string        `yaml:"subject" env:"OUTBOX_SUBJECT"`
+	Interval   time.Duration `yaml:"interval" env:"OUTBOX_INTERVAL"`
+	Retention  time.Duration `yaml:"retention" env:"OUTBOX_RETENTION"`
+	// ReplaySize is how many events GET /expenses/stream keeps for
+	// clients resuming with Last-Event-ID.
+	ReplaySize int `yaml:"replay_size" env:"OUTBOX_REPLAY_SIZE"`
+}
+
+// publisher opens the configured publisher.
+func (o outboxConfig) publisher() (outbox.Publisher, error) {
+	switch o.Publisher {
+	case outboxFile:
+		return outbox.NewFile(o.File)
+	case outboxNATS:
+		return outbox.NewNATS(o.NATSURL, o.Subject)
+	}
+	return outbox.NewMemory(o.MemorySize), nil
+}
+
+// attachmentConfig picks where attachment blobs are kept; see package
+// attachment.
+type attachmentConfig struct {
+	Storage string   `yaml:"storage" env:"ATTACHMENT_STORAGE"`
+	Dir     string   `yaml:"dir" env:"ATTACHMENT_DIR"`
+	MaxSize int      `yaml:"max_size" env:"ATTACHMENT_MAX_SIZE"`
+	S3      s3Config `yaml:"s3"`
+}
+
+type s3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY" redact:"true"`
+}
+
+func (a attachmentConfig) attachment() attachment.Config {
+	return attachment.Config{
+		Storage: a.Storage,
+		Dir:     a.Dir,
+		MaxSize: int64(a.MaxSize),
+		S3: attachment.S3Config{
+			Endpoint:  a.S3.Endpoint,
+			Region:    a.S3.Region,
+			Bucket:    a.S3.Bucket,
+			AccessKey: a.S3.AccessKey,
+			SecretKey: a.S3.SecretKey,
+		},
+	}
+}
+
+// tagConfig sets how tags are normalized before they are stored.
+type tagConfig struct {
+	Trim     bool `yaml:"trim" env:"TAG_TRIM"`
+	FoldCase bool `yaml:"fold_case" env:"TAG_FOLD_CASE"`
+}
+
+// validationConfig tightens the named limits of package validate, each
+// given as "name=value", for everyone and, as "workspace:name=value", for
+// single workspaces. A workspace is the user a request authenticated as.
+type validationConfig struct {
+	Limits     []string `yaml:"limits" env:"VALIDATION_LIMITS" reload:"true"`
+	Workspaces []string `yaml:"workspaces" env:"VALIDATION_WORKSPACE_LIMITS" reload:"true"`
+}
+
+// limits returns the limits for everyone and those of each workspace, which
+// start from the former.
+func (v validationConfig) limits() (validate.Limits, map[string]validate.Limits, error) {
+	limits, err := validate.DefaultLimits().Tighten(v.Limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	overrides := map[string][]string{}
+	var order []string
+	for _, w := range v.Workspaces {
+		name, limit, ok := strings.Cut(w, ":")
+		name = strings.TrimSpace(name)
+		if !ok || len(name) == 0 {
+			return nil, nil, fmt.Errorf("%q is not workspace:name=value", w)
+		}
+		if _, seen := overrides[name]; !seen {
+			order = append(order, name)
+		}
+		overrides[name] = append(overrides[name], limit)
+	}
+	workspaces := make(map[string]validate.Limits, len(order))
+	for _, name := range order {
+		if workspaces[name], err = limits.Tighten(overrides[name]); err != nil {
+			return nil, nil, fmt.Errorf("workspace %s: %w", name, err)
+		}
+	}
+	return limits, workspaces, nil
+}
+
+// currencyConfig names the currency amounts are stored in and the rates,
+// as "CODE=rate", that views convert them with.
+type currencyConfig struct {
+	Base  string   `yaml:"base" env:"BASE_CURRENCY"`
+	Rates []string `yaml:"rates" env:"CURRENCY_RATES" reload:"true"`
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// rates parses Rates into how much one unit of the base currency is worth
+// in each currency, keyed by upper case code.
+func (c currencyConfig) rates() (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, s := range c.Rates {
+		code, value, ok := strings.Cut(s, "=")
+		code = strings.TrimSpace(code)
+		r, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || !currencyCode.MatchString(code) || err != nil || r <= 0 || math.IsInf(r, 0) {
+			return nil, fmt.Errorf("%q is not CODE=rate with a positive rate", s)
+		}
+		rates[strings.ToUpper(code)] = r
+	}
+	return rates, nil
+}
+
+type timeoutConfig struct {
+	Read     time.Duration `yaml:"read" env:"READ_TIMEOUT"`
+	Write    time.Duration `yaml:"write" env:"WRITE_TIMEOUT"`
+	Idle     time.Duration `yaml:"idle" env:"IDLE_TIMEOUT"`
+	Shutdown time.Duration `yaml:"shutdown" env:"SHUTDOWN_TIMEOUT"`
+}
+
+type featureConfig struct {
+	Scheduler      bool `yaml:"scheduler" env:"FEATURE_SCHEDULER"`
+	ReindexOnStart bool `yaml:"reindex_on_start" env:"FEATURE_REINDEX_ON_START"`
+	Webhooks       bool `yaml:"webhooks" env:"FEATURE_WEBHOOKS"`
+}
+
+// tracingConfig picks where OpenTelemetry spans are exported.
+type tracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+func (t tracingConfig) telemetry() telemetry.Config {
+	return telemetry.Config{Exporter: t.Exporter, Endpoint: t.Endpoint, SampleRatio: t.SampleRatio}
+}
+
+// logConfig sets the log level, overall and per package as "package=level".
+// Levels changed through /admin/log-levels last until the next reload.
+type logConfig struct {
+	Level    string   `yaml:"level" env:"LOG_LEVEL" flag:"log-level" reload:"true"`
+	Packages []string `yaml:"packages" env:"LOG_PACKAGE_LEVELS" reload:"true"`
+}
+
+func (l logConfig) levels() (slog.Level, map[string]slog.Level, error) {
+	def, err := logging.ParseLevel(l.Level)
+	if err != nil {
+		return def, nil, err
+	}
+	known := map[string]bool{}
+	for _, p := range logging.Packages() {
+		known[p] = true
+	}
+	pkgs := map[string]slog.Level{}
+	for _, s := range l.Packages {
+		name, level, ok := strings.Cut(s, "=")
+		if !ok || !known[name] {
+			return def, nil, fmt.Errorf("%q is not package=level for a known package", s)
+		}
+		if pkgs[name], err = logging.ParseLevel(level); err != nil {
+			return def, nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return def, pkgs, nil
+}
+
+// apply sets the log levels; validate has already checked them.
+func (l logConfig) apply() {
+	def, pkgs, _ := l.levels()
+	logging.SetLevels(def, pkgs)
+}
+
+func defaultConfig() config {
+	return config{
+		Listen: ":2565",
+		Database: databaseConfig{
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+			QueryTimeout:    10 * time.Second,
+		},
+		Auth: authConfig{Mode: authBasic},
+		Timeouts: timeoutConfig{
+			Read:     time.Minute,
+			Write:    time.Minute,
+			Idle:     2 * time.Minute,
+			Shutdown: 10 * time.Second,
+		},
+		Features: featureConfig{
+			Scheduler:      true,
+			ReindexOnStart: true,
+			Webhooks:       true,
+		},
+		Tracing: tracingConfig{
+			Exporter:    telemetry.Off,
+			SampleRatio: 1,
+		},
+		RateLimit: rateLimitConfig{AuthRPS: 10, AuthBurst: 20, Backend: rateLimitMemory},
+		Cache: cacheConfig{
+			Backend:       cacheOff,
+			TTL:           time.Minute,
+			MaxEntries:    1000,
+			MaxEntryBytes: 1 << 20,
+		},
+		Outbox: outboxConfig{
+			Publisher:  outboxMemory,
+			MemorySize: 1000,
+			Subject:    "events",
+			Interval:   time.Second,
+			Retention:  7 * 24 * time.Hour,
+			ReplaySize: 1000,
+		},
+		Attachments: attachmentConfig{
+			Storage: attachment.Local,
+			Dir:     "attachments",
+			MaxSize: 10 << 20,
+		},
+		Tags:            tagConfig{Trim: true},
+		RefreshInterval: 30 * time.Second,
+		Currency:        currencyConfig{Base: "THB"},
+		Log:             logConfig{Level: "info"},
+	}
+}
+
+// configLoader remembers where the configuration came from so that it can
+// be loaded again on SIGHUP.
+type configLoader struct {
+	path  string
+	flags []setting
+}
+
+type setting struct {
+	path, value string
+}
+
+// configFlags registers --config and the flags of every field tagged flag
+// on fs.
+func configFlags(fs *flag.FlagSet) *configLoader {
+	l := &configLoader{}
+	fs.StringVar(&l.path, "config", "", "YAML config file (overrides CONFIG_FILE)")
+
+	cfg := config{}
+	for _, f := range fields(reflect.ValueOf(&cfg).Elem(), "") {
+		if len(f.flag) == 0 {
+			continue
+		}
+		path := f.path
+		fs.Func(f.flag, fmt.Sprintf("%s (overrides %s)", path, f.env), func(s string) error {
+			l.flags = append(l.flags, setting{path, s})
+			return nil
+		})
+	}
+	return l
+}
+
+func (l *configLoader) load() (config, error) {
+	cfg := defaultConfig()
+
+	path := l.path
+	if len(path) == 0 {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if len(path) > 0 {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	all := fields(reflect.ValueOf(&cfg).Elem(), "")
+	for _, f := range all {
+		if s, ok := os.LookupEnv(f.env); ok && len(f.env) > 0 {
+			if err := set(f.value, s); err != nil {
+				return cfg, fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+	for _, s := range l.flags {
+		for _, f := range all {
+			if f.path != s.path {
+				continue
+			}
+			if err := set(f.value, s.value); err != nil {
+				return cfg, fmt.Errorf("--%s: %w", f.flag, err)
+			}
+		}
+	}
+
+	// PORT has always been given as ":2565"; accept a bare port too.
+	if _, err := strconv.Atoi(cfg.Listen); err == nil {
+		cfg.Listen = ":" + cfg.Listen
+	}
+
+	return cfg, cfg.validate()
+}
+
+func (c config) validate() error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.Listen)
+	check(err == nil, "listen must be host:port, got %q", c.Listen)
+	_, err = c.ipExtractor()
+	check(err == nil, "trusted_proxies: %v", err)
+	check(len(c.Database.URL) > 0, "database.url is required, set DATABASE_URL")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns must not exceed database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0 && c.Database.ConnMaxIdleTime >= 0, "database connection lifetimes must not be negative")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout must be positive")
+	check(c.Database.QueryTimeout > 0, "database.query_timeout must be positive")
+	check(c.Auth.Mode == authBasic || c.Auth.Mode == authNone, "auth.mode must be %q or %q, got %q", authBasic, authNone, c.Auth.Mode)
+	for _, o := range c.CORS.AllowOrigins {
+		u, err := url.Parse(o)
+		check(o == "*" || err == nil && len(u.Scheme) > 0 && len(u.Host) > 0, "cors.allow_origins: %q is not an origin", o)
+	}
+	check(c.RateLimit.RPS >= 0, "rate_limit.rps must not be negative")
+	check(c.RateLimit.Burst >= 0, "rate_limit.burst must not be negative")
+	check(c.RateLimit.AuthRPS >= 0 && c.RateLimit.AuthBurst >= 0, "rate_limit.auth_rps and auth_burst must not be negative")
+	_, err = c.RateLimit.limits()
+	check(err == nil, "rate_limit.groups: %v", err)
+	check(c.RateLimit.Backend == rateLimitMemory || c.RateLimit.Backend == rateLimitRedis,
+		"rate_limit.backend must be %q or %q, got %q", rateLimitMemory, rateLimitRedis, c.RateLimit.Backend)
+	if c.RateLimit.Backend == rateLimitRedis {
+		err = resp.CheckURL(c.RateLimit.RedisURL)
+		check(err == nil, "rate_limit.redis_url: %v", err)
+	}
+	switch c.Cache.Backend {
+	case cacheOff, cacheMemory:
+	case cacheRedis:
+		err = resp.CheckURL(c.Cache.RedisURL)
+		check(err == nil, "cache.redis_url: %v", err)
+	default:
+		errs = append(errs, fmt.Sprintf("cache.backend must be %q, %q or %q, got %q", cacheOff, cacheMemory, cacheRedis, c.Cache.Backend))
+	}
+	check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	check(c.Cache.MaxEntries > 0, "cache.max_entries must be positive")
+	check(c.Cache.MaxEntryBytes >= 0, "cache.max_entry_bytes must not be negative")
+	switch c.Outbox.Publisher {
+	case outboxMemory:
+	case outboxFile:
+		check(len(c.Outbox.File) > 0, "outbox.file is required with the file publisher")
+	case outboxNATS:
+		err = nats.CheckURL(c.Outbox.NATSURL)
+		check(err == nil, "outbox.nats_url: %v", err)
+		check(len(c.Outbox.Subject) > 0 && !strings.ContainsAny(c.Outbox.Subject, " \t*>"), "outbox.subject must be a NATS subject without wildcards, got %q", c.Outbox.Subject)
+	default:
+		errs = append(errs, fmt.Sprintf("outbox.publisher must be %q, %q or %q, got %q", outboxMemory, outboxFile, outboxNATS, c.Outbox.Publisher))
+	}
+	check(c.Outbox.MemorySize > 0, "outbox.memory_size must be positive")
+	check(c.Outbox.Interval > 0, "outbox.interval must be positive")
+	check(c.Outbox.Retention > 0, "outbox.retention must be positive")
+	check(c.Outbox.ReplaySize > 0, "outbox.replay_size must be positive")
+	switch c.Attachments.Storage {
+	case attachment.Local:
+		check(len(c.Attachments.Dir) > 0, "attachments.dir is required with local storage")
+	case attachment.S3:
+		u, err := url.Parse(c.Attachments.S3.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0,
+			"attachments.s3.endpoint must be an http or https URL, got %q", c.Attachments.S3.Endpoint)
+		check(len(c.Attachments.S3.Bucket) > 0, "attachments.s3.bucket is required with s3 storage")
+		check(len(c.Attachments.S3.AccessKey) > 0 && len(c.Attachments.S3.SecretKey) > 0,
+			"attachments.s3.access_key and secret_key are required with s3 storage")
+	default:
+		errs = append(errs, fmt.Sprintf("attachments.storage must be %q or %q, got %q", attachment.Local, attachment.S3, c.Attachments.Storage))
+	}
+	check(c.Attachments.MaxSize > 0, "attachments.max_size must be positive")
+	_, _, err = c.Validation.limits()
+	check(err == nil, "validation.limits: %v", err)
+	check(currencyCode.MatchString(c.Currency.Base), "currency.base must be a three letter code, got %q", c.Currency.Base)
+	_, err = c.Currency.rates()
+	check(err == nil, "currency.rates: %v", err)
+	check(c.Timeouts.Read >= 0 && c.Timeouts.Write >= 0 && c.Timeouts.Idle >= 0, "timeouts must not be negative")
+	check(c.RefreshInterval > 0, "refresh_interval must be positive")
+	check(c.Timeouts.Shutdown > 0, "timeouts.shutdown must be positive")
+	_, err = openapi.Validator(c.OpenAPIValidation)
+	check(err == nil, "openapi_validation must be %q, %q or %q, got %q", openapi.Off, openapi.Requests, openapi.All, c.OpenAPIValidation)
+	switch c.Tracing.Exporter {
+	case telemetry.Off, telemetry.Stdout, telemetry.OTLP:
+	default:
+		errs = append(errs, fmt.Sprintf("tracing.exporter must be %q, %q or %q, got %q", telemetry.Off, telemetry.Stdout, telemetry.OTLP, c.Tracing.Exporter))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	_, _, err = c.Log.levels()
+	check(err == nil, "log: %v", err)
+
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// reload returns c with the fields that are safe to change while serving
+// taken from next, and the paths of the other fields that differ and so
+// need a restart.
+func (c config) reload(next config) (config, []string) {
+	var restart []string
+	nextFields := fields(reflect.ValueOf(&next).Elem(), "")
+	for i, f := range fields(reflect.ValueOf(&c).Elem(), "") {
+		n := nextFields[i]
+		switch {
+		case f.reload:
+			f.value.Set(n.value)
+		case !reflect.DeepEqual(f.value.Interface(), n.value.Interface()):
+			restart = append(restart, f.path)
+		}
+	}
+	return c, restart
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)[^\s&]+`)
+
+// String lists every setting, one per line, with passwords redacted so that
+// it can be logged.
+func (c config) String() string {
+	var b strings.Builder
+	for _, f := range fields(reflect.ValueOf(&c).Elem(), "") {
+		v := f.value.Interface()
+		switch {
+		case f.redact:
+			v = redact(f.value.String())
+		case f.value.Kind() == reflect.Slice:
+			v = strings.Join(f.value.Interface().([]string), ",")
+		}
+		fmt.Fprintf(&b, "%s: %v\n", f.path, v)
+	}
+	return b.String()
+}
+
+// redact hides the password in a URL or DSN, and a plain secret entirely.
+func redact(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	if strings.Contains(s, "://") || dsnPassword.MatchString(s) {
+		return redactDSN(s)
+	}
+	return "xxxxx"
+}
+
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			dsn = u.String()
+		}
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+type field struct {
+	path, env, flag string
+	reload, redact  bool
+	value           reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields flattens the settings in v, naming each by its dotted YAML path.
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		path := prefix + sf.Tag.Get("yaml")
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, fields(v.Field(i), path+".")...)
+			continue
+		}
+		out = append(out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			reload: sf.Tag.Get("reload") == "true",
+			redact: sf.Tag.Get("redact") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return out
+}
+
+// set parses s into v. Lists are comma separated.
+func set(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// connect opens the database, waits for it to answer and, if migrate is
+// set, brings the schema up to date.
+func connect(cfg config, migrate bool) error {
+	err := database.Connect(cfg.Database.URL, database.Pool{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
+	if err != nil {
+		return err
+	}
+	database.QueryTimeout = cfg.Database.QueryTimeout
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Database.ConnectTimeout)
+	defer cancel()
+	if err := database.Ping(ctx); err != nil {
+		return err
+	}
+	if !migrate {
+		return nil
+	}
+	return database.Migrate()
+}
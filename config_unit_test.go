@@ -0,0 +1,249 @@
+//go:build unit
+
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/ratelimit"
+)
+
+func loadTestConfig(t *testing.T, file string, args ...string) (config, error) {
+	t.Setenv("CONFIG_FILE", "")
+	if len(file) > 0 {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"--config", path}, args...)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := configFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return loader.load()
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/expenses")
+
+	cfg, err := loadTestConfig(t, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":2565", cfg.Listen)
+	assert.Equal(t, authBasic, cfg.Auth.Mode)
+	assert.Equal(t, 10*time.Second, cfg.Timeouts.Shutdown)
+	assert.True(t, cfg.Features.Scheduler)
+}
+
+func TestLoadConfig_FlagsOverrideEnvOverrideFile(t *testing.T) {
+	file := `
+listen: ":8000"
+database:
+  url: postgres://file/expenses
+  max_open_conns: 50
+cors:
+  allow_origins: ["https://file.example"]
+timeouts:
+  read: 5s
+`
+	t.Setenv("PORT", "9000")
+	t.Setenv("DATABASE_URL", "postgres://env/expenses")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://a.example, https://b.example")
+
+	cfg, err := loadTestConfig(t, file, "--database-url", "postgres://flag/expenses")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Listen)
+	assert.Equal(t, "postgres://flag/expenses", cfg.Database.URL)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, 5*time.Second, cfg.Timeouts.Read)
+}
+
+func TestLoadConfig_RejectsUnknownKeys(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/expenses")
+
+	_, err := loadTestConfig(t, "listen_address: :8000\n")
+
+	assert.ErrorContains(t, err, "listen_address")
+}
+
+func TestLoadConfig_ReportsEveryInvalidSetting(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("AUTH_MODE", "token")
+	t.Setenv("RATE_LIMIT_RPS", "-1")
+	t.Setenv("CACHE_BACKEND", "disk")
+	t.Setenv("OUTBOX_PUBLISHER", "kafka")
+	t.Setenv("ATTACHMENT_STORAGE", "ftp")
+	t.Setenv("VALIDATION_LIMITS", "title=abc")
+	t.Setenv("CURRENCY_RATES", "USD=-1")
+
+	_, err := loadTestConfig(t, "")
+
+	assert.ErrorContains(t, err, "database.url is required")
+	assert.ErrorContains(t, err, "auth.mode")
+	assert.ErrorContains(t, err, "rate_limit.rps")
+	assert.ErrorContains(t, err, "cache.backend")
+	assert.ErrorContains(t, err, "outbox.publisher")
+	assert.ErrorContains(t, err, "attachments.storage")
+	assert.ErrorContains(t, err, "validation.limits")
+	assert.ErrorContains(t, err, "currency.rates")
+}
+
+func TestLoadConfig_S3NeedsBucketAndCredentials(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/expenses")
+	t.Setenv("ATTACHMENT_STORAGE", "s3")
+	t.Setenv("S3_ENDPOINT", "https://s3.example.com")
+
+	_, err := loadTestConfig(t, "")
+	assert.ErrorContains(t, err, "attachments.s3.bucket")
+	assert.ErrorContains(t, err, "attachments.s3.access_key")
+
+	t.Setenv("S3_BUCKET", "receipts")
+	t.Setenv("S3_ACCESS_KEY", "AKIA")
+	t.Setenv("S3_SECRET_KEY", "s3cret")
+	cfg, err := loadTestConfig(t, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "receipts", cfg.Attachments.attachment().S3.Bucket)
+}
+
+func TestValidationConfig_Limits(t *testing.T) {
+	v := validationConfig{Limits: []string{"tags=10"}, Workspaces: []string{"alice:tags=5", "alice:title=50", "bob:note=100"}}
+
+	limits, workspaces, err := v.limits()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, limits["tags"])
+	assert.Equal(t, 5.0, workspaces["alice"]["tags"])
+	assert.Equal(t, 50.0, workspaces["alice"]["title"])
+	assert.Equal(t, 10.0, workspaces["bob"]["tags"])
+	assert.Equal(t, 100.0, workspaces["bob"]["note"])
+
+	for _, bad := range []validationConfig{
+		{Limits: []string{"tags=100"}},
+		{Workspaces: []string{"tags=5"}},
+		{Limits: []string{"tags=10"}, Workspaces: []string{"alice:tags=15"}},
+	} {
+		_, _, err := bad.limits()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCurrencyConfig_Rates(t *testing.T) {
+	rates, err := currencyConfig{Rates: []string{"usd=0.028", " EUR = 0.026"}}.rates()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"USD": 0.028, "EUR": 0.026}, rates)
+
+	for _, bad := range []string{"USD", "USD=0", "DOLLAR=1", "USD=abc"} {
+		_, err := currencyConfig{Rates: []string{bad}}.rates()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfig_LogLevels(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/expenses")
+	t.Setenv("LOG_PACKAGE_LEVELS", "database=debug,http=warn")
+
+	cfg, err := loadTestConfig(t, "", "--log-level", "error")
+	assert.NoError(t, err)
+	def, pkgs, err := cfg.Log.levels()
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelError, def)
+	assert.Equal(t, map[string]slog.Level{"database": slog.LevelDebug, "http": slog.LevelWarn}, pkgs)
+
+	t.Setenv("LOG_PACKAGE_LEVELS", "nosuchpackage=debug")
+	_, err = loadTestConfig(t, "")
+	assert.ErrorContains(t, err, "nosuchpackage")
+}
+
+func TestRateLimitConfig_Limits(t *testing.T) {
+	r := rateLimitConfig{RPS: 2.5, Groups: []string{"expenses=5:10", "admin=1"}}
+
+	limits, err := r.limits()
+
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Rate: 2.5, Burst: 3}, limits.For("tags"))
+	assert.Equal(t, ratelimit.Limit{Rate: 5, Burst: 10}, limits.For("expenses"))
+	assert.Equal(t, ratelimit.Limit{Rate: 1, Burst: 1}, limits.For("admin"))
+
+	for _, bad := range []string{"expenses", "expenses=fast", "expenses=5:-1", "=5"} {
+		_, err := rateLimitConfig{Groups: []string{bad}}.limits()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestConfig_StringRedactsPasswords(t *testing.T) {
+	for _, dsn := range []string{
+		"postgres://app:s3cret@db/expenses?sslmode=disable",
+		"host=db user=app password=s3cret dbname=expenses",
+	} {
+		cfg := defaultConfig()
+		cfg.Database.URL = dsn
+
+		s := cfg.String()
+
+		assert.NotContains(t, s, "s3cret")
+		assert.Contains(t, s, "database.url: ")
+	}
+}
+
+func TestConfig_StringRedactsS3SecretKey(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Attachments.S3.AccessKey = "AKIA"
+	cfg.Attachments.S3.SecretKey = "s3cret"
+
+	s := cfg.String()
+
+	assert.NotContains(t, s, "s3cret")
+	assert.Contains(t, s, "attachments.s3.secret_key: xxxxx")
+	assert.Contains(t, s, "attachments.s3.access_key: AKIA")
+}
+
+func TestConfig_ReloadOnlyAppliesSafeSettings(t *testing.T) {
+	cfg := defaultConfig()
+	next := defaultConfig()
+	next.Listen = ":9000"
+	next.RateLimit.RPS = 5
+	next.CORS.AllowOrigins = []string{"https://a.example"}
+
+	got, restart := cfg.reload(next)
+
+	assert.Equal(t, ":2565", got.Listen)
+	assert.Equal(t, 5.0, got.RateLimit.RPS)
+	assert.Equal(t, []string{"https://a.example"}, got.CORS.AllowOrigins)
+	assert.Equal(t, []string{"listen"}, restart)
+}
+
+func TestConfig_IPExtractorOnlyBelievesTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:5000"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+
+	direct, err := config{}.ipExtractor()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", direct(req))
+
+	proxied, err := config{TrustedProxies: []string{"10.0.0.0/8"}}.ipExtractor()
+	assert.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", proxied(req))
+
+	untrusted, err := config{TrustedProxies: []string{"192.0.2.0/24"}}.ipExtractor()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", untrusted(req))
+
+	_, err = config{TrustedProxies: []string{"10.0.0.1"}}.ipExtractor()
+	assert.Error(t, err)
+}
@@ -1,29 +1,96 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"os"
+	"errors"
+	"fmt"
+	"time"
 
-	"github.com/labstack/gommon/log"
-	_ "github.com/lib/pq"
+	"github.com/umateedev/assessment/logging"
 )
 
+var log = logging.For("database")
+
 var Db *sql.DB
 
-func InitDb() {
-	var err error
+// QueryTimeout bounds the database work done for a single request.
+var QueryTimeout = 10 * time.Second
 
-	dbUrl := os.Getenv("DATABASE_URL")
-	if len(dbUrl) == 0 {
-		log.Fatal("Please set enviroment variable for DATABASE_URL")
-	}
+// Pool sizes the connection pool. Zero values keep the database/sql
+// defaults.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
 
-	Db, err = sql.Open("postgres", dbUrl)
+// Connect opens the database at url and makes it available as Db.
+func Connect(url string, pool Pool) error {
+	if len(url) == 0 {
+		return errors.New("please set enviroment variable for DATABASE_URL")
+	}
 
+	connector, err := newConnector(url)
 	if err != nil {
-		log.Fatal("Connect to database error", err)
+		return err
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
 	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	Db = db
+	return nil
+}
+
+// Ping waits until the database answers, backing off between attempts,
+// for as long as ctx allows.
+func Ping(ctx context.Context) error {
+	wait := 100 * time.Millisecond
+	for {
+		err := Db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		log.WarnContext(ctx, "Database not ready", "retry_in", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready: %w", err)
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > 5*time.Second {
+			wait = 5 * time.Second
+		}
+	}
+}
 
+// Context returns ctx limited to QueryTimeout. Handlers pass the request
+// context so that queries stop when the client goes away.
+func Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, QueryTimeout)
+}
+
+// StreamContext returns a context for a query whose rows are streamed to
+// the client. The query is cancelled if it has not produced its first row
+// within QueryTimeout; once firstRow is called only ctx ends it, so a long
+// list is not cut off while the client is still reading it.
+func StreamContext(ctx context.Context) (stream context.Context, firstRow func(), cancel context.CancelFunc) {
+	stream, stop := context.WithCancel(ctx)
+	t := time.AfterFunc(QueryTimeout, stop)
+	return stream, func() { t.Stop() }, func() {
+		t.Stop()
+		stop()
+	}
+}
+
+// Migrate creates any missing tables, columns and indexes. It is safe to run
+// on every start.
+func Migrate() error {
 	createTb := `
 	CREATE TABLE IF NOT EXISTS expenses
 	(
@@ -33,9 +100,172 @@ func InitDb() {
 		note TEXT,
 		tags TEXT[]
 	);
+	ALTER TABLE expenses ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+	CREATE TABLE IF NOT EXISTS categories
+	(
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		parent_id INTEGER REFERENCES categories (id)
+	);
+
+	CREATE TABLE IF NOT EXISTS category_paths
+	(
+		ancestor INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+		descendant INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+		depth INTEGER NOT NULL,
+		PRIMARY KEY (ancestor, descendant)
+	);
+	CREATE INDEX IF NOT EXISTS category_paths_descendant_idx ON category_paths (descendant);
+
+	ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories (id);
+	ALTER TABLE expenses ADD COLUMN IF NOT EXISTS rule_ids INTEGER[] NOT NULL DEFAULT '{}';
+	ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search TSVECTOR;
+	ALTER TABLE expenses ADD COLUMN IF NOT EXISTS owner TEXT;
+	CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
+
+	CREATE TABLE IF NOT EXISTS recurring_expenses
+	(
+		id SERIAL PRIMARY KEY,
+		title TEXT,
+		amount FLOAT,
+		note TEXT,
+		tags TEXT[],
+		rule TEXT NOT NULL,
+		starts_at TIMESTAMPTZ NOT NULL,
+		next_run TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS recurring_expenses_next_run_idx ON recurring_expenses (next_run);
+	ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS owner TEXT;
+	ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT 'UTC';
+
+	CREATE TABLE IF NOT EXISTS recurring_exceptions
+	(
+		recurring_id INTEGER NOT NULL REFERENCES recurring_expenses (id) ON DELETE CASCADE,
+		occurs_on DATE NOT NULL,
+		skip BOOLEAN NOT NULL DEFAULT false,
+		title TEXT,
+		amount FLOAT,
+		note TEXT,
+		tags TEXT[],
+		PRIMARY KEY (recurring_id, occurs_on)
+	);
+
+	CREATE TABLE IF NOT EXISTS attachments
+	(
+		id SERIAL PRIMARY KEY,
+		expense_id INTEGER NOT NULL REFERENCES expenses (id),
+		filename TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		sha256 TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		UNIQUE (expense_id, sha256)
+	);
+	CREATE INDEX IF NOT EXISTS attachments_sha256_idx ON attachments (sha256);
+
+	CREATE TABLE IF NOT EXISTS rules
+	(
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		title TEXT NOT NULL DEFAULT '',
+		note TEXT NOT NULL DEFAULT '',
+		amount_gte FLOAT,
+		amount_lt FLOAT,
+		add_tags TEXT[] NOT NULL DEFAULT '{}',
+		category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL,
+		priority INTEGER NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT true
+	);
+
+	CREATE TABLE IF NOT EXISTS views
+	(
+		id SERIAL PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		filter TEXT NOT NULL DEFAULT '',
+		sort TEXT NOT NULL DEFAULT '',
+		columns TEXT[] NOT NULL DEFAULT '{}',
+		currency TEXT NOT NULL DEFAULT '',
+		shared BOOLEAN NOT NULL DEFAULT false,
+		pinned BOOLEAN NOT NULL DEFAULT false
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS views_pinned_idx ON views (owner) WHERE pinned;
+
+	CREATE TABLE IF NOT EXISTS tag_aliases
+	(
+		alias TEXT PRIMARY KEY,
+		tag TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS users
+	(
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys
+	(
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE IF NOT EXISTS budgets
+	(
+		category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+		amount FLOAT NOT NULL
+	);
+	ALTER TABLE budgets ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+	ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_pkey;
+	CREATE UNIQUE INDEX IF NOT EXISTS budgets_owner_category_idx ON budgets (owner, category_id);
+
+	CREATE TABLE IF NOT EXISTS webhooks
+	(
+		id SERIAL PRIMARY KEY,
+		owner TEXT NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries
+	(
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		event_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ,
+		response_status INTEGER,
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		delivered_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id);
+
+	CREATE TABLE IF NOT EXISTS outbox
+	(
+		id BIGSERIAL PRIMARY KEY,
+		seq BIGINT UNIQUE,
+		type TEXT NOT NULL,
+		expense_id INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		published_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS outbox_unsequenced_idx ON outbox (id) WHERE seq IS NULL;
+	CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (seq) WHERE published_at IS NULL;
+	ALTER TABLE outbox ADD COLUMN IF NOT EXISTS owner TEXT;
 	`
-	_, err = Db.Exec(createTb)
-	if err != nil {
-		log.Fatal("Cannot create table", err)
-	}
+	_, err := Db.Exec(createTb)
+	return err
 }
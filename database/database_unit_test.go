@@ -0,0 +1,33 @@
+//go:build unit
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamContext_BoundsOnlyTheFirstRow(t *testing.T) {
+	defer func(d time.Duration) { QueryTimeout = d }(QueryTimeout)
+	QueryTimeout = 20 * time.Millisecond
+
+	slow, _, cancel := StreamContext(context.Background())
+	defer cancel()
+	select {
+	case <-slow.Done():
+	case <-time.After(time.Second):
+		t.Fatal("query without rows was not cancelled")
+	}
+
+	streaming, firstRow, cancel := StreamContext(context.Background())
+	defer cancel()
+	firstRow()
+	time.Sleep(3 * QueryTimeout)
+	assert.NoError(t, streaming.Err())
+
+	cancel()
+	assert.Error(t, streaming.Err())
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxAttempts is how many times Retry and InTx run their function.
+var MaxAttempts = 3
+
+// IsTransient reports whether err is likely to go away if the same work is
+// tried again: a serialization failure or deadlock, or a connection that
+// was reset or is being shut down.
+func IsTransient(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01", "57P01", "57P03":
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Retry runs fn until it succeeds, fails with an error that is not
+// transient, ctx is done or MaxAttempts is reached. fn must be safe to run
+// more than once.
+//
+// database/sql already retries queries that find a dead pooled connection,
+// so plain reads rarely need this; it is meant for transactions.
+func Retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		if attempt > 0 {
+			wait := time.Duration(25<<attempt)*time.Millisecond + time.Duration(rand.Int63n(int64(25*time.Millisecond)))
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+		}
+
+		if err = fn(); err == nil || !IsTransient(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// commitError marks a failed COMMIT. Whether the transaction was applied is
+// unknown if the connection dropped, so only a serialization failure or
+// deadlock, which always rolls back, is worth retrying. It deliberately
+// does not unwrap, which keeps IsTransient from seeing the cause.
+type commitError struct{ err error }
+
+func (e commitError) Error() string { return e.err.Error() }
+
+// InTx runs fn in a transaction and commits it, retrying the whole
+// transaction on transient errors. Errors returned by fn roll it back and
+// are returned unchanged, so fn should only do database work and leave
+// writing the response to the caller.
+func InTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	err := Retry(ctx, func() error {
+		tx, err := Db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01") {
+				return err
+			}
+			return commitError{err}
+		}
+		return nil
+	})
+
+	var ce commitError
+	if errors.As(err, &ce) {
+		return ce.err
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+//go:build unit
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{driver.ErrBadConn, true},
+		{sql.ErrNoRows, false},
+		{errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsTransient(tt.err), tt.err.Error())
+	}
+}
+
+func TestRetry_StopsAtMaxAttempts(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), func() error {
+		calls++
+		return &pq.Error{Code: "40001"}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, MaxAttempts, calls)
+}
+
+func TestRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), func() error {
+		calls++
+		return sql.ErrNoRows
+	})
+
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, 1, calls)
+}
+
+func mockDb(t *testing.T) sqlmock.Sqlmock {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	Db = db
+	return mock
+}
+
+func TestInTx_RetriesSerializationFailure(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE expenses").WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE expenses").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := InTx(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE expenses SET note = ''")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInTx_DoesNotRetryFailedCommit(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE expenses").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(syscall.ECONNRESET)
+
+	err := InTx(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE expenses SET note = ''")
+		return err
+	})
+
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPing_RetriesUntilReady(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectPing().WillReturnError(syscall.ECONNREFUSED)
+	mock.ExpectPing()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := Ping(ctx)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPing_GivesUpWhenContextIsDone(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectPing().WillReturnError(syscall.ECONNREFUSED)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Ping(ctx)
+
+	assert.ErrorContains(t, err, "database not ready")
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmts caches prepared statements by query. database/sql prepares a
+// statement again on each pooled connection that runs it, including new
+// connections after the server restarts, so one *sql.Stmt per query is
+// enough. The cache belongs to the Db it was prepared on and starts over
+// when Db is replaced.
+var stmts = struct {
+	sync.Mutex
+	db *sql.DB
+	m  map[string]*sql.Stmt
+}{}
+
+// Stmt returns the prepared statement for query, preparing it on first use.
+// Only use it for fixed queries; queries built from user input would grow
+// the cache without bound.
+func Stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmts.Lock()
+	if stmts.db != Db {
+		closeStmts()
+		stmts.db, stmts.m = Db, map[string]*sql.Stmt{}
+	}
+	if s, ok := stmts.m[query]; ok {
+		stmts.Unlock()
+		return s, nil
+	}
+	db := stmts.db
+	stmts.Unlock()
+
+	s, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts.Lock()
+	replaced := stmts.db != db
+	existing, ok := stmts.m[query]
+	if !replaced && !ok {
+		stmts.m[query] = s
+	}
+	stmts.Unlock()
+
+	switch {
+	case replaced:
+		s.Close()
+		return Stmt(ctx, query)
+	case ok:
+		// Another request prepared it first.
+		s.Close()
+		return existing, nil
+	}
+	return s, nil
+}
+
+// closeStmts closes every cached statement. stmts must be locked.
+func closeStmts() error {
+	var first error
+	for _, s := range stmts.m {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	stmts.m = nil
+	return first
+}
+
+// Close closes the cached statements and then Db.
+func Close() error {
+	stmts.Lock()
+	err := closeStmts()
+	stmts.db = nil
+	stmts.Unlock()
+
+	if Db == nil {
+		return err
+	}
+	if dbErr := Db.Close(); err == nil {
+		err = dbErr
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+//go:build unit
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmt_PreparesOnce(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectPrepare("SELECT 1").WillBeClosed()
+	mock.ExpectClose()
+
+	first, err := Stmt(context.Background(), "SELECT 1")
+	assert.NoError(t, err)
+	second, err := Stmt(context.Background(), "SELECT 1")
+	assert.NoError(t, err)
+
+	assert.Same(t, first, second)
+	assert.NoError(t, Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStmt_PreparesAgainOnNewDb(t *testing.T) {
+	old := mockDb(t)
+	old.ExpectPrepare("SELECT 1").WillBeClosed()
+	first, err := Stmt(context.Background(), "SELECT 1")
+	assert.NoError(t, err)
+
+	mock := mockDb(t)
+	mock.ExpectPrepare("SELECT 1")
+	second, err := Stmt(context.Background(), "SELECT 1")
+
+	assert.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.NoError(t, old.ExpectationsWereMet())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/umateedev/assessment/database"
+
+// tracedConnector wraps the pq driver so that every statement run through
+// Db records a client span under the span in its context. Calls without a
+// span in their context, such as Migrate, are not traced.
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (t tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tracedConn{conn}, nil
+}
+
+// startSpan starts a span named after op when ctx is already traced.
+func startSpan(ctx context.Context, op, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	attrs := []attribute.KeyValue{attribute.String("db.system", "postgresql"), attribute.String("db.operation", op)}
+	if len(query) > 0 {
+		attrs = append(attrs, attribute.String("db.statement", query))
+	}
+	return otel.Tracer(instrumentation).Start(ctx, "db."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it failed unless err is nil or driver.ErrSkip,
+// which only asks database/sql to fall back to another method.
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != driver.ErrSkip {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := startSpan(ctx, "prepare", query)
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, "query", query)
+	rows, err := q.QueryContext(ctx, query, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, "exec", query)
+	res, err := e.ExecContext(ctx, query, args)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, span := startSpan(ctx, "begin", "")
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return tracedTx{Tx: tx, ctx: ctx}, nil
+}
+
+func (c tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession and IsValid keep database/sql's connection pooling checks
+// working through the wrapper.
+func (c tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// tracedTx records commit and rollback under the span that began the
+// transaction.
+type tracedTx struct {
+	driver.Tx
+	ctx context.Context
+}
+
+func (t tracedTx) Commit() error {
+	_, span := startSpan(t.ctx, "commit", "")
+	err := t.Tx.Commit()
+	endSpan(span, err)
+	return err
+}
+
+func (t tracedTx) Rollback() error {
+	_, span := startSpan(t.ctx, "rollback", "")
+	err := t.Tx.Rollback()
+	endSpan(span, err)
+	return err
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, "query", s.query)
+	rows, err := q.QueryContext(ctx, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (s tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, "exec", s.query)
+	res, err := e.ExecContext(ctx, args)
+	endSpan(span, err)
+	return res, err
+}
+
+// newConnector returns a traced pq connector for url.
+func newConnector(url string) (driver.Connector, error) {
+	c, err := pq.NewConnector(url)
+	if err != nil {
+		return nil, err
+	}
+	return tracedConnector{c}, nil
+}
@@ -0,0 +1,58 @@
+//go:build unit
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// dsnConnector opens connections to the sqlmock database registered as dsn.
+type dsnConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.d }
+
+func TestTracedConnector_RecordsQueriesInTrace(t *testing.T) {
+	mockDb, mock, err := sqlmock.NewWithDSN(t.Name())
+	assert.NoError(t, err)
+	defer mockDb.Close()
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	mock.ExpectExec("DELETE FROM expenses").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	db := sql.OpenDB(tracedConnector{dsnConnector{mockDb.Driver(), t.Name()}})
+	defer db.Close()
+
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+	var n int
+	assert.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	// Untraced calls, such as Migrate, do not start a trace of their own.
+	_, err = db.ExecContext(context.Background(), "DELETE FROM expenses")
+	assert.NoError(t, err)
+	parent.End()
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	spans := sr.Ended()
+	if assert.Len(t, spans, 2) {
+		q := spans[0]
+		assert.Equal(t, "db.query", q.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), q.Parent().SpanID())
+		assert.Contains(t, q.Attributes(), attribute.String("db.statement", "SELECT 1"))
+		assert.Equal(t, "request", spans[1].Name())
+	}
+}
@@ -1,27 +1,66 @@
 package expense
 
 import (
+	"database/sql"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/cache"
 	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/rule"
+	"github.com/umateedev/assessment/search"
+	"github.com/umateedev/assessment/tag"
+	"github.com/umateedev/assessment/validate"
+	"github.com/umateedev/assessment/webhook"
 )
 
 func CreateExpenseHandler(c echo.Context) error {
 	e := Expense{}
 	err := c.Bind(&e)
 	if err != nil {
-		log.Printf("Invalid request %s", err.Error())
-		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid request"})
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+	e.Tags = tag.Normalize(e.Tags)
+	if errs := validate.StructFor(auth.User(c), &e); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	t := rule.Target{Title: e.Title, Note: e.Note, Amount: e.Amount, Tags: e.Tags, CategoryId: e.CategoryId}
+	e.RuleIds = rule.Apply(&t)
+	e.Tags, e.CategoryId = t.Tags, t.CategoryId
+	if len(e.RuleIds) > 0 {
+		// Rules can add tags past the limits.
+		if errs := validate.StructFor(auth.User(c), &e); len(errs) > 0 {
+			return problem.Invalid(c, errs...)
+		}
 	}
 
-	row := database.Db.QueryRow("INSERT INTO expenses (title, amount, note, tags) VALUES ($1, $2, $3, $4) RETURNING id", e.Title, e.Amount, e.Note, pq.Array(&e.Tags))
-	err = row.Scan(&e.Id)
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "INSERT INTO expenses (title, amount, note, tags, category_id, rule_ids, search, owner) VALUES ($1, $2, $3, $4, $5, $6, $7::tsvector, NULLIF($8, '')) RETURNING id",
+			e.Title, e.Amount, e.Note, pq.Array(&e.Tags), e.CategoryId, pq.Array(e.RuleIds), search.Vector(e.Title, e.Note), auth.User(c))
+		if err := row.Scan(&e.Id); err != nil {
+			return err
+		}
+		if err := outbox.Add(ctx, tx, outbox.ExpenseCreated, e.Id, e); err != nil {
+			return err
+		}
+		return notify(ctx, tx, auth.User(c), webhook.ExpenseCreated, e, nil)
+	})
+	if isForeignKeyViolation(err) {
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeCategoryNotFound, "category not found"))
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Error{Message: err.Error()})
+		return problem.Internal(c, err)
 	}
 
+	cache.ExpenseCreated(ctx)
 	return c.JSON(http.StatusCreated, e)
 }
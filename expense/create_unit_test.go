@@ -3,6 +3,8 @@
 package expense
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,8 +12,14 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
 	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/rule"
+	"github.com/umateedev/assessment/validate"
+	"github.com/umateedev/assessment/webhook"
 )
 
 func TestCreateExpense_ReturnBadRequest_WhenInvalidRequest(t *testing.T) {
@@ -35,6 +43,39 @@ func TestCreateExpense_ReturnBadRequest_WhenInvalidRequest(t *testing.T) {
 	}
 }
 
+func TestCreateExpense_ReturnUnprocessableEntity_WhenFieldsInvalid(t *testing.T) {
+	limits, _ := validate.DefaultLimits().Tighten([]string{"tags=1"})
+	validate.SetLimits(limits, nil)
+	t.Cleanup(func() { validate.SetLimits(validate.DefaultLimits(), nil) })
+	e := echo.New()
+	body := `{
+		"title": " ",
+		"amount": -1.005,
+		"note": "",
+		"tags": ["food", "beverage"]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	err := CreateExpenseHandler(c)
+
+	expected := `[
+		{"field": "title", "message": "is required"},
+		{"field": "amount", "message": "must be greater than 0"},
+		{"field": "amount", "message": "must have at most 2 decimal places for THB"},
+		{"field": "tags", "message": "must have at most 1 items"}
+	]`
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		p := map[string]json.RawMessage{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		assert.JSONEq(t, expected, string(p["errors"]))
+	}
+}
+
 func TestCreateExpense_ReturnInternalServerError_WhenInsertFailed(t *testing.T) {
 	e := echo.New()
 	body := `{
@@ -54,7 +95,9 @@ func TestCreateExpense_ReturnInternalServerError_WhenInsertFailed(t *testing.T)
 	defer db.Close()
 
 	database.Db = db
+	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO expenses").WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
 	c := e.NewContext(req, rec)
 
 	err = CreateExpenseHandler(c)
@@ -85,7 +128,15 @@ func TestCreateExpense_ReturnSuccess(t *testing.T) {
 	defer db.Close()
 
 	database.Db = db
+	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO expenses").WillReturnRows(newExpense)
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(outbox.ExpenseCreated, 1, `{"id":1,"title":"strawberry smoothie","amount":79,"note":"night market promotion discount 10 bath","tags":["food","beverage"]}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), webhook.ExpenseCreated, sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 	c := e.NewContext(req, rec)
 
 	err = CreateExpenseHandler(c)
@@ -96,3 +147,149 @@ func TestCreateExpense_ReturnSuccess(t *testing.T) {
 		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
 	}
 }
+
+func TestCreateExpense_ReturnBadRequest_WhenCategoryNotFound(t *testing.T) {
+	e := echo.New()
+	body := `{
+		"title": "latte",
+		"amount": 95,
+		"tags": ["coffee"],
+		"category_id": 42
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO expenses").WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+	c := e.NewContext(req, rec)
+
+	err = CreateExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCreateExpense_AppliesRules(t *testing.T) {
+	e := echo.New()
+	body := `{
+		"title": "Starbucks Siam",
+		"amount": 145,
+		"note": "",
+		"tags": ["food"]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE enabled").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "note", "amount_gte", "amount_lt", "add_tags", "category_id", "priority", "enabled"}).
+			AddRow(4, "coffee", "/starbucks/i", "", nil, 500.0, pq.Array([]string{"coffee", "food"}), nil, 0, true))
+	if err := rule.LoadRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		mock.ExpectQuery("SELECT (.+) FROM rules WHERE enabled").WillReturnRows(sqlmock.NewRows(nil))
+		rule.LoadRules(context.Background())
+	}()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO expenses").
+		WithArgs("Starbucks Siam", 145.0, "", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	c := e.NewContext(req, rec)
+
+	err = CreateExpenseHandler(c)
+
+	expected := "{\"id\":1,\"title\":\"Starbucks Siam\",\"amount\":145,\"note\":\"\",\"tags\":[\"food\",\"coffee\"],\"rule_ids\":[4]}"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestCreateExpense_EnqueuesWebhooksForTheCreatingUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+
+	for i, user := range []string{"alice", "bob"} {
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(`{"title": "lunch", "amount": 120}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		auth.SetUser(c, user)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").
+			WithArgs("lunch", 120.0, "", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), sqlmock.AnyArg(), user).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 1))
+		mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO webhook_deliveries").
+			WithArgs(sqlmock.AnyArg(), webhook.ExpenseCreated, sqlmock.AnyArg(), user).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if assert.NoError(t, CreateExpenseHandler(c)) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateExpense_ReturnUnprocessableEntity_WhenRulesAddTagsPastTheLimit(t *testing.T) {
+	limits, _ := validate.DefaultLimits().Tighten([]string{"tags=2"})
+	validate.SetLimits(limits, nil)
+	t.Cleanup(func() { validate.SetLimits(validate.DefaultLimits(), nil) })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE enabled").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "note", "amount_gte", "amount_lt", "add_tags", "category_id", "priority", "enabled"}).
+			AddRow(4, "coffee", "/starbucks/i", "", nil, nil, pq.Array([]string{"coffee"}), nil, 0, true))
+	if err := rule.LoadRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		mock.ExpectQuery("SELECT (.+) FROM rules WHERE enabled").WillReturnRows(sqlmock.NewRows(nil))
+		rule.LoadRules(context.Background())
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(`{"title": "Starbucks", "amount": 145, "tags": ["food", "drink"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	err = CreateExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"tags","message":"must have at most 2 items"`)
+	}
+}
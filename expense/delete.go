@@ -0,0 +1,119 @@
+package expense
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/attachment"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
+)
+
+// DeleteExpenseHandler deletes an expense together with its attachments.
+func DeleteExpenseHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, id must be a number")
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	var hashes []string
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if hashes, err = attachment.Purge(ctx, tx, id); err != nil {
+			return err
+		}
+		return deleteExpense(ctx, tx, id)
+	})
+	switch err {
+	case nil:
+	case errNotFound:
+		return problem.NotFound(c, "expense not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	attachment.RemoveUnreferenced(ctx, hashes)
+	cache.ExpenseChanged(ctx, id)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PurgeBefore deletes every expense created before t, with its attachments,
+// and returns how many were deleted. A zero t deletes all expenses.
+func PurgeBefore(ctx context.Context, t time.Time) (int, error) {
+	query, args := "SELECT id FROM expenses ORDER BY id", []interface{}{}
+	if !t.IsZero() {
+		query, args = "SELECT id FROM expenses WHERE created_at < $1 ORDER BY id", []interface{}{t}
+	}
+
+	rows, err := database.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(ids) > 0 {
+		defer cache.ExpensesChanged(ctx)
+	}
+	for i, id := range ids {
+		var hashes []string
+		err := database.InTx(ctx, func(tx *sql.Tx) error {
+			var err error
+			if hashes, err = attachment.Purge(ctx, tx, id); err != nil {
+				return err
+			}
+			return deleteExpense(ctx, tx, id)
+		})
+		switch err {
+		case nil:
+			attachment.RemoveUnreferenced(ctx, hashes)
+		case errNotFound:
+		default:
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+var errNotFound = errors.New("expense not found")
+
+// deleteExpense records the events and deletes an expense, or returns
+// errNotFound, on which tx must be rolled back. The outbox event comes first
+// so that it gets the owner of the expense.
+func deleteExpense(ctx context.Context, tx *sql.Tx, id int) error {
+	data := map[string]int{"id": id}
+	if err := outbox.Add(ctx, tx, outbox.ExpenseDeleted, id, data); err != nil {
+		return err
+	}
+	var owner string
+	err := tx.QueryRowContext(ctx, "DELETE FROM expenses WHERE id = $1 RETURNING COALESCE(owner, '')", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return errNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return webhook.Enqueue(ctx, tx, owner, webhook.ExpenseDeleted, data)
+}
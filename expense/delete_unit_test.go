@@ -0,0 +1,84 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/webhook"
+)
+
+func TestDeleteExpense_RemovesBlobsAfterCommit(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM attachments WHERE expense_id=\\$1 RETURNING sha256").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"sha256"}).AddRow("abc"))
+	mock.ExpectExec("INSERT INTO outbox").WithArgs(outbox.ExpenseDeleted, 1, `{"id":1}`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("DELETE FROM expenses WHERE id = \\$1").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("alice"))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), webhook.ExpenseDeleted, sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WithArgs("abc").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("abc").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectCommit()
+
+	err = DeleteExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteExpense_ReturnNotFound(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("9")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM attachments").WillReturnRows(sqlmock.NewRows([]string{"sha256"}))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("DELETE FROM expenses").WillReturnRows(sqlmock.NewRows([]string{"owner"}))
+	mock.ExpectRollback()
+
+	err = DeleteExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
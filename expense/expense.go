@@ -1,13 +1,57 @@
 package expense
 
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/category"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/webhook"
+)
+
+var log = logging.For("expense")
+
 type Expense struct {
-	Id     int      `json:"id"`
-	Title  string   `json:"title"`
-	Amount float64  `json:"amount"`
-	Note   string   `json:"note"`
-	Tags   []string `json:"tags"`
+	Id         int      `json:"id"`
+	Title      string   `json:"title" validate:"required,max=title"`
+	Amount     float64  `json:"amount" validate:"gt=0,scale"`
+	Note       string   `json:"note" validate:"max=note"`
+	Tags       []string `json:"tags" validate:"max=tags,each=required|max=tag"`
+	CategoryId *int     `json:"category_id,omitempty"`
+	RuleIds    []int64  `json:"rule_ids,omitempty"`
+}
+
+// isForeignKeyViolation reports whether err is caused by a category_id that
+// does not exist.
+func isForeignKeyViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23503"
+}
+
+// notify queues, as part of tx, the webhook event of a created or updated
+// expense of owner and a budget.exceeded event for every budget the expense
+// takes over. old is the expense before an update, nil for a new one;
+// budgets are only checked when the amount or category changed, as the
+// month of an expense never does.
+func notify(ctx context.Context, tx *sql.Tx, owner, event string, e Expense, old *Expense) error {
+	if err := webhook.Enqueue(ctx, tx, owner, event, e); err != nil {
+		return err
+	}
+	if e.CategoryId == nil {
+		return nil
+	}
+	var oldCategoryId *int
+	var oldAmount float64
+	if old != nil {
+		if old.Amount == e.Amount && sameCategory(old.CategoryId, e.CategoryId) {
+			return nil
+		}
+		oldCategoryId, oldAmount = old.CategoryId, old.Amount
+	}
+	return category.NotifyBudgetsExceeded(ctx, tx, owner, e.Id, oldCategoryId, oldAmount)
 }
 
-type Error struct {
-	Message string `json:"message"`
+func sameCategory(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
 }
@@ -1,68 +1,189 @@
 package expense
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/cache"
 	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/filter"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/view"
 )
 
 func GetExpenseByIdHandler(c echo.Context) error {
 	id := c.Param("id")
 	if len(id) == 0 {
-		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid request, missing param id"})
+		return problem.BadRequest(c, "Invalid request, missing param id")
 	}
 
-	stmt, err := database.Db.Prepare("SELECT id, title, amount, note, tags FROM expenses WHERE id=$1")
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	var entry cache.Entry
+	if n, err := strconv.Atoi(id); err == nil {
+		entry = cache.ItemEntry(ctx, n)
+		if body, ok := entry.Get(ctx); ok {
+			return cache.Write(c, body, true)
+		}
+	}
+
+	stmt, err := database.Stmt(ctx, "SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses WHERE id=$1")
 	if err != nil {
-		log.Printf("Prepare statement error", err)
-		return c.JSON(http.StatusInternalServerError, "Prepare statement error")
+		return problem.Internal(c, err)
 	}
 
 	e := Expense{}
-	row := stmt.QueryRow(id)
-	err = row.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags))
+	row := stmt.QueryRowContext(ctx, id)
+	err = row.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds))
 	switch err {
 	case sql.ErrNoRows:
-		return c.JSON(http.StatusNotFound, Error{Message: "expense not found"})
+		return problem.NotFound(c, "expense not found")
 	case nil:
-		return c.JSON(http.StatusOK, e)
+		return writeCached(c, ctx, entry, e)
 	default:
-		return c.JSON(http.StatusInternalServerError, Error{Message: "can't scan expense:" + err.Error()})
+		return problem.Internal(c, err)
 
 	}
 }
 
+// maxPageSize bounds ?limit= on the expense list.
+const maxPageSize = 1000
+
+// GetAllExpenseHandler lists expenses, optionally narrowed by a filter
+// expression in ?filter= (see package filter). Without any query parameters
+// the caller's pinned view applies, if they have one. Lists are cached per
+// user and query, except pinned views, which change without an expense
+// changing.
+//
+// ?limit= switches to keyset paging: at most limit expenses ordered by id,
+// starting after the id in ?after=. An empty page is 200 with [] rather than
+// 404, so clients can tell the end of the list from a missing resource.
+//
+// The list is streamed as rows are read, as NDJSON if the client accepts
+// application/x-ndjson; see listWriter. Only the wait for the first row is bounded
+// by the query timeout; after that the list runs as long as the client
+// keeps reading.
 func GetAllExpenseHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
 
-	stmt, err := database.Db.Prepare("SELECT id, title, amount, note, tags FROM expenses")
-	if err != nil {
-		log.Printf("Prepare statement error", err)
-		return c.JSON(http.StatusInternalServerError, "Prepare statement error")
+	if user := auth.User(c); len(user) > 0 && len(c.QueryParams()) == 0 {
+		v, err := view.Pinned(ctx, user)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		if v != nil {
+			return listView(c, *v)
+		}
 	}
 
-	expenses := []Expense{}
-	rows, err := stmt.Query()
+	var entry cache.Entry
+	if !wantsNDJSON(c) {
+		entry = cache.ListEntry(ctx, auth.User(c), c.QueryParams().Encode())
+		if body, ok := entry.Get(ctx); ok {
+			return cache.Write(c, body, true)
+		}
+	}
+
+	query := "SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses"
+	var args []interface{}
+	var where []string
+	if expr := c.QueryParam("filter"); len(expr) > 0 {
+		n, err := filter.Parse(expr)
+		if err != nil {
+			return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeInvalidFilter, err.Error()))
+		}
+		var cond string
+		cond, args = filter.Compile(n, "", nil)
+		where = append(where, cond)
+	}
+
+	limit := 0
+	paged := len(c.QueryParam("limit")) > 0
+	if paged {
+		var err error
+		limit, err = strconv.Atoi(c.QueryParam("limit"))
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return problem.BadRequest(c, fmt.Sprintf("Invalid request, limit must be between 1 and %d", maxPageSize))
+		}
+		if s := c.QueryParam("after"); len(s) > 0 {
+			after, err := strconv.Atoi(s)
+			if err != nil {
+				return problem.BadRequest(c, "Invalid request, after must be an expense id")
+			}
+			args = append(args, after)
+			where = append(where, fmt.Sprintf("id > $%d", len(args)))
+		}
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if paged {
+		args = append(args, limit)
+		query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(args))
+	}
+
+	// Filters make the query text unbounded, so only the handful of
+	// unfiltered shapes go through the statement cache.
+	qctx, firstRow, qcancel := database.StreamContext(c.Request().Context())
+	defer qcancel()
+	var rows *sql.Rows
+	var err error
+	if len(c.QueryParam("filter")) > 0 {
+		rows, err = database.Db.QueryContext(qctx, query, args...)
+	} else {
+		var stmt *sql.Stmt
+		if stmt, err = database.Stmt(ctx, query); err == nil {
+			rows, err = stmt.QueryContext(qctx, args...)
+		}
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Error{Message: err.Error()})
+		return problem.Internal(c, err)
 	}
+	defer rows.Close()
 
+	w := newListWriter(c, entry)
 	for rows.Next() {
+		firstRow()
 		e := Expense{}
-		err := rows.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags))
+		err := rows.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, Error{Message: err.Error()})
-
+			return w.fail(err)
+		}
+		if err := w.write(e); err != nil {
+			return w.fail(err)
 		}
-		expenses = append(expenses, e)
+	}
+	if err := rows.Err(); err != nil {
+		return w.fail(err)
+	}
+
+	if w.n == 0 && !paged {
+		return problem.NotFound(c, "expense not found")
 	}
 
-	if len(expenses) == 0 {
-		return c.JSON(http.StatusNotFound, Error{Message: "expense not found"})
+	if err := w.close(); err != nil {
+		return w.fail(err)
 	}
+	return nil
+}
 
-	return c.JSON(http.StatusOK, expenses)
+// writeCached sends v and keeps it under entry.
+func writeCached(c echo.Context, ctx context.Context, entry cache.Entry, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	entry.Store(ctx, body)
+	return cache.Write(c, body, false)
 }
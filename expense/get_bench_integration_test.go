@@ -0,0 +1,113 @@
+//go:build integration
+
+package expense
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+)
+
+// The benchmarks run the GET handlers against the database in DATABASE_URL,
+// for example:
+//
+//	DATABASE_URL=postgres://... go test -tags integration -run '^$' -bench . -benchmem ./expense
+//
+// The PrepareEachRequest variants do what the handlers did before the
+// statement cache: prepare a statement on every request and never close it.
+
+func benchDb(b *testing.B) int {
+	url := os.Getenv("DATABASE_URL")
+	if len(url) == 0 {
+		b.Skip("DATABASE_URL is not set")
+	}
+	if err := database.Connect(url, database.Pool{}); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		b.Fatal(err)
+	}
+
+	var id int
+	err := database.Db.QueryRow("INSERT INTO expenses (title, amount, note, tags) VALUES ('bench', 1, 'bench', '{bench}') RETURNING id").Scan(&id)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.Db.Exec("DELETE FROM expenses WHERE id = $1", id) })
+	return id
+}
+
+func benchHandler(b *testing.B, target string, params []string, h echo.HandlerFunc) {
+	e := echo.New()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+		if len(params) > 0 {
+			c.SetParamNames("id")
+			c.SetParamValues(params...)
+		}
+		if err := h(c); err != nil || rec.Code != http.StatusOK {
+			b.Fatalf("status %d, error %v", rec.Code, err)
+		}
+	}
+}
+
+func BenchmarkGetExpenseById(b *testing.B) {
+	id := strconv.Itoa(benchDb(b))
+	benchHandler(b, "/expenses/"+id, []string{id}, GetExpenseByIdHandler)
+}
+
+func BenchmarkGetExpenseById_PrepareEachRequest(b *testing.B) {
+	id := strconv.Itoa(benchDb(b))
+	benchHandler(b, "/expenses/"+id, []string{id}, func(c echo.Context) error {
+		stmt, err := database.Db.PrepareContext(context.Background(), "SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses WHERE id=$1")
+		if err != nil {
+			return err
+		}
+		e := Expense{}
+		err = stmt.QueryRow(c.Param("id")).Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, e)
+	})
+}
+
+func BenchmarkGetAllExpense(b *testing.B) {
+	benchDb(b)
+	benchHandler(b, "/expenses?limit=50", nil, GetAllExpenseHandler)
+}
+
+func BenchmarkGetAllExpense_PrepareEachRequest(b *testing.B) {
+	benchDb(b)
+	benchHandler(b, "/expenses?limit=50", nil, func(c echo.Context) error {
+		stmt, err := database.Db.PrepareContext(context.Background(), "SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses ORDER BY id LIMIT 50")
+		if err != nil {
+			return err
+		}
+		rows, err := stmt.Query()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		expenses := []Expense{}
+		for rows.Next() {
+			e := Expense{}
+			if err := rows.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds)); err != nil {
+				return err
+			}
+			expenses = append(expenses, e)
+		}
+		return c.JSON(http.StatusOK, expenses)
+	})
+}
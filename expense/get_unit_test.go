@@ -3,15 +3,19 @@
 package expense
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/cache"
 	"github.com/umateedev/assessment/database"
 )
 
@@ -79,8 +83,8 @@ func TestGetExpenseById_ReturnSuccess(t *testing.T) {
 	defer db.Close()
 
 	database.Db = db
-	mockExpense := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags"}).
-		AddRow("1", "test", 10, "test", pq.Array([]string{"foo", "bar"}))
+	mockExpense := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+		AddRow("1", "test", 10, "test", pq.Array([]string{"foo", "bar"}), nil, "{}")
 	mock.ExpectPrepare("SELECT(.*)").
 		ExpectQuery().
 		WithArgs(expenseId).
@@ -95,6 +99,50 @@ func TestGetExpenseById_ReturnSuccess(t *testing.T) {
 	}
 }
 
+func TestGetExpenseById_ServesFromCache_UntilChanged(t *testing.T) {
+	cache.Setup(cache.NewLRU(10), cache.Config{TTL: time.Minute})
+	defer cache.Setup(nil, cache.Config{})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.SetPath("/expenses/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		assert.NoError(t, GetExpenseByIdHandler(c))
+		return rec
+	}
+	mock.ExpectPrepare("SELECT(.*)").
+		ExpectQuery().
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+			AddRow("1", "test", 10, "test", pq.Array([]string{"foo"}), nil, "{}"))
+
+	first, second := get(), get()
+
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	cache.ExpenseChanged(context.Background(), 1)
+	mock.ExpectQuery("SELECT(.*)").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+			AddRow("1", "changed", 10, "test", pq.Array([]string{"foo"}), nil, "{}"))
+
+	third := get()
+
+	assert.Equal(t, "MISS", third.Header().Get("X-Cache"))
+	assert.Contains(t, third.Body.String(), `"title":"changed"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetAllExpense_ReturnInternalServerError_WhenDbFailed(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/expense", nil)
@@ -108,7 +156,7 @@ func TestGetAllExpense_ReturnInternalServerError_WhenDbFailed(t *testing.T) {
 	}
 	defer db.Close()
 	database.Db = db
-	mock.ExpectPrepare("SELECT id, title, amount, note, tags FROM expenses").
+	mock.ExpectPrepare("SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses").
 		ExpectQuery().
 		WillReturnError(sqlmock.ErrCancelled)
 
@@ -133,18 +181,180 @@ func TestGetAllExpense_ReturnSuccess(t *testing.T) {
 	defer db.Close()
 
 	database.Db = db
-	mockExpense := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags"}).
-		AddRow("1", "test", 10, "test", pq.Array([]string{"foo", "bar"})).
-		AddRow("2", "test2", 10, "test2", pq.Array([]string{"foo2", "bar2"}))
-	mock.ExpectPrepare("SELECT id, title, amount, note, tags FROM expenses").
+	mockExpense := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+		AddRow("1", "test", 10, "test", pq.Array([]string{"foo", "bar"}), nil, "{}").
+		AddRow("2", "test2", 10, "test2", pq.Array([]string{"foo2", "bar2"}), 3, "{7}")
+	mock.ExpectPrepare("SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses").
 		ExpectQuery().
 		WillReturnRows(mockExpense)
 
 	err = GetAllExpenseHandler(c)
 
-	expected := "[{\"id\":1,\"title\":\"test\",\"amount\":10,\"note\":\"test\",\"tags\":[\"foo\",\"bar\"]},{\"id\":2,\"title\":\"test2\",\"amount\":10,\"note\":\"test2\",\"tags\":[\"foo2\",\"bar2\"]}]"
+	expected := "[{\"id\":1,\"title\":\"test\",\"amount\":10,\"note\":\"test\",\"tags\":[\"foo\",\"bar\"]},{\"id\":2,\"title\":\"test2\",\"amount\":10,\"note\":\"test2\",\"tags\":[\"foo2\",\"bar2\"],\"category_id\":3,\"rule_ids\":[7]}]"
 	if assert.NoError(t, err) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
 	}
 }
+
+func TestGetAllExpense_ReturnBadRequest_WhenFilterInvalid(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter="+url.QueryEscape("amount > abc"), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "position 9")
+	}
+}
+
+func TestGetAllExpense_AppliesFilter(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter="+url.QueryEscape("tag:food AND amount>5"), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mockExpense := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+		AddRow("1", "test", 10, "test", pq.Array([]string{"food"}), nil, "{}")
+	mock.ExpectQuery("SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses WHERE ($1 = ANY(COALESCE(tags, '{}')) AND amount > $2)").
+		WithArgs("food", 5.0).
+		WillReturnRows(mockExpense)
+
+	err = GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetAllExpense_ReturnPage_WhenLimitGiven(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter=tag:food&limit=2&after=5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT id, title, amount, note, tags, category_id, rule_ids FROM expenses WHERE $1 = ANY(COALESCE(tags, '{}')) AND id > $2 ORDER BY id LIMIT $3").
+		WithArgs("food", 5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}))
+
+	err = GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[]", strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetAllExpense_ReturnBadRequest_WhenLimitInvalid(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?limit=0", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestGetAllExpense_StreamsNDJSON(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter=tag:food", nil)
+	req.Header.Set(echo.HeaderAccept, MIMEApplicationNDJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	rows := sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"})
+	for i := 1; i <= flushEvery+1; i++ {
+		rows.AddRow(i, "test", 10, "", pq.Array([]string{"food"}), nil, "{}")
+	}
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE").WillReturnRows(rows)
+
+	err = GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MIMEApplicationNDJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.True(t, rec.Flushed)
+		lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+		assert.Len(t, lines, flushEvery+1)
+		assert.Equal(t, `{"id":1,"title":"test","amount":10,"note":"","tags":["food"]}`, lines[0])
+	}
+}
+
+func TestGetAllExpense_ReturnInternalServerError_WhenFirstRowFails(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter=tag:food", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+			AddRow(1, "test", 10, "", pq.Array([]string{"food"}), nil, "{}").
+			RowError(0, sqlmock.ErrCancelled))
+
+	err = GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestGetAllExpense_AbortsResponse_WhenRowFailsMidStream(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?filter=tag:food", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "Title", "Amount", "Note", "Tags", "CategoryId", "RuleIds"}).
+			AddRow(1, "test", 10, "", pq.Array([]string{"food"}), nil, "{}").
+			AddRow(2, "test", 10, "", pq.Array([]string{"food"}), nil, "{}").
+			RowError(1, sqlmock.ErrCancelled))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { GetAllExpenseHandler(c) })
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Body.String(), `[{"id":1`))
+	assert.False(t, strings.HasSuffix(rec.Body.String(), "]"), "a cut list must not look complete")
+}
@@ -0,0 +1,72 @@
+package expense
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/search"
+)
+
+type SearchResult struct {
+	Expense
+	Rank    float64 `json:"rank"`
+	Snippet Snippet `json:"snippet"`
+}
+
+// Snippet holds excerpts of the matching fields with matches wrapped in <b></b>.
+type Snippet struct {
+	Title string `json:"title,omitempty"`
+	Note  string `json:"note,omitempty"`
+}
+
+const maxSearchLimit = 100
+
+// SearchExpenseHandler ranks expenses whose title or note contain every word
+// of ?q=, most relevant first.
+func SearchExpenseHandler(c echo.Context) error {
+	q := c.QueryParam("q")
+	query := search.Query(q)
+	if len(query) == 0 {
+		return problem.BadRequest(c, "Invalid request, missing query q")
+	}
+
+	limit := 20
+	if s := c.QueryParam("limit"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return problem.BadRequest(c, "Invalid request, limit must be between 1 and 100")
+		}
+		limit = n
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, `SELECT id, title, amount, note, tags, category_id, rule_ids, ts_rank(search, q) AS rank
+	FROM expenses, CAST($1 AS tsquery) q
+	WHERE search @@ q
+	ORDER BY rank DESC, id
+	LIMIT $2`, query, limit)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		r := SearchResult{}
+		e := &r.Expense
+		err := rows.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds), &r.Rank)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		r.Snippet = Snippet{Title: search.Snippet(e.Title, q), Note: search.Snippet(e.Note, q)}
+		results = append(results, r)
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
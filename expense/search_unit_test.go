@@ -0,0 +1,56 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestSearchExpense_ReturnBadRequest_WhenQueryMissing(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/search?q=%20", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := SearchExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestSearchExpense_ReturnRankedResultsWithSnippets(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/search?q=night+market", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM expenses, CAST\\(\\$1 AS tsquery\\) q WHERE search @@ q").
+		WithArgs("'night' & 'market'", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id", "rule_ids", "rank"}).
+			AddRow(1, "smoothie", 79.0, "night market promotion", pq.Array([]string{"food"}), nil, "{}", 0.4))
+
+	err = SearchExpenseHandler(c)
+
+	expected := "[{\"id\":1,\"title\":\"smoothie\",\"amount\":79,\"note\":\"night market promotion\",\"tags\":[\"food\"],\"rank\":0.4,\"snippet\":{\"note\":\"\\u003cb\\u003enight\\u003c/b\\u003e \\u003cb\\u003emarket\\u003c/b\\u003e promotion\"}}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
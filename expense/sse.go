@@ -0,0 +1,128 @@
+package expense
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+)
+
+// MIMETextEventStream is the content type of Server-Sent Events.
+const MIMETextEventStream = "text/event-stream"
+
+// EventReset tells a stream client that events it missed are no longer
+// kept, so it should reload the expenses before applying new events.
+const EventReset = "reset"
+
+// heartbeat is how often an idle stream sends a comment, so that proxies
+// keep it open and dead clients are noticed.
+var heartbeat = 15 * time.Second
+
+var feed *outbox.Feed
+
+// SetFeed sets where StreamExpenseHandler gets events from.
+func SetFeed(f *outbox.Feed) {
+	feed = f
+}
+
+// StreamExpenseHandler sends expense changes as Server-Sent Events until
+// the client goes away or the server shuts down. A caller gets the changes
+// of expenses they created, directly or through a recurring template, and
+// of expenses without an owner, which are created with authentication off
+// or predate owners, and so are shared. Each event has the
+// outbox sequence number as its id and the event type as its name; a
+// client reconnecting with Last-Event-ID gets what it missed, if it is
+// still kept, and an EventReset event otherwise.
+func StreamExpenseHandler(c echo.Context) error {
+	if feed == nil {
+		return problem.Internal(c, errors.New("expense stream is not set up"))
+	}
+
+	last := feed.Last()
+	if s := c.Request().Header.Get("Last-Event-ID"); len(s) > 0 {
+		seq, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || seq < 0 {
+			return problem.BadRequest(c, "Invalid request, Last-Event-ID must be an event id")
+		}
+		last = seq
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, MIMETextEventStream)
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(res.Writer)
+
+	user := auth.User(c)
+	ctx := c.Request().Context()
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	// send writes b with a deadline of its own: the server's write
+	// timeout is meant for whole responses and would end the stream.
+	send := func(b []byte) error {
+		rc.SetWriteDeadline(time.Now().Add(2 * heartbeat))
+		if _, err := res.Write(b); err != nil {
+			log.DebugContext(ctx, "Expense stream closed", "error", err)
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+
+	if err := send([]byte(fmt.Sprintf("retry: %d\n\n", 5*time.Second/time.Millisecond))); err != nil {
+		return nil
+	}
+	for {
+		events, ok, wake := feed.Since(last)
+		if !ok {
+			last = feed.Last()
+			if err := send([]byte(fmt.Sprintf("id: %d\nevent: %s\ndata: {}\n\n", last, EventReset))); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		if len(events) > 0 {
+			var b []byte
+			for _, e := range events {
+				if len(e.Owner) > 0 && e.Owner != user {
+					continue
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					log.ErrorContext(ctx, "Expense stream aborted", "seq", e.Seq, "error", err)
+					return nil
+				}
+				b = append(b, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)...)
+			}
+			if len(b) > 0 {
+				if err := send(b); err != nil {
+					return nil
+				}
+				ticker.Reset(heartbeat)
+			}
+			last = events[len(events)-1].Seq
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+			if err := send([]byte(": heartbeat\n\n")); err != nil {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		case <-feed.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+//go:build unit
+
+package expense
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+)
+
+var outboxColumns = []string{"seq", "type", "expense_id", "owner", "data", "created_at"}
+
+// streamFeed sets up a feed holding events 1 to n, kept up to size.
+func streamFeed(t *testing.T, size int, n int64) (*outbox.Feed, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.Db = db
+
+	rows := sqlmock.NewRows(outboxColumns)
+	for seq := int64(1); seq <= n; seq++ {
+		rows.AddRow(seq, outbox.ExpenseCreated, seq, "", `{"id":1}`, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	f := outbox.NewFeed(size, time.Hour)
+	if err := f.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	SetFeed(f)
+	t.Cleanup(func() { SetFeed(nil) })
+	return f, mock
+}
+
+// openStream connects to StreamExpenseHandler with lastEventId, if not
+// empty.
+func openStream(t *testing.T, lastEventId string) (*http.Response, *bufio.Reader) {
+	return openStreamAs(t, "", lastEventId)
+}
+
+// openStreamAs is openStream authenticated as user.
+func openStreamAs(t *testing.T, user, lastEventId string) (*http.Response, *bufio.Reader) {
+	e := echo.New()
+	e.GET("/expenses/stream", StreamExpenseHandler, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, user)
+			return next(c)
+		}
+	})
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/expenses/stream", nil)
+	if len(lastEventId) > 0 {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res, bufio.NewReader(res.Body)
+}
+
+// nextEvent reads up to the next blank line.
+func nextEvent(t *testing.T, r *bufio.Reader) string {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		if line == "\n" {
+			return b.String()
+		}
+		b.WriteString(line)
+	}
+}
+
+func TestStreamExpense_ResumesAfterLastEventId(t *testing.T) {
+	streamFeed(t, 10, 3)
+
+	res, r := openStream(t, "1")
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, MIMETextEventStream, res.Header.Get(echo.HeaderContentType))
+	assert.Equal(t, "retry: 5000\n", nextEvent(t, r))
+	assert.Equal(t, `id: 2
+event: expense.created
+data: {"seq":2,"type":"expense.created","expense_id":2,"data":{"id":1},"created_at":"2024-05-01T09:00:00Z"}
+`, nextEvent(t, r))
+	assert.True(t, strings.HasPrefix(nextEvent(t, r), "id: 3\n"))
+}
+
+func TestStreamExpense_SendsNewEventsAndHeartbeats(t *testing.T) {
+	saved := heartbeat
+	t.Cleanup(func() { heartbeat = saved })
+	heartbeat = 50 * time.Millisecond
+	f, mock := streamFeed(t, 10, 3)
+
+	_, r := openStream(t, "")
+	nextEvent(t, r) // retry
+	assert.Equal(t, ": heartbeat\n", nextEvent(t, r))
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(outboxColumns).
+		AddRow(4, outbox.ExpenseDeleted, 9, "", `{"id":9}`, time.Now()))
+	assert.NoError(t, f.Poll(context.Background()))
+
+	ev := nextEvent(t, r)
+	for strings.HasPrefix(ev, ":") {
+		ev = nextEvent(t, r)
+	}
+	assert.True(t, strings.HasPrefix(ev, "id: 4\nevent: expense.deleted\n"), ev)
+}
+
+func TestStreamExpense_ResetsWhenMissedEventsAreGone(t *testing.T) {
+	streamFeed(t, 2, 5)
+
+	_, r := openStream(t, "1")
+
+	nextEvent(t, r) // retry
+	assert.Equal(t, "id: 5\nevent: reset\ndata: {}\n", nextEvent(t, r))
+}
+
+func TestStreamExpense_ReturnBadRequest_WhenLastEventIdInvalid(t *testing.T) {
+	streamFeed(t, 2, 0)
+
+	res, _ := openStream(t, "abc")
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestStreamExpense_EndsWhenFeedStops(t *testing.T) {
+	f, mock := streamFeed(t, 2, 0)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(outboxColumns))
+	f.Start()
+
+	_, r := openStream(t, "")
+	nextEvent(t, r) // retry
+	f.Stop()
+
+	_, err := r.ReadString('\n')
+	assert.Error(t, err)
+}
+
+func TestStreamExpense_SendsOnlyOwnAndSharedExpenses(t *testing.T) {
+	f, mock := streamFeed(t, 10, 0)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(outboxColumns).
+		AddRow(1, outbox.ExpenseCreated, 1, "alice", `{"id":1}`, time.Now()).
+		AddRow(2, outbox.ExpenseCreated, 2, "bob", `{"id":2}`, time.Now()).
+		AddRow(3, outbox.ExpenseCreated, 3, "", `{"id":3}`, time.Now()))
+	assert.NoError(t, f.Poll(context.Background()))
+
+	_, r := openStreamAs(t, "alice", "0")
+
+	nextEvent(t, r) // retry
+	assert.True(t, strings.HasPrefix(nextEvent(t, r), "id: 1\n"))
+	assert.True(t, strings.HasPrefix(nextEvent(t, r), "id: 3\n"))
+}
@@ -0,0 +1,123 @@
+package expense
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// MIMEApplicationNDJSON lists one JSON expense per line.
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// flushEvery is how many rows are sent between flushes.
+const flushEvery = 100
+
+// wantsNDJSON reports whether the client asked for NDJSON.
+func wantsNDJSON(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMEApplicationNDJSON)
+}
+
+// listWriter streams a list as rows are read, as a JSON array or as NDJSON.
+// Nothing is sent before the first row, so that a list that is empty or
+// fails early still gets a proper status.
+type listWriter struct {
+	c      echo.Context
+	ndjson bool
+	cache  *cache.Buffer
+	n      int
+}
+
+// newListWriter streams to c and, for JSON arrays, into entry. NDJSON is
+// meant for exports and is not cached.
+func newListWriter(c echo.Context, entry cache.Entry) *listWriter {
+	w := &listWriter{c: c, ndjson: wantsNDJSON(c)}
+	if w.ndjson {
+		entry = cache.Entry{}
+	}
+	w.cache = entry.Buffer()
+	return w
+}
+
+func (w *listWriter) start() error {
+	res := w.c.Response()
+	if w.ndjson {
+		res.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+	} else {
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	}
+	cache.Headers(w.c, false)
+	res.WriteHeader(http.StatusOK)
+	if !w.ndjson {
+		return w.send([]byte("["))
+	}
+	return nil
+}
+
+func (w *listWriter) send(b []byte) error {
+	w.cache.Write(b)
+	_, err := w.c.Response().Write(b)
+	return err
+}
+
+// write sends one row.
+func (w *listWriter) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if w.n == 0 {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case w.ndjson:
+		b = append(b, '\n')
+	case w.n > 0:
+		b = append([]byte(","), b...)
+	}
+	if err := w.send(b); err != nil {
+		return err
+	}
+
+	w.n++
+	if w.n%flushEvery == 0 {
+		w.c.Response().Flush()
+	}
+	return nil
+}
+
+// close ends the list and stores it.
+func (w *listWriter) close() error {
+	if w.n == 0 {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	if !w.ndjson {
+		if err := w.send([]byte("]")); err != nil {
+			return err
+		}
+	}
+	// The list may have taken longer than QueryTimeout to send.
+	ctx, cancel := database.Context(w.c.Request().Context())
+	defer cancel()
+	w.cache.Store(ctx)
+	return nil
+}
+
+// fail reports err as a 500 if nothing was sent yet. Otherwise the status
+// is gone, so the connection is cut: the client sees a broken response
+// rather than a short list that looks complete.
+func (w *listWriter) fail(err error) error {
+	if !w.c.Response().Committed {
+		return problem.Internal(w.c, err)
+	}
+	log.ErrorContext(w.c.Request().Context(), "List aborted", "rows", w.n, "error", err)
+	panic(http.ErrAbortHandler)
+}
@@ -1,12 +1,20 @@
 package expense
 
 import (
+	"database/sql"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/cache"
 	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/search"
+	"github.com/umateedev/assessment/tag"
+	"github.com/umateedev/assessment/validate"
+	"github.com/umateedev/assessment/webhook"
 )
 
 func UpdateExpenseHandler(c echo.Context) error {
@@ -14,26 +22,52 @@ func UpdateExpenseHandler(c echo.Context) error {
 
 	id := c.Param("id")
 	if len(id) == 0 {
-		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid request, missing param id"})
+		return problem.BadRequest(c, "Invalid request, missing param id")
 	}
 
 	err := c.Bind(&e)
 	if err != nil {
-		log.Printf("Invalid request %s", err.Error())
-		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid request"})
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
 	}
+	e.Tags = tag.Normalize(e.Tags)
+	if errs := validate.StructFor(auth.User(c), &e); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
 
-	stmt, err := database.Db.Prepare("UPDATE expenses SET title = $1, amount = $2, note = $3, tags = $4 WHERE id = $5 RETURNING id")
+	stmt, err := database.Stmt(ctx, `UPDATE expenses x SET title = $1, amount = $2, note = $3, tags = $4, category_id = $5, search = $6::tsvector
+	FROM (SELECT id, amount, category_id FROM expenses WHERE id = $7 FOR UPDATE) old
+	WHERE x.id = old.id RETURNING x.id, COALESCE(x.owner, ''), old.amount, old.category_id`)
 	if err != nil {
-		log.Printf("Prepare statement error", err)
-		return c.JSON(http.StatusInternalServerError, "Prepare statement error")
+		return problem.Internal(c, err)
 	}
 
-	row := stmt.QueryRow(e.Title, e.Amount, e.Note, pq.Array(&e.Tags), id)
-	err = row.Scan(&e.Id)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, Error{Message: err.Error()})
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		row := tx.StmtContext(ctx, stmt).QueryRowContext(ctx, e.Title, e.Amount, e.Note, pq.Array(&e.Tags), e.CategoryId, search.Vector(e.Title, e.Note), id)
+		var owner string
+		var old Expense
+		if err := row.Scan(&e.Id, &owner, &old.Amount, &old.CategoryId); err != nil {
+			return err
+		}
+		if err := outbox.Add(ctx, tx, outbox.ExpenseUpdated, e.Id, e); err != nil {
+			return err
+		}
+		return notify(ctx, tx, owner, webhook.ExpenseUpdated, e, &old)
+	})
+	if isForeignKeyViolation(err) {
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeCategoryNotFound, "category not found"))
+	}
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "expense not found")
+	default:
+		return problem.Internal(c, err)
 	}
 
+	cache.ExpenseChanged(ctx, e.Id)
 	return c.JSON(http.StatusOK, e)
 }
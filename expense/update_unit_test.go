@@ -12,6 +12,9 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
 )
 
 func TestUpdateExpense_ReturnBadRequest_WhenInvalidRequest(t *testing.T) {
@@ -101,7 +104,7 @@ func TestUpdateExpense_ReturnSuccess(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
-	updatedExpense := sqlmock.NewRows([]string{"Id"}).AddRow("1")
+	updatedExpense := sqlmock.NewRows([]string{"Id", "owner", "amount", "category_id"}).AddRow("1", "bob", 79.0, nil)
 
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -110,7 +113,16 @@ func TestUpdateExpense_ReturnSuccess(t *testing.T) {
 	defer db.Close()
 
 	database.Db = db
-	mock.ExpectPrepare("UPDATE expenses").ExpectQuery().WillReturnRows(updatedExpense)
+	stmt := mock.ExpectPrepare("UPDATE expenses")
+	mock.ExpectBegin()
+	stmt.ExpectQuery().WillReturnRows(updatedExpense)
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(outbox.ExpenseUpdated, 1, `{"id":1,"title":"strawberry smoothie","amount":79,"note":"night market promotion discount 10 bath","tags":["food","beverage"]}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), webhook.ExpenseUpdated, sqlmock.AnyArg(), "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	c := e.NewContext(req, rec)
 	c.SetPath("/expense/:id")
@@ -125,3 +137,85 @@ func TestUpdateExpense_ReturnSuccess(t *testing.T) {
 		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
 	}
 }
+
+func TestUpdateExpense_ReturnNotFound_WhenExpenseMissing(t *testing.T) {
+	e := echo.New()
+	body := `{"title": "tea", "amount": 20, "note": "", "tags": []}`
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	stmt := mock.ExpectPrepare("UPDATE expenses")
+	mock.ExpectBegin()
+	stmt.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	c := e.NewContext(req, rec)
+	c.SetPath("/expense/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("99")
+
+	err = UpdateExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, problem.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), `"code":"not_found"`)
+	}
+}
+
+func TestUpdateExpense_ChecksBudgetsOnlyWhenAmountOrCategoryChanged(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		oldAmount   float64
+		oldCategory interface{}
+		checked     bool
+	}{
+		{"unchanged", 79, 3, false},
+		{"amount changed", 50, 3, true},
+		{"category changed", 79, 2, true},
+		{"category added", 79, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Open sqlmock error '%s'", err)
+			}
+			defer db.Close()
+			database.Db = db
+
+			stmt := mock.ExpectPrepare("UPDATE expenses")
+			mock.ExpectBegin()
+			stmt.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id", "owner", "amount", "category_id"}).AddRow(1, "bob", tc.oldAmount, tc.oldCategory))
+			mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+			if tc.checked {
+				mock.ExpectQuery("FROM expenses x").
+					WithArgs(1, tc.oldCategory, tc.oldAmount).
+					WillReturnRows(sqlmock.NewRows([]string{"category_id", "name", "amount", "spent", "month"}))
+			}
+			mock.ExpectCommit()
+
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"title": "lunch", "amount": 79, "category_id": 3}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetPath("/expenses/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			err = UpdateExpenseHandler(c)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package expense
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/view"
+)
+
+// GetViewExpensesHandler lists the expenses selected by a saved view.
+func GetViewExpensesHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	v, err := view.Get(ctx, c.Param("id"), auth.User(c))
+	switch err {
+	case nil:
+		return listView(c, v)
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "view not found")
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+func listView(c echo.Context, v view.View) error {
+	query, args, err := v.Query("id, title, amount, note, tags, category_id, rule_ids")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	ctx, firstRow, cancel := database.StreamContext(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	w := newListWriter(c, cache.Entry{})
+	for rows.Next() {
+		firstRow()
+		e := Expense{}
+		err := rows.Scan(&e.Id, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.CategoryId, pq.Array(&e.RuleIds))
+		if err != nil {
+			return w.fail(err)
+		}
+
+		// Without a rate, which a config reload can remove, the amount stays
+		// in the base currency and is not labelled with the view's.
+		amount, converted := view.Convert(e.Amount, v.Currency)
+		row := map[string]interface{}{
+			"id":     e.Id,
+			"title":  e.Title,
+			"amount": amount,
+			"note":   e.Note,
+			"tags":   e.Tags,
+		}
+		if e.CategoryId != nil {
+			row["category_id"] = *e.CategoryId
+		}
+		if len(e.RuleIds) > 0 {
+			row["rule_ids"] = e.RuleIds
+		}
+
+		row = v.Project(row)
+		if converted {
+			row["currency"] = v.Currency
+		}
+		if err := w.write(row); err != nil {
+			return w.fail(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return w.fail(err)
+	}
+
+	if err := w.close(); err != nil {
+		return w.fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/view"
+)
+
+var viewColumns = []string{"id", "owner", "name", "filter", "sort", "columns", "currency", "shared", "pinned"}
+
+func TestGetViewExpenses_ReturnProjectedAndConverted(t *testing.T) {
+	view.SetCurrencies("", map[string]float64{"USD": 0.03})
+	t.Cleanup(func() { view.SetCurrencies("", nil) })
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/views/:id/expenses")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	auth.SetUser(c, "alice")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM views WHERE id = \\$1 AND \\(owner = \\$2 OR shared\\)").
+		WithArgs("1", "alice").
+		WillReturnRows(sqlmock.NewRows(viewColumns).
+			AddRow(1, "bob", "food", "tag:food", "-amount", pq.Array([]string{"title", "amount"}), "USD", true, false))
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE \\$1 = ANY\\(COALESCE\\(tags, '{}'\\)\\) ORDER BY amount DESC, id ASC").
+		WithArgs("food").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id", "rule_ids"}).
+			AddRow(1, "dinner", 1000.0, "", pq.Array([]string{"food"}), nil, "{}"))
+
+	err = GetViewExpensesHandler(c)
+
+	expected := "[{\"amount\":30,\"currency\":\"USD\",\"title\":\"dinner\"}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestGetViewExpenses_DropsTheCurrency_WhenRateMissing(t *testing.T) {
+	view.SetCurrencies("", map[string]float64{"EUR": 0.026})
+	t.Cleanup(func() { view.SetCurrencies("", nil) })
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/views/:id/expenses")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	auth.SetUser(c, "alice")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM views WHERE id = \\$1 AND \\(owner = \\$2 OR shared\\)").
+		WithArgs("1", "alice").
+		WillReturnRows(sqlmock.NewRows(viewColumns).
+			AddRow(1, "bob", "food", "tag:food", "-amount", pq.Array([]string{"title", "amount"}), "USD", true, false))
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE \\$1 = ANY\\(COALESCE\\(tags, '{}'\\)\\) ORDER BY amount DESC, id ASC").
+		WithArgs("food").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id", "rule_ids"}).
+			AddRow(1, "dinner", 1000.0, "", pq.Array([]string{"food"}), nil, "{}"))
+
+	err = GetViewExpensesHandler(c)
+
+	expected := "[{\"amount\":1000,\"title\":\"dinner\"}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestGetAllExpense_UsesPinnedView_WhenNoParams(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, "alice")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM views WHERE owner = \\$1 AND pinned").
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows(viewColumns).
+			AddRow(3, "alice", "big", "amount>500", "", pq.Array([]string{}), "", false, true))
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE amount > \\$1 ORDER BY id ASC").
+		WithArgs(500.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id", "rule_ids"}))
+
+	err = GetAllExpenseHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "[]", strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"strings"
+)
+
+// Eval reports whether r matches n. It mirrors Compile, except that
+// category:N only matches N itself since the category tree is not in memory.
+func Eval(n Node, r Record) bool {
+	switch n := n.(type) {
+	case And:
+		return Eval(n.Left, r) && Eval(n.Right, r)
+	case Or:
+		return Eval(n.Left, r) || Eval(n.Right, r)
+	case Not:
+		return !Eval(n.X, r)
+	case Set:
+		return evalSet(n, r.Tags)
+	case Compare:
+		return evalCompare(n, r)
+	}
+	return true
+}
+
+func evalSet(n Set, tags []string) bool {
+	has := map[string]bool{}
+	for _, t := range tags {
+		has[t] = true
+	}
+
+	switch n.Op {
+	case "any":
+		for _, v := range n.Values {
+			if has[v] {
+				return true
+			}
+		}
+		return false
+	case "all":
+		for _, v := range n.Values {
+			if !has[v] {
+				return false
+			}
+		}
+		return true
+	}
+	return has[n.Values[0]]
+}
+
+func evalCompare(n Compare, r Record) bool {
+	switch n.Field {
+	case "title", "note":
+		text := r.Title
+		if n.Field == "note" {
+			text = r.Note
+		}
+		v := n.Value.(string)
+		switch n.Op {
+		case ":":
+			return strings.Contains(strings.ToLower(text), strings.ToLower(v))
+		case "=":
+			return text == v
+		}
+		return text != v
+	case "tag", "tags":
+		return evalSet(Set{Op: "has", Values: []string{n.Value.(string)}}, r.Tags)
+	case "amount":
+		return compareFloat(r.Amount, n.Op, n.Value.(float64))
+	case "id":
+		return compareFloat(float64(r.Id), n.Op, float64(n.Value.(int)))
+	case "category":
+		match := r.CategoryId != nil && *r.CategoryId == n.Value.(int)
+		if n.Op == "!=" {
+			return !match
+		}
+		return match
+	case "date":
+		d := n.Value.(DateRange)
+		in := (d.From.IsZero() || !r.CreatedAt.Before(d.From)) && (d.To.IsZero() || r.CreatedAt.Before(d.To))
+		if n.Op == "!=" {
+			return !in
+		}
+		return in
+	}
+	return false
+}
+
+func compareFloat(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+//go:build unit
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	category := 3
+	r := Record{
+		Id:         7,
+		Title:      "Strawberry smoothie",
+		Note:       "night market promotion",
+		Amount:     79,
+		Tags:       []string{"food", "beverage"},
+		CategoryId: &category,
+		CreatedAt:  time.Date(2023, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+
+	for expr, want := range map[string]bool{
+		"tag:food AND amount>50 AND NOT note:refund": true,
+		"title:SMOOTHIE":                    true,
+		"title=smoothie":                    false,
+		"amount>=80 OR id=7":                true,
+		"tags any (gadget, beverage)":       true,
+		"tags all (food, gadget)":           false,
+		"tags has beverage":                 true,
+		"date:2023-01-01..2023-01-15":       true,
+		"date>2023-01-15":                   false,
+		"date<=2023-01-15 date>=2023-01-15": true,
+		"date!=2023-01-15":                  false,
+		"category:3 AND category!=4":        true,
+		"NOT (tag:food OR tag:gadget)":      false,
+	} {
+		n, err := Parse(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, want, Eval(n, r), expr)
+		}
+	}
+}
+
+func TestEval_NullTagsAndCategory(t *testing.T) {
+	r := Record{Id: 8, Title: "Bus fare", Amount: 15}
+
+	for expr, want := range map[string]bool{
+		"tag:food":                 false,
+		"NOT tag:food":             true,
+		"tags any (food, travel)":  false,
+		"NOT tags all (food)":      true,
+		"category:4":               false,
+		"NOT category:4":           true,
+		"category!=4":              true,
+		"note!=refund":             true,
+		"NOT note:refund":          true,
+		"NOT (tag:food OR note:x)": true,
+	} {
+		n, err := Parse(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, want, Eval(n, r), expr)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package filter implements a small expression language for selecting
+// expenses, for example:
+//
+//	tag:food AND amount>200 AND NOT note:refund
+//	tags any (food, coffee) date:2023-01-01..2023-01-31
+//	(title:"night market" OR category:3) amount<=500
+//
+// Comparisons are field op value with the operators = != > >= < <= and ":",
+// which means "contains" for text, "has" for tags, "on or within" for dates
+// and "in or below" for categories. Tags also support has, any (...) and
+// all (...). Terms are combined with AND, OR, NOT and parentheses; adjacent
+// terms are ANDed.
+//
+// An expression is parsed once and can then be compiled to a parameterized
+// SQL condition or evaluated against a Record in memory.
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	X Node
+}
+
+// Compare is a field compared with a single value. Value is a string,
+// float64, int or DateRange depending on the field.
+type Compare struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// Set is a tag set operator: has, any or all.
+type Set struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+// DateRange is the half-open interval [From, To). A zero bound is open.
+type DateRange struct {
+	From, To time.Time
+}
+
+func (And) node()     {}
+func (Or) node()      {}
+func (Not) node()     {}
+func (Compare) node() {}
+func (Set) node()     {}
+
+// Record is the in-memory view of an expense used by Eval.
+type Record struct {
+	Id         int
+	Title      string
+	Note       string
+	Amount     float64
+	Tags       []string
+	CategoryId *int
+	CreatedAt  time.Time
+}
+
+// SyntaxError points at the byte offset in the expression where parsing failed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+const (
+	kindText     = "text"
+	kindNumber   = "number"
+	kindTags     = "tags"
+	kindDate     = "date"
+	kindCategory = "category"
+	kindId       = "id"
+)
+
+type field struct {
+	column string
+	kind   string
+}
+
+var fields = map[string]field{
+	"title":    {"title", kindText},
+	"note":     {"note", kindText},
+	"amount":   {"amount", kindNumber},
+	"tag":      {"tags", kindTags},
+	"tags":     {"tags", kindTags},
+	"date":     {"created_at", kindDate},
+	"category": {"category_id", kindCategory},
+	"id":       {"id", kindId},
+}
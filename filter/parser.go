@@ -0,0 +1,294 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// Parse parses an expression. Errors are *SyntaxError.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, &SyntaxError{0, "empty expression"}
+	}
+
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{t.pos, "unexpected " + describe(t)}
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) keyword(t token, kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+func (p *parser) or() (Node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword(p.peek(), "OR") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if p.keyword(t, "AND") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || p.keyword(t, "OR") {
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) unary() (Node, error) {
+	t := p.peek()
+	if p.keyword(t, "NOT") {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+
+	if t.kind == tokLParen {
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, &SyntaxError{t.pos, "expected ')' but found " + describe(t)}
+		}
+		return n, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Node, error) {
+	t := p.next()
+	if t.kind != tokWord {
+		return nil, &SyntaxError{t.pos, "expected field but found " + describe(t)}
+	}
+	name := strings.ToLower(t.text)
+	f, ok := fields[name]
+	if !ok {
+		return nil, &SyntaxError{t.pos, "unknown field '" + t.text + "'"}
+	}
+
+	op := p.next()
+	if f.kind == kindTags && op.kind == tokWord {
+		return p.set(name, op)
+	}
+	if op.kind != tokOp {
+		return nil, &SyntaxError{op.pos, "expected operator after " + name + " but found " + describe(op)}
+	}
+
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, &SyntaxError{v.pos, "expected value but found " + describe(v)}
+	}
+
+	value, err := convert(f.kind, op, v)
+	if err != nil {
+		return nil, err
+	}
+	return Compare{Field: name, Op: op.text, Value: value}, nil
+}
+
+func (p *parser) set(name string, op token) (Node, error) {
+	kw := strings.ToLower(op.text)
+	switch kw {
+	case "has":
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, &SyntaxError{v.pos, "expected tag but found " + describe(v)}
+		}
+		return Set{Field: name, Op: "has", Values: []string{v.text}}, nil
+	case "any", "all":
+	default:
+		return nil, &SyntaxError{op.pos, "expected operator or has, any, all after " + name + " but found " + describe(op)}
+	}
+
+	if t := p.next(); t.kind != tokLParen {
+		return nil, &SyntaxError{t.pos, "expected '(' after " + kw}
+	}
+
+	var values []string
+	for {
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, &SyntaxError{v.pos, "expected tag but found " + describe(v)}
+		}
+		values = append(values, v.text)
+
+		t := p.next()
+		if t.kind == tokRParen {
+			break
+		}
+		if t.kind != tokComma {
+			return nil, &SyntaxError{t.pos, "expected ',' or ')' but found " + describe(t)}
+		}
+	}
+
+	return Set{Field: name, Op: kw, Values: values}, nil
+}
+
+// convert checks the operator is valid for the kind of field and parses the value.
+func convert(kind string, op, v token) (interface{}, error) {
+	invalidOp := &SyntaxError{op.pos, "operator " + op.text + " is not supported for " + kind + " fields"}
+
+	switch kind {
+	case kindText:
+		if op.text != ":" && op.text != "=" && op.text != "!=" {
+			return nil, invalidOp
+		}
+		return v.text, nil
+	case kindTags:
+		if op.text != ":" && op.text != "=" {
+			return nil, invalidOp
+		}
+		return v.text, nil
+	case kindNumber:
+		if op.text == ":" {
+			return nil, invalidOp
+		}
+		f, err := strconv.ParseFloat(v.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{v.pos, "expected number but found " + describe(v)}
+		}
+		return f, nil
+	case kindId, kindCategory:
+		if kind == kindId && op.text == ":" {
+			return nil, invalidOp
+		}
+		if kind == kindCategory && op.text != ":" && op.text != "=" && op.text != "!=" {
+			return nil, invalidOp
+		}
+		n, err := strconv.Atoi(v.text)
+		if err != nil {
+			return nil, &SyntaxError{v.pos, "expected integer but found " + describe(v)}
+		}
+		return n, nil
+	case kindDate:
+		return dateValue(op, v)
+	}
+	return nil, invalidOp
+}
+
+// dateValue turns every date comparison into a DateRange in UTC, so
+// "date > 2023-01-31" becomes [2023-02-01, ∞).
+func dateValue(op, v token) (interface{}, error) {
+	parse := func(s string, offset int) (time.Time, error) {
+		if len(s) == 0 {
+			return time.Time{}, nil
+		}
+		d, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return d, &SyntaxError{v.pos + offset, "expected date YYYY-MM-DD but found '" + s + "'"}
+		}
+		return d, nil
+	}
+
+	if from, to, ok := strings.Cut(v.text, ".."); ok {
+		if op.text != ":" && op.text != "=" {
+			return nil, &SyntaxError{op.pos, "date ranges require ':' or '='"}
+		}
+		f, err := parse(from, 0)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parse(to, len(from)+2)
+		if err != nil {
+			return nil, err
+		}
+		if !t.IsZero() {
+			t = t.AddDate(0, 0, 1)
+		}
+		if len(from) == 0 && len(to) == 0 {
+			return nil, &SyntaxError{v.pos, "date range needs at least one bound"}
+		}
+		return DateRange{f, t}, nil
+	}
+
+	d, err := parse(v.text, 0)
+	if err != nil {
+		return nil, err
+	}
+	next := d.AddDate(0, 0, 1)
+
+	switch op.text {
+	case ":", "=", "!=":
+		return DateRange{d, next}, nil
+	case ">":
+		return DateRange{From: next}, nil
+	case ">=":
+		return DateRange{From: d}, nil
+	case "<":
+		return DateRange{To: d}, nil
+	case "<=":
+		return DateRange{To: next}, nil
+	}
+	return nil, &SyntaxError{op.pos, "operator " + op.text + " is not supported for date fields"}
+}
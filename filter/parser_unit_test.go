@@ -0,0 +1,62 @@
+//go:build unit
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_PrecedenceAndImplicitAnd(t *testing.T) {
+	n, err := Parse(`tag:food AND amount>200 AND NOT note:refund OR title:"night market" category=3`)
+
+	expected := Or{
+		And{And{Compare{"tag", ":", "food"}, Compare{"amount", ">", 200.0}}, Not{Compare{"note", ":", "refund"}}},
+		And{Compare{"title", ":", "night market"}, Compare{"category", "=", 3}},
+	}
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, n)
+	}
+}
+
+func TestParse_TagSetsAndDateRanges(t *testing.T) {
+	n, err := Parse(`(tags any (food, "fast food") OR tags all (a,b)) date:2023-01-01..2023-01-31 date<2024-01-01`)
+
+	jan1 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb1 := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	next := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := And{
+		And{
+			Or{Set{"tags", "any", []string{"food", "fast food"}}, Set{"tags", "all", []string{"a", "b"}}},
+			Compare{"date", ":", DateRange{jan1, feb1}},
+		},
+		Compare{"date", "<", DateRange{To: next}},
+	}
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, n)
+	}
+}
+
+func TestParse_ReturnPositionOfError(t *testing.T) {
+	for input, pos := range map[string]int{
+		"":                         0,
+		"amount > abc":             9,
+		"colour:red":               0,
+		"tag:food AND":             12,
+		"(amount > 1":              11,
+		"title:\"open":             6,
+		"date:2023-13-01":          5,
+		"date:2023-01-01..2023-02": 17,
+		"amount ! 5":               7,
+		"title > b":                6,
+		"tags some (a)":            5,
+		"tags any (a b)":           12,
+	} {
+		_, err := Parse(input)
+		if assert.Error(t, err, input) {
+			assert.Equal(t, pos, err.(*SyntaxError).Pos, input+": "+err.Error())
+		}
+	}
+}
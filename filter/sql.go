@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Compile turns n into a SQL condition over the expenses table. Values are
+// appended to args and referenced as $len(args)+1 onwards, so the condition
+// can be combined with other parameters. alias, when set, qualifies every
+// column, e.g. "e" gives e.amount.
+//
+// note, tags and category_id may be NULL. The condition treats them as an
+// empty note, no tags and no category, as Eval does, so a negated
+// predicate matches such rows rather than being NULL and dropping them.
+func Compile(n Node, alias string, args []interface{}) (string, []interface{}) {
+	c := &compiler{alias: alias, args: args}
+	return c.compile(n), c.args
+}
+
+type compiler struct {
+	alias string
+	args  []interface{}
+}
+
+func (c *compiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *compiler) column(name string) string {
+	col := fields[name].column
+	if len(c.alias) > 0 {
+		return c.alias + "." + col
+	}
+	return col
+}
+
+// tags is the tags column with NULL read as no tags.
+func (c *compiler) tags(name string) string {
+	return "COALESCE(" + c.column(name) + ", '{}')"
+}
+
+func (c *compiler) compile(n Node) string {
+	switch n := n.(type) {
+	case And:
+		return "(" + c.compile(n.Left) + " AND " + c.compile(n.Right) + ")"
+	case Or:
+		return "(" + c.compile(n.Left) + " OR " + c.compile(n.Right) + ")"
+	case Not:
+		return "(" + c.compile(n.X) + ") IS NOT TRUE"
+	case Set:
+		col := c.tags(n.Field)
+		switch n.Op {
+		case "any":
+			return col + " && " + c.arg(pq.Array(n.Values)) + "::text[]"
+		case "all":
+			return col + " @> " + c.arg(pq.Array(n.Values)) + "::text[]"
+		}
+		return c.arg(n.Values[0]) + " = ANY(" + col + ")"
+	case Compare:
+		return c.compare(n)
+	}
+	return "TRUE"
+}
+
+func (c *compiler) compare(n Compare) string {
+	col := c.column(n.Field)
+
+	switch fields[n.Field].kind {
+	case kindText:
+		text := "COALESCE(" + col + ", '')"
+		if n.Op == ":" {
+			return text + " ILIKE " + c.arg("%"+escapeLike(n.Value.(string))+"%")
+		}
+		return text + " " + n.Op + " " + c.arg(n.Value)
+	case kindTags:
+		return c.arg(n.Value) + " = ANY(" + c.tags(n.Field) + ")"
+	case kindCategory:
+		if n.Op == ":" {
+			return col + " IN (SELECT descendant FROM category_paths WHERE ancestor = " + c.arg(n.Value) + ")"
+		}
+		if n.Op == "!=" {
+			return col + " IS DISTINCT FROM " + c.arg(n.Value)
+		}
+		return col + " = " + c.arg(n.Value)
+	case kindDate:
+		r := n.Value.(DateRange)
+		var conds []string
+		if !r.From.IsZero() {
+			conds = append(conds, col+" >= "+c.arg(r.From))
+		}
+		if !r.To.IsZero() {
+			conds = append(conds, col+" < "+c.arg(r.To))
+		}
+		cond := "(" + strings.Join(conds, " AND ") + ")"
+		if n.Op == "!=" {
+			return cond + " IS NOT TRUE"
+		}
+		return cond
+	}
+
+	return col + " " + n.Op + " " + c.arg(n.Value)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,55 @@
+//go:build unit
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile_ParameterizesValues(t *testing.T) {
+	n, err := Parse(`tag:food AND amount>200 AND NOT note:"50%_off"`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sql, args := Compile(n, "", []interface{}{"first"})
+
+	assert.Equal(t, "(($2 = ANY(COALESCE(tags, '{}')) AND amount > $3) AND (COALESCE(note, '') ILIKE $4) IS NOT TRUE)", sql)
+	assert.Equal(t, []interface{}{"first", "food", 200.0, `%50\%\_off%`}, args)
+}
+
+func TestCompile_SetsDatesAndCategoriesWithAlias(t *testing.T) {
+	n, err := Parse(`tags all (a, b) date!=2023-01-01 category:4`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sql, args := Compile(n, "e", nil)
+
+	assert.Equal(t, "((COALESCE(e.tags, '{}') @> $1::text[] AND (e.created_at >= $2 AND e.created_at < $3) IS NOT TRUE) AND e.category_id IN (SELECT descendant FROM category_paths WHERE ancestor = $4))", sql)
+	assert.Equal(t, []interface{}{
+		pq.Array([]string{"a", "b"}),
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		4,
+	}, args)
+}
+
+func TestCompile_NegationsMatchNullColumns(t *testing.T) {
+	n, err := Parse(`NOT tag:food NOT tags any (a, b) NOT category:4 note!=x`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sql, args := Compile(n, "", nil)
+
+	assert.Equal(t, "(((($1 = ANY(COALESCE(tags, '{}'))) IS NOT TRUE AND "+
+		"(COALESCE(tags, '{}') && $2::text[]) IS NOT TRUE) AND "+
+		"(category_id IN (SELECT descendant FROM category_paths WHERE ancestor = $3)) IS NOT TRUE) AND "+
+		"COALESCE(note, '') != $4)", sql)
+	assert.Equal(t, []interface{}{"food", pq.Array([]string{"a", "b"}), 4, "x"}, args)
+}
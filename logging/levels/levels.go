@@ -0,0 +1,74 @@
+// Package levels serves /admin/log-levels, which reads and changes the
+// level each package logs at while the server runs.
+package levels
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/problem"
+)
+
+var log = logging.For("logging")
+
+// Levels is the body of GET /admin/log-levels.
+type Levels struct {
+	Default  string            `json:"default"`
+	Packages map[string]string `json:"packages"`
+}
+
+// PackageLevel is the level of one package.
+type PackageLevel struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+func levelName(pkg string) string {
+	if pkg == "" {
+		return strings.ToLower(logging.DefaultLevel().String())
+	}
+	return strings.ToLower(logging.Level(pkg).String())
+}
+
+// GetLevelsHandler lists the level every package logs at.
+func GetLevelsHandler(c echo.Context) error {
+	res := Levels{Default: levelName(""), Packages: map[string]string{}}
+	for _, p := range logging.Packages() {
+		res.Packages[p] = levelName(p)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// PutLevelHandler overrides the level of one package, also over the level
+// the config gives it, until it is reset.
+func PutLevelHandler(c echo.Context) error {
+	pl := PackageLevel{}
+	if err := c.Bind(&pl); err != nil {
+		return problem.BadRequest(c, "Invalid request body")
+	}
+	pl.Package = c.Param("package")
+
+	l, err := logging.ParseLevel(pl.Level)
+	if err != nil {
+		return problem.BadRequest(c, "level must be debug, info, warn or error")
+	}
+	if !logging.SetLevel(pl.Package, l) {
+		return problem.NotFound(c, "package not found")
+	}
+	log.InfoContext(c.Request().Context(), "Log level changed", "target", pl.Package, "level", levelName(pl.Package))
+
+	pl.Level = levelName(pl.Package)
+	return c.JSON(http.StatusOK, pl)
+}
+
+// DeleteLevelHandler puts a package back on the level of the config.
+func DeleteLevelHandler(c echo.Context) error {
+	pkg := c.Param("package")
+	if !logging.ResetLevel(pkg) {
+		return problem.NotFound(c, "package not found")
+	}
+	log.InfoContext(c.Request().Context(), "Log level reset", "target", pkg)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+//go:build unit
+
+package levels
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/problem"
+)
+
+func capture(t *testing.T) {
+	logging.SetOutput(io.Discard)
+	logging.SetLevels(slog.LevelInfo, nil)
+	for _, p := range logging.Packages() {
+		logging.ResetLevel(p)
+	}
+	t.Cleanup(func() { logging.SetOutput(os.Stderr) })
+}
+
+func TestPutLevelHandler(t *testing.T) {
+	capture(t)
+	logging.For("expense")
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level": "debug"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("package")
+	c.SetParamValues("expense")
+
+	err := PutLevelHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"package": "expense", "level": "debug"}`, rec.Body.String())
+		assert.Equal(t, slog.LevelDebug, logging.Level("expense"))
+	}
+}
+
+func TestPutLevelHandler_Rejects(t *testing.T) {
+	capture(t)
+	logging.For("expense")
+	tests := []struct {
+		pkg, body string
+		want      int
+	}{
+		{"expense", `{"level": "loud"}`, http.StatusBadRequest},
+		{"missing", `{"level": "debug"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("package")
+		c.SetParamValues(tt.pkg)
+
+		err := PutLevelHandler(c)
+
+		if assert.NoError(t, err, tt.body) {
+			assert.Equal(t, tt.want, rec.Code, tt.body)
+			assert.Equal(t, problem.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType), tt.body)
+		}
+	}
+}
+
+func TestGetLevelsHandler(t *testing.T) {
+	capture(t)
+	logging.For("expense")
+	logging.SetLevels(slog.LevelWarn, map[string]slog.Level{"expense": slog.LevelDebug})
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	err := GetLevelsHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Contains(t, rec.Body.String(), `"default":"warn"`)
+		assert.Contains(t, rec.Body.String(), `"expense":"debug"`)
+		assert.Contains(t, rec.Body.String(), `"http":"warn"`)
+	}
+}
+
+func TestDeleteLevelHandler_ReturnNotFound(t *testing.T) {
+	capture(t)
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), rec)
+	c.SetParamNames("package")
+	c.SetParamValues("missing")
+
+	err := DeleteLevelHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"not_found"`)
+	}
+}
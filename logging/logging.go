@@ -0,0 +1,219 @@
+// Package logging is the API's structured logger. Every package logs
+// through its own slog.Logger from For, which writes JSON lines carrying
+// the package name and, when given a request context, the request and
+// trace ids. Attributes that may hold credentials or personal data are
+// redacted, and levels can be changed per package while serving.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/umateedev/assessment/telemetry"
+)
+
+// Redacted replaces the value of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitive lists attribute keys whose values are never written. Keys are
+// compared case-insensitively.
+var sensitive = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"password":      true,
+	"password_hash": true,
+	"secret":        true,
+	"token":         true,
+	"dsn":           true,
+	"database_url":  true,
+	"username":      true,
+	"user":          true,
+	"email":         true,
+	"note":          true,
+}
+
+// replace redacts sensitive attributes and writes durations as "1.5s"
+// rather than nanoseconds.
+func replace(groups []string, a slog.Attr) slog.Attr {
+	if sensitive[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindDuration {
+		return slog.String(a.Key, a.Value.Duration().String())
+	}
+	return a
+}
+
+// output is where every logger writes; Setup replaces it.
+var output = struct {
+	sync.Mutex
+	w io.Writer
+}{w: os.Stderr}
+
+type writer struct{}
+
+func (writer) Write(p []byte) (int, error) {
+	output.Lock()
+	defer output.Unlock()
+	return output.w.Write(p)
+}
+
+// base formats every line. It lets everything through; pkgHandler decides
+// what is enabled.
+var base slog.Handler = slog.NewJSONHandler(writer{}, &slog.HandlerOptions{
+	Level:       slog.Level(-128),
+	ReplaceAttr: replace,
+})
+
+// SetOutput sends every logger's lines to w. They go to stderr until it is
+// called.
+func SetOutput(w io.Writer) {
+	output.Lock()
+	defer output.Unlock()
+	output.w = w
+}
+
+// SetLevels replaces the default level and the per-package levels of the
+// config. Overrides made with SetLevel keep taking precedence.
+func SetLevels(def slog.Level, packages map[string]slog.Level) {
+	configured := make(map[string]slog.Level, len(packages))
+	for p, l := range packages {
+		configured[p] = l
+	}
+	levels.Lock()
+	defer levels.Unlock()
+	levels.def = def
+	levels.configured = configured
+}
+
+var log = For("logging")
+
+var levels = struct {
+	sync.RWMutex
+	def        slog.Level
+	configured map[string]slog.Level
+	pkgs       map[string]slog.Level
+	known      map[string]bool
+}{configured: map[string]slog.Level{}, pkgs: map[string]slog.Level{}, known: map[string]bool{}}
+
+// For returns the logger of pkg. Packages call it once, at init.
+func For(pkg string) *slog.Logger {
+	h := slog.Handler(base)
+	if len(pkg) > 0 {
+		levels.Lock()
+		levels.known[pkg] = true
+		levels.Unlock()
+		h = h.WithAttrs([]slog.Attr{slog.String("package", pkg)})
+	}
+	return slog.New(pkgHandler{h, pkg})
+}
+
+// Level returns the level pkg logs at.
+func Level(pkg string) slog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	if l, ok := levels.pkgs[pkg]; ok {
+		return l
+	}
+	if l, ok := levels.configured[pkg]; ok {
+		return l
+	}
+	return levels.def
+}
+
+// DefaultLevel returns the level of packages without an override.
+func DefaultLevel() slog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	return levels.def
+}
+
+// SetDefaultLevel changes the level of packages without an override.
+func SetDefaultLevel(l slog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.def = l
+}
+
+// SetLevel overrides the level of pkg. It reports false for a package that
+// has no logger.
+func SetLevel(pkg string, l slog.Level) bool {
+	levels.Lock()
+	defer levels.Unlock()
+	if !levels.known[pkg] {
+		return false
+	}
+	levels.pkgs[pkg] = l
+	return true
+}
+
+// ResetLevel drops the override of pkg, if any, putting it back on the
+// level of the config.
+func ResetLevel(pkg string) bool {
+	levels.Lock()
+	defer levels.Unlock()
+	if !levels.known[pkg] {
+		return false
+	}
+	delete(levels.pkgs, pkg)
+	return true
+}
+
+// ParseLevel parses debug, info, warn or error, in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
+
+// Packages returns the names of all packages with a logger, sorted.
+func Packages() []string {
+	levels.RLock()
+	defer levels.RUnlock()
+	pkgs := make([]string, 0, len(levels.known))
+	for p := range levels.known {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+type requestIdKey struct{}
+
+// WithRequestId returns ctx carrying the request id to log.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// pkgHandler applies the level of pkg and adds the ids in the context.
+type pkgHandler struct {
+	slog.Handler
+	pkg string
+}
+
+func (h pkgHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= Level(h.pkg)
+}
+
+func (h pkgHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(requestIdKey{}).(string); ok && len(id) > 0 {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := telemetry.TraceID(ctx); len(id) > 0 {
+		r.AddAttrs(slog.String("trace_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h pkgHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return pkgHandler{h.Handler.WithAttrs(attrs), h.pkg}
+}
+
+func (h pkgHandler) WithGroup(name string) slog.Handler {
+	return pkgHandler{h.Handler.WithGroup(name), h.pkg}
+}
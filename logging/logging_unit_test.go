@@ -0,0 +1,96 @@
+//go:build unit
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func capture(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+	SetLevels(slog.LevelInfo, nil)
+	for _, p := range Packages() {
+		ResetLevel(p)
+	}
+	t.Cleanup(func() { SetOutput(os.Stderr) })
+	return buf
+}
+
+func line(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	m := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	return m
+}
+
+func TestLogger_RedactsSensitiveFields(t *testing.T) {
+	buf := capture(t)
+
+	For("test").Info("Created user", "username", "alice", "Password", "hunter2", "note", "rent for flat 4B", "id", 7)
+
+	m := line(t, buf)
+	assert.Equal(t, Redacted, m["username"])
+	assert.Equal(t, Redacted, m["Password"])
+	assert.Equal(t, Redacted, m["note"])
+	assert.Equal(t, float64(7), m["id"])
+	assert.Equal(t, "test", m["package"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestLogger_AddsRequestId(t *testing.T) {
+	buf := capture(t)
+	ctx := WithRequestId(context.Background(), "r1")
+
+	For("test").InfoContext(ctx, "Invalid request")
+
+	assert.Equal(t, "r1", line(t, buf)["request_id"])
+}
+
+func TestLogger_PerPackageLevels(t *testing.T) {
+	buf := capture(t)
+	quiet, chatty := For("quiet"), For("chatty")
+
+	assert.True(t, SetLevel("quiet", slog.LevelError))
+	assert.True(t, SetLevel("chatty", slog.LevelDebug))
+	assert.False(t, SetLevel("missing", slog.LevelDebug))
+
+	quiet.Warn("dropped")
+	assert.Empty(t, buf.String())
+	chatty.Debug("kept")
+	assert.Equal(t, "kept", line(t, buf)["msg"])
+
+	buf.Reset()
+	assert.True(t, ResetLevel("quiet"))
+	quiet.Warn("kept")
+	assert.Equal(t, "quiet", line(t, buf)["package"])
+}
+
+func TestSetLevels_KeepsOverrides(t *testing.T) {
+	capture(t)
+	For("expense")
+	For("tag")
+	assert.True(t, SetLevel("expense", slog.LevelDebug))
+
+	SetLevels(slog.LevelWarn, map[string]slog.Level{"expense": slog.LevelError, "tag": slog.LevelError})
+
+	assert.Equal(t, slog.LevelDebug, Level("expense"))
+	assert.Equal(t, slog.LevelError, Level("tag"))
+	assert.True(t, ResetLevel("expense"))
+	assert.Equal(t, slog.LevelError, Level("expense"))
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
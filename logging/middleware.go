@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var access = For("http")
+
+// Middleware puts the request id on the request context, so that lines
+// logged with it carry the id, and writes an access log line per request.
+// It goes after middleware.RequestID. Errors are handed to the error
+// handler here so that the line has the status actually sent.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Response().Header().Get(echo.HeaderXRequestID)
+			if len(id) == 0 {
+				id = c.Request().Header.Get(echo.HeaderXRequestID)
+			}
+			req := c.Request().WithContext(WithRequestId(c.Request().Context(), id))
+			c.SetRequest(req)
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			access.InfoContext(req.Context(), "Request",
+				"method", req.Method,
+				"route", c.Path(),
+				"path", req.URL.Path,
+				"status", res.Status,
+				"latency", time.Since(start),
+				"bytes_out", res.Size,
+				"remote_ip", c.RealIP(),
+			)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+//go:build unit
+
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LogsRequestWithId(t *testing.T) {
+	buf := capture(t)
+	e := echo.New()
+	e.Use(middleware.RequestID(), Middleware())
+	e.GET("/expenses/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "expense not found")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	req.Header.Set(echo.HeaderXRequestID, "r1")
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	m := line(t, buf)
+	assert.Equal(t, "r1", m["request_id"])
+	assert.Equal(t, "/expenses/:id", m["route"])
+	assert.Equal(t, float64(http.StatusNotFound), m["status"])
+}
@@ -0,0 +1,199 @@
+// Package nats is a small client for the NATS text protocol. It publishes
+// and nothing else: no subscriptions, no JetStream. Flush confirms that
+// the server has read everything published before it.
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPort is used when the URL has none.
+const DefaultPort = "4222"
+
+// Error is an -ERR line from the server.
+type Error string
+
+func (e Error) Error() string { return "nats: " + string(e) }
+
+// Conn is one connection to a server.
+type Conn struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	w          *bufio.Writer
+	maxPayload int
+}
+
+// info is the part of the server's INFO used here.
+type info struct {
+	AuthRequired bool `json:"auth_required"`
+	MaxPayload   int  `json:"max_payload"`
+}
+
+type connect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// parseURL checks rawURL, nats://[user:password@]host[:port] or
+// nats://token@host[:port], and returns the address and credentials.
+func parseURL(rawURL string) (addr string, c connect, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", c, err
+	}
+	if u.Scheme != "nats" || len(u.Host) == 0 {
+		return "", c, fmt.Errorf("nats: URL must be nats://host[:port], got %q", u.Redacted())
+	}
+	addr = u.Host
+	if len(u.Port()) == 0 {
+		addr = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			c.User, c.Pass = u.User.Username(), pass
+		} else {
+			c.Token = u.User.Username()
+		}
+	}
+	return addr, c, nil
+}
+
+// CheckURL reports whether rawURL can be passed to Dial.
+func CheckURL(rawURL string) error {
+	_, _, err := parseURL(rawURL)
+	return err
+}
+
+// Dial connects to the server at rawURL, nats://[user:password@]host[:port]
+// or nats://token@host[:port], and waits until it has accepted the
+// connection. name identifies the client in the server's monitoring.
+func Dial(ctx context.Context, rawURL, name string) (*Conn, error) {
+	addr, opts, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := newConn(nc)
+	if err := c.handshake(ctx, opts, name); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newConn(nc net.Conn) *Conn {
+	return &Conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+}
+
+func (c *Conn) handshake(ctx context.Context, opts connect, name string) error {
+	c.deadline(ctx)
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if op != "INFO" {
+		return fmt.Errorf("nats: expected INFO, got %q", line)
+	}
+	var i info
+	if err := json.Unmarshal([]byte(args), &i); err != nil {
+		return fmt.Errorf("nats: malformed INFO: %w", err)
+	}
+	if i.AuthRequired && len(opts.User) == 0 && len(opts.Token) == 0 {
+		return errors.New("nats: server requires credentials")
+	}
+	c.maxPayload = i.MaxPayload
+
+	opts.Name, opts.Lang, opts.Version, opts.Protocol = name, "go", "1", 1
+	b, _ := json.Marshal(opts)
+	fmt.Fprintf(c.w, "CONNECT %s\r\n", b)
+	return c.Flush(ctx)
+}
+
+func (c *Conn) deadline(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+}
+
+func (c *Conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("nats: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// Publish queues data for subject. Nothing is sent before Flush.
+func (c *Conn) Publish(subject string, data []byte) error {
+	if len(subject) == 0 || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", subject)
+	}
+	if c.maxPayload > 0 && len(data) > c.maxPayload {
+		return fmt.Errorf("nats: %d byte message exceeds the server's limit of %d", len(data), c.maxPayload)
+	}
+	fmt.Fprintf(c.w, "PUB %s %d\r\n", subject, len(data))
+	c.w.Write(data)
+	c.w.WriteString("\r\n")
+	return nil
+}
+
+// Flush sends what was published and waits for the server to answer a
+// PING sent after it, which it does once it has processed the rest.
+func (c *Conn) Flush(ctx context.Context) error {
+	c.deadline(ctx)
+	c.w.WriteString("PING\r\n")
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch op {
+		case "PONG":
+			return nil
+		case "PING":
+			c.w.WriteString("PONG\r\n")
+			if err := c.w.Flush(); err != nil {
+				return err
+			}
+		case "+OK", "INFO":
+		case "-ERR":
+			return Error(strings.Trim(args, "'"))
+		default:
+			return fmt.Errorf("nats: unexpected %q", line)
+		}
+	}
+}
+
+// Close closes the connection; anything not flushed is lost.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
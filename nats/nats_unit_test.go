@@ -0,0 +1,81 @@
+//go:build unit
+
+package nats
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/nats/natstest"
+)
+
+func TestParseURL(t *testing.T) {
+	addr, c, err := parseURL("nats://app:pw@broker")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "broker:4222", addr)
+		assert.Equal(t, "app", c.User)
+		assert.Equal(t, "pw", c.Pass)
+	}
+
+	addr, c, err = parseURL("nats://s3cr3t@broker:4333")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "broker:4333", addr)
+		assert.Equal(t, "s3cr3t", c.Token)
+	}
+
+	assert.Error(t, CheckURL("redis://broker"))
+	assert.Error(t, CheckURL("nats://"))
+}
+
+func TestConn_PublishAndFlush(t *testing.T) {
+	s := natstest.NewServer(t, "s3cr3t")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(ctx, s.URL(), "test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, c.Publish("expenses.created", []byte(`{"id":1}`)))
+	assert.NoError(t, c.Publish("expenses.deleted", []byte("line one\r\nline two")))
+	assert.Empty(t, s.Messages())
+
+	if assert.NoError(t, c.Flush(ctx)) {
+		assert.Equal(t, []natstest.Msg{
+			{Subject: "expenses.created", Data: []byte(`{"id":1}`)},
+			{Subject: "expenses.deleted", Data: []byte("line one\r\nline two")},
+		}, s.Messages())
+	}
+
+	assert.Error(t, c.Publish("has space", nil))
+}
+
+func TestDial_RequiresCredentials(t *testing.T) {
+	s := natstest.NewServer(t, "s3cr3t")
+
+	_, err := Dial(context.Background(), "nats://"+s.Addr(), "test")
+	assert.EqualError(t, err, "nats: server requires credentials")
+
+	_, err = Dial(context.Background(), "nats://wrong@"+s.Addr(), "test")
+	assert.Equal(t, Error("Authorization Violation"), err)
+}
+
+func TestConn_Flush_AnswersServerPing(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 64)
+		server.Read(buf) // PING
+		server.Write([]byte("PING\r\n"))
+		server.Read(buf) // PONG
+		server.Write([]byte("+OK\r\nPONG\r\n"))
+	}()
+
+	assert.NoError(t, newConn(client).Flush(context.Background()))
+}
@@ -0,0 +1,164 @@
+// Package natstest runs a stand-in NATS server for tests. It understands
+// CONNECT, PUB, PING and PONG, and records what is published instead of
+// delivering it.
+package natstest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Msg is a published message.
+type Msg struct {
+	Subject string
+	Data    []byte
+}
+
+type Server struct {
+	ln    net.Listener
+	token string
+
+	mu    sync.Mutex
+	msgs  []Msg
+	conns map[net.Conn]bool
+}
+
+// NewServer starts a server that requires token, if not empty, and stops
+// it when the test ends.
+func NewServer(t testing.TB, token string) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ln: ln, token: token, conns: map[net.Conn]bool{}}
+	t.Cleanup(func() {
+		ln.Close()
+		s.Disconnect()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = true
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Addr is the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// URL is a nats:// URL for the server, with its token.
+func (s *Server) URL() string {
+	if len(s.token) > 0 {
+		return fmt.Sprintf("nats://%s@%s", s.token, s.Addr())
+	}
+	return "nats://" + s.Addr()
+}
+
+// Messages returns what has been published so far.
+func (s *Server) Messages() []Msg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Msg(nil), s.msgs...)
+}
+
+// Disconnect drops every client connection, as a server restart would.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+func (s *Server) serve(nc net.Conn) {
+	defer func() {
+		nc.Close()
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+	}()
+	r := bufio.NewReader(nc)
+	w := bufio.NewWriter(nc)
+
+	info, _ := json.Marshal(map[string]interface{}{
+		"server_id":     "natstest",
+		"version":       "2.10.0",
+		"proto":         1,
+		"max_payload":   1 << 20,
+		"auth_required": len(s.token) > 0,
+	})
+	fmt.Fprintf(w, "INFO %s\r\n", info)
+	w.Flush()
+
+	connected := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch strings.ToUpper(op) {
+		case "CONNECT":
+			var opts struct {
+				Token string `json:"auth_token"`
+			}
+			json.Unmarshal([]byte(args), &opts)
+			if opts.Token != s.token {
+				fmt.Fprint(w, "-ERR 'Authorization Violation'\r\n")
+				w.Flush()
+				return
+			}
+			connected = true
+		case "PING":
+			fmt.Fprint(w, "PONG\r\n")
+		case "PONG":
+		case "PUB":
+			f := strings.Fields(args)
+			n := -1
+			if len(f) == 2 {
+				n, _ = strconv.Atoi(f[1])
+			}
+			if n < 0 {
+				fmt.Fprint(w, "-ERR 'Unknown Protocol Operation'\r\n")
+				w.Flush()
+				return
+			}
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			if !connected {
+				fmt.Fprint(w, "-ERR 'Authorization Violation'\r\n")
+				w.Flush()
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, Msg{Subject: f[0], Data: data[:n]})
+			s.mu.Unlock()
+		default:
+			fmt.Fprint(w, "-ERR 'Unknown Protocol Operation'\r\n")
+			w.Flush()
+			return
+		}
+		if r.Buffered() == 0 {
+			w.Flush()
+		}
+	}
+}
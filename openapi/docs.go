@@ -0,0 +1,49 @@
+package openapi
+
+import (
+	"embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/problem"
+)
+
+// redocVersion is the Redoc release embedded from redoc/.
+const redocVersion = "2.1.5"
+
+//go:generate curl -fsSL -o redoc/redoc.standalone.js https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js
+
+//go:embed redoc
+var redoc embed.FS
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>Expenses API</title>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+<redoc spec-url="/openapi.json"></redoc>
+<script src="/docs/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// DocsHandler renders the document with the embedded Redoc.
+func DocsHandler(c echo.Context) error {
+	if _, err := redoc.Open("redoc/redoc.standalone.js"); err != nil {
+		return problem.NotFound(c, "Redoc "+redocVersion+" is not embedded, run go generate ./openapi")
+	}
+	return c.HTML(http.StatusOK, docsPage)
+}
+
+// RedocHandler serves the embedded Redoc bundle.
+func RedocHandler(c echo.Context) error {
+	b, err := redoc.ReadFile("redoc/redoc.standalone.js")
+	if err != nil {
+		return problem.NotFound(c, "Redoc "+redocVersion+" is not embedded, run go generate ./openapi")
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=86400")
+	return c.Blob(http.StatusOK, "text/javascript; charset=utf-8", b)
+}
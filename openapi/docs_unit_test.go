@@ -0,0 +1,30 @@
+//go:build unit
+
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedocHandler_ServesTheEmbeddedBundle(t *testing.T) {
+	e := echo.New()
+	e.GET("/docs", DocsHandler)
+	e.GET("/docs/redoc.standalone.js", RedocHandler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/redoc.standalone.js", nil))
+
+	if assert.Equal(t, http.StatusOK, rec.Code, "redoc/redoc.standalone.js is missing, run go generate ./openapi and commit it") {
+		assert.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.NotEmpty(t, rec.Body.Bytes())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
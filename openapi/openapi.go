@@ -0,0 +1,126 @@
+// Package openapi serves the OpenAPI 3.1 description of the API and can
+// validate traffic against it.
+//
+// openapi.json is maintained by hand next to the routes in server.go; a unit
+// test in package main fails when the two disagree.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("openapi")
+
+//go:embed openapi.json
+var spec []byte
+
+// doc is spec parsed into the subset of OpenAPI used for validation.
+var doc = mustParse(spec)
+
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*response `json:"responses"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Parameters  []parameter          `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func mustParse(b []byte) *document {
+	d := &document{}
+	if err := json.Unmarshal(b, d); err != nil {
+		panic("openapi: invalid openapi.json: " + err.Error())
+	}
+	return d
+}
+
+// Route is an operation in the document, with the path written the way echo
+// registers it (/expenses/:id).
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Routes lists every operation in the document, sorted by path and method.
+func Routes() []Route {
+	var routes []Route
+	for path, item := range doc.Paths {
+		for method := range item {
+			routes = append(routes, Route{Method: strings.ToUpper(method), Path: echoPath(path)})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// lookup finds the operation for an echo route path such as /expenses/:id.
+func lookup(method, path string) *operation {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+
+	item := doc.Paths[strings.Join(parts, "/")]
+	if item == nil {
+		return nil
+	}
+	return item[strings.ToLower(method)]
+}
+
+func echoPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			parts[i] = ":" + p[1:len(p)-1]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func SpecHandler(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, spec)
+}
@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/umateedev/assessment/problem"
+)
+
+// Schema is the subset of JSON Schema 2020-12 used by openapi.json.
+type Schema struct {
+	Ref              string             `json:"$ref"`
+	Type             types              `json:"type"`
+	Properties       map[string]*Schema `json:"properties"`
+	Required         []string           `json:"required"`
+	Items            *Schema            `json:"items"`
+	AllOf            []*Schema          `json:"allOf"`
+	Enum             []interface{}      `json:"enum"`
+	Format           string             `json:"format"`
+	Minimum          *float64           `json:"minimum"`
+	Maximum          *float64           `json:"maximum"`
+	ExclusiveMinimum *float64           `json:"exclusiveMinimum"`
+	MinLength        *int               `json:"minLength"`
+	MaxLength        *int               `json:"maxLength"`
+	MaxItems         *int               `json:"maxItems"`
+}
+
+// types accepts both "type": "string" and "type": ["string", "null"].
+type types []string
+
+func (t *types) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+func resolve(s *Schema) *Schema {
+	for s != nil && len(s.Ref) > 0 {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validateValue checks v, as decoded by encoding/json, against s. Field
+// names in the returned errors are JSON pointers relative to the value.
+func validateValue(s *Schema, v interface{}, path string) []problem.FieldError {
+	s = resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	fail := func(format string, args ...interface{}) []problem.FieldError {
+		field := strings.TrimPrefix(path, "/")
+		if len(field) == 0 {
+			field = "body"
+		}
+		return []problem.FieldError{{Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	var errs []problem.FieldError
+	for _, sub := range s.AllOf {
+		errs = append(errs, validateValue(sub, v, path)...)
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		return append(errs, fail("must be %s", strings.Join(s.Type, " or "))...)
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if e == v {
+				found = true
+			}
+		}
+		if !found {
+			errs = append(errs, fail("must be one of %v", s.Enum)...)
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, fail("must have at least %d characters", *s.MinLength)...)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, fail("must have at most %d characters", *s.MaxLength)...)
+		}
+		if !validFormat(s.Format, v) {
+			errs = append(errs, fail("must be a %s", s.Format)...)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs = append(errs, fail("must be at least %g", *s.Minimum)...)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs = append(errs, fail("must be at most %g", *s.Maximum)...)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			errs = append(errs, fail("must be greater than %g", *s.ExclusiveMinimum)...)
+		}
+	case []interface{}:
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, fail("must have at most %d items", *s.MaxItems)...)
+		}
+		for i, item := range v {
+			errs = append(errs, validateValue(s.Items, item, fmt.Sprintf("%s/%d", path, i))...)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, problem.FieldError{Field: strings.TrimPrefix(path+"/"+name, "/"), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value, ok := v[name]; ok {
+				errs = append(errs, validateValue(s.Properties[name], value, path+"/"+name)...)
+			}
+		}
+	}
+	return errs
+}
+
+func (t types) match(v interface{}) bool {
+	for _, name := range t {
+		switch v := v.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || name == "integer" && v == math.Trunc(v) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validFormat(format, v string) bool {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse("2006-01-02", v)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, v)
+	}
+	return err == nil
+}
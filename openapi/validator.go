@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/problem"
+)
+
+// Validation modes accepted by Validator.
+const (
+	Off      = "off"
+	Requests = "requests"
+	All      = "all"
+)
+
+// Validator returns middleware that checks requests against the document and
+// rejects invalid ones with 422. In All mode responses are checked too and
+// any drift from the document is logged; the response itself is left alone,
+// so All is meant for development and tests. Routes that are not in the
+// document pass through unchecked.
+func Validator(mode string) (echo.MiddlewareFunc, error) {
+	switch mode {
+	case "", Off:
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }, nil
+	case Requests, All:
+	default:
+		return nil, fmt.Errorf("unknown OpenAPI validation mode %q", mode)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op := lookup(c.Request().Method, c.Path())
+			if op == nil {
+				return next(c)
+			}
+
+			errs, err := checkRequest(c, op)
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				return err
+			}
+			if err != nil {
+				return problem.BadRequest(c, "Invalid request, "+err.Error())
+			}
+			if len(errs) > 0 {
+				return problem.Invalid(c, errs...)
+			}
+
+			if mode != All {
+				return next(c)
+			}
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			err = next(c)
+			if drift := checkResponse(op, c.Response().Status, c.Response().Header().Get(echo.HeaderContentType), rec.body.Bytes()); len(drift) > 0 {
+				log.WarnContext(c.Request().Context(), "OpenAPI response drift", "method", c.Request().Method, "route", c.Path(), "drift", drift)
+			}
+			return err
+		}
+	}, nil
+}
+
+func checkRequest(c echo.Context, op *operation) ([]problem.FieldError, error) {
+	var errs []problem.FieldError
+	for _, p := range op.Parameters {
+		var value string
+		switch p.In {
+		case "path":
+			value = c.Param(p.Name)
+		case "query":
+			value = c.QueryParam(p.Name)
+		default:
+			continue
+		}
+
+		if len(value) == 0 {
+			if p.Required {
+				errs = append(errs, problem.FieldError{Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		errs = append(errs, validateValue(p.Schema, coerce(resolve(p.Schema), value), "/"+p.Name)...)
+	}
+
+	if op.RequestBody == nil {
+		return errs, nil
+	}
+	media, ok := op.RequestBody.Content[echo.MIMEApplicationJSON]
+	if !ok {
+		return errs, nil
+	}
+
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			errs = append(errs, problem.FieldError{Field: "body", Message: "is required"})
+		}
+		return errs, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	return append(errs, validateValue(media.Schema, v, "")...), nil
+}
+
+// coerce converts a path or query parameter to the type its schema declares
+// so that it can be validated like a JSON value.
+func coerce(s *Schema, value string) interface{} {
+	if s == nil || len(s.Type) == 0 {
+		return value
+	}
+	switch s.Type[0] {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func checkResponse(op *operation, status int, contentType string, body []byte) []problem.FieldError {
+	r, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		r, ok = op.Responses["default"]
+	}
+	if !ok {
+		return []problem.FieldError{{Field: "status", Message: fmt.Sprintf("%d is not documented", status)}}
+	}
+	for r != nil && len(r.Ref) > 0 {
+		r = doc.Components.Responses[r.Ref[len("#/components/responses/"):]]
+	}
+	if r == nil || len(r.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := r.Content[mediaType]
+	if !ok {
+		if _, any := r.Content["*/*"]; any {
+			return nil
+		}
+		return []problem.FieldError{{Field: "content-type", Message: fmt.Sprintf("%q is not documented for %d", mediaType, status)}}
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []problem.FieldError{{Field: "body", Message: err.Error()}}
+	}
+	return validateValue(media.Schema, v, "")
+}
+
+// isJSON reports whether bodies of mediaType are checked against the schema.
+func isJSON(mediaType string) bool {
+	return mediaType == echo.MIMEApplicationJSON || mediaType == problem.MIMEApplicationProblemJSON
+}
+
+// recorder keeps a copy of the response body for checkResponse. Only JSON
+// is kept, so that event streams and downloads do not pile up in memory.
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header().Get(echo.HeaderContentType)); isJSON(mediaType) {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,111 @@
+//go:build unit
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/problem"
+)
+
+func newServer(t *testing.T, mode string, handler echo.HandlerFunc) *echo.Echo {
+	e := echo.New()
+	validator, err := Validator(mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Use(validator)
+	e.POST("/expenses", handler)
+	e.GET("/expenses/search", handler)
+	return e
+}
+
+func TestValidator_RejectsInvalidBody(t *testing.T) {
+	called := false
+	e := newServer(t, Requests, func(c echo.Context) error {
+		called = true
+		return c.NoContent(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(`{"title": "", "amount": "79", "tags": ["food", 1]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	p := problem.Problem{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, []problem.FieldError{
+		{Field: "amount", Message: "must be number"},
+		{Field: "tags/1", Message: "must be string"},
+		{Field: "title", Message: "must have at least 1 characters"},
+	}, p.Errors)
+}
+
+func TestValidator_PassesValidBody(t *testing.T) {
+	e := newServer(t, Requests, func(c echo.Context) error {
+		body := map[string]interface{}{}
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(`{"title": "tea", "amount": 20}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"title": "tea", "amount": 20}`, rec.Body.String())
+}
+
+func TestValidator_ChecksQueryParameters(t *testing.T) {
+	e := newServer(t, Requests, func(c echo.Context) error {
+		return c.JSON(http.StatusOK, []interface{}{})
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/expenses/search?limit=500", nil))
+
+	p := problem.Problem{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, []problem.FieldError{
+		{Field: "q", Message: "is required"},
+		{Field: "limit", Message: "must be at most 100"},
+	}, p.Errors)
+}
+
+func TestCheckResponse(t *testing.T) {
+	op := lookup(http.MethodPost, "expenses")
+
+	ok := checkResponse(op, http.StatusCreated, echo.MIMEApplicationJSONCharsetUTF8,
+		[]byte(`{"id": 1, "title": "tea", "amount": 20, "note": "", "tags": ["food"]}`))
+	assert.Empty(t, ok)
+
+	drift := checkResponse(op, http.StatusCreated, echo.MIMEApplicationJSON, []byte(`{"id": "1", "title": "tea", "amount": 20, "note": ""}`))
+	assert.Equal(t, []problem.FieldError{
+		{Field: "tags", Message: "is required"},
+		{Field: "id", Message: "must be integer"},
+	}, drift)
+
+	undocumented := checkResponse(op, http.StatusTeapot, echo.MIMEApplicationJSON, nil)
+	assert.Equal(t, []problem.FieldError{{Field: "status", Message: "418 is not documented"}}, undocumented)
+
+	problemBody := checkResponse(op, http.StatusNotFound, problem.MIMEApplicationProblemJSON, nil)
+	assert.Equal(t, []problem.FieldError{{Field: "status", Message: "404 is not documented"}}, problemBody)
+}
+
+func TestValidator_UnknownMode(t *testing.T) {
+	_, err := Validator("strict")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/umateedev/assessment/database"
+)
+
+// Feed follows published events for consumers in this process, such as
+// the live expense stream. It reads them back from the outbox table rather
+// than from a publisher, so that every replica sees every event whichever
+// relay published it, and keeps the latest in memory so that consumers can
+// resume after reconnecting.
+type Feed struct {
+	buf      *Memory
+	size     int
+	interval time.Duration
+
+	mu   sync.Mutex
+	last int64
+	wake chan struct{}
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewFeed returns a feed that checks for events every interval and keeps
+// the last size.
+func NewFeed(size int, interval time.Duration) *Feed {
+	return &Feed{
+		buf:      NewMemory(size),
+		size:     size,
+		interval: interval,
+		wake:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *Feed) Start() {
+	go func() {
+		defer close(f.done)
+
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := database.Context(context.Background())
+			if err := f.Poll(ctx); err != nil {
+				log.Error("Outbox feed failed", "error", err)
+			}
+			cancel()
+
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops following and ends Done. It returns once the current poll has
+// finished, and may be called more than once.
+func (f *Feed) Stop() {
+	f.stopOnce.Do(func() { close(f.stop) })
+	<-f.done
+}
+
+// Done is closed when the feed stops; consumers should end then.
+func (f *Feed) Done() <-chan struct{} {
+	return f.stop
+}
+
+// Poll reads the events published since the last poll. The first poll
+// reads the latest size events, so that consumers can resume across a
+// restart.
+func (f *Feed) Poll(ctx context.Context) error {
+	f.mu.Lock()
+	last := f.last
+	f.mu.Unlock()
+
+	query := `SELECT seq, type, expense_id, COALESCE(owner, ''), data, created_at FROM outbox
+	WHERE published_at IS NOT NULL AND seq > $1 ORDER BY seq LIMIT $2`
+	if last == 0 {
+		query = `SELECT * FROM (SELECT seq, type, expense_id, COALESCE(owner, ''), data, created_at FROM outbox
+		WHERE published_at IS NOT NULL AND seq > $1 ORDER BY seq DESC LIMIT $2) latest ORDER BY seq`
+	}
+	rows, err := database.Db.QueryContext(ctx, query, last, f.size)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e := Event{}
+		var data string
+		if err := rows.Scan(&e.Seq, &e.Type, &e.ExpenseId, &e.Owner, &data, &e.CreatedAt); err != nil {
+			return err
+		}
+		e.Data = []byte(data)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil || len(events) == 0 {
+		return err
+	}
+
+	f.mu.Lock()
+	f.buf.Publish(ctx, events)
+	f.last = events[len(events)-1].Seq
+	close(f.wake)
+	f.wake = make(chan struct{})
+	f.mu.Unlock()
+	return nil
+}
+
+// Since returns the events kept after seq, oldest first, and a channel
+// that is closed when more arrive. ok is false if some events after seq
+// are no longer kept, or if seq is ahead of the feed, as it is after the
+// outbox was emptied.
+func (f *Feed) Since(seq int64) (events []Event, ok bool, wake <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events, ok = f.buf.Since(seq)
+	return events, ok && seq <= f.last, f.wake
+}
+
+// Last returns the sequence number of the latest event seen.
+func (f *Feed) Last() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last
+}
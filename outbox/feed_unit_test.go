@@ -0,0 +1,70 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func eventRows(seqs ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows(eventColumns)
+	for _, s := range seqs {
+		rows.AddRow(s, ExpenseUpdated, 7, "", `{"id":7}`, time.Now())
+	}
+	return rows
+}
+
+func TestFeed_PollLoadsLatestThenFollows(t *testing.T) {
+	mock := mockDb(t)
+	ctx := context.Background()
+	f := NewFeed(3, time.Second)
+
+	mock.ExpectQuery(`SELECT \* FROM \(SELECT (.+) ORDER BY seq DESC LIMIT \$2\) latest ORDER BY seq`).
+		WithArgs(0, 3).WillReturnRows(eventRows(8, 9, 10))
+	assert.NoError(t, f.Poll(ctx))
+	assert.Equal(t, int64(10), f.Last())
+
+	events, ok, wake := f.Since(10)
+	assert.True(t, ok)
+	assert.Empty(t, events)
+
+	mock.ExpectQuery("SELECT (.+) FROM outbox WHERE published_at IS NOT NULL AND seq > \\$1 ORDER BY seq LIMIT \\$2").
+		WithArgs(10, 3).WillReturnRows(eventRows(11))
+	assert.NoError(t, f.Poll(ctx))
+
+	select {
+	case <-wake:
+	default:
+		t.Fatal("wake was not closed by new events")
+	}
+	events, ok, _ = f.Since(10)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{11}, seqs(events))
+
+	_, ok, _ = f.Since(7)
+	assert.False(t, ok, "event 8 was dropped")
+	_, ok, _ = f.Since(12)
+	assert.False(t, ok, "ahead of the feed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFeed_StopEndsDone(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectQuery("SELECT").WillReturnRows(eventRows())
+	f := NewFeed(3, time.Hour)
+	f.Start()
+
+	f.Stop()
+	f.Stop()
+
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("Done is still open")
+	}
+}
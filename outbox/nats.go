@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/umateedev/assessment/nats"
+)
+
+// NATS publishes each event as JSON to the subject "<prefix>.<type>", e.g.
+// events.expense.created. It connects on first use and again after an
+// error.
+type NATS struct {
+	url    string
+	prefix string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+// NewNATS returns a publisher for the server at url; see nats.Dial.
+func NewNATS(url, prefix string) (*NATS, error) {
+	if err := nats.CheckURL(url); err != nil {
+		return nil, err
+	}
+	return &NATS{url: url, prefix: prefix}, nil
+}
+
+func (p *NATS) Publish(ctx context.Context, events []Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		c, err := nats.Dial(ctx, p.url, "expenses-outbox")
+		if err != nil {
+			return err
+		}
+		p.conn = c
+	}
+	err := p.publish(ctx, events)
+	if err != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return err
+}
+
+func (p *NATS) publish(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := p.conn.Publish(p.prefix+"."+e.Type, b); err != nil {
+			return err
+		}
+	}
+	return p.conn.Flush(ctx)
+}
+
+func (p *NATS) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
@@ -0,0 +1,98 @@
+// Package outbox publishes expense changes to other services without dual
+// writes.
+//
+// Anything that changes an expense calls Add, or AddFrom for statements that
+// change many, in the same transaction, so the event is recorded if and
+// only if the change is. A Relay later gives
+// each recorded event the next sequence number and hands it to a
+// Publisher. Sequence numbers increase by one per event in the order they
+// are relayed and are never reused, so a consumer that remembers the last
+// one it processed can resume after a restart and skip anything it has
+// seen: delivery is at least once, and an event is published again,
+// with the same sequence number, if the relay fails before recording
+// that it was published.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("outbox")
+
+// Event types.
+const (
+	ExpenseCreated = "expense.created"
+	ExpenseUpdated = "expense.updated"
+	ExpenseDeleted = "expense.deleted"
+)
+
+// Event is a change as published.
+type Event struct {
+	Seq       int64           `json:"seq"`
+	Type      string          `json:"type"`
+	ExpenseId int             `json:"expense_id"`
+	Owner     string          `json:"owner,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Add records an event of type typ about an expense as part of tx. data is
+// stored as JSON. The event belongs to the owner of the expense, which is
+// read in tx, so Add must run while the expense exists.
+func Add(ctx context.Context, tx *sql.Tx, typ string, expenseId int, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO outbox (type, expense_id, owner, data) VALUES ($1, $2, (SELECT owner FROM expenses WHERE id = $2), $3)", typ, expenseId, string(b))
+	return err
+}
+
+// Returning ends the statements passed to AddFrom.
+const Returning = " RETURNING id, owner, title, amount, note, tags, category_id, rule_ids"
+
+// expenseData builds the data of an event from a row of Returning, as
+// package expense marshals an Expense.
+const expenseData = `(jsonb_build_object('id', id, 'title', title, 'amount', amount, 'note', COALESCE(note, ''), 'tags', tags) ||
+	jsonb_strip_nulls(jsonb_build_object('category_id', category_id, 'rule_ids', NULLIF(rule_ids, '{}'))))::text`
+
+// Changed is an expense changed by an AddFrom statement, with the data of
+// its event.
+type Changed struct {
+	ExpenseId  int
+	Owner      string
+	Amount     float64
+	CategoryId *int
+	Data       json.RawMessage
+}
+
+// AddFrom runs stmt, an INSERT or UPDATE of expenses that ends in
+// Returning, as part of tx and records an event of type typ for every
+// expense it returns. It returns those expenses.
+func AddFrom(ctx context.Context, tx *sql.Tx, typ, stmt string, args ...interface{}) ([]Changed, error) {
+	args = append(args, typ)
+	rows, err := tx.QueryContext(ctx, "WITH changed AS ("+stmt+"), recorded AS (INSERT INTO outbox (type, expense_id, owner, data) SELECT $"+strconv.Itoa(len(args))+", id, owner, "+expenseData+" FROM changed)"+
+		" SELECT id, COALESCE(owner, ''), amount, category_id, "+expenseData+" FROM changed ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changed []Changed
+	for rows.Next() {
+		var ch Changed
+		var data string
+		if err := rows.Scan(&ch.ExpenseId, &ch.Owner, &ch.Amount, &ch.CategoryId, &data); err != nil {
+			return nil, err
+		}
+		ch.Data = json.RawMessage(data)
+		changed = append(changed, ch)
+	}
+	return changed, rows.Err()
+}
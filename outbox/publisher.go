@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Publisher hands events to consumers. Publish gets events in sequence
+// order and must not return before they are safely handed over; on error
+// the whole batch is published again later.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// Memory keeps the latest events in memory, for tests and for consumers in
+// the same process.
+type Memory struct {
+	mu    sync.Mutex
+	buf   []Event
+	start int
+	n     int
+}
+
+// NewMemory returns a publisher that keeps the last size events.
+func NewMemory(size int) *Memory {
+	return &Memory{buf: make([]Event, size)}
+}
+
+func (m *Memory) Publish(ctx context.Context, events []Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range events {
+		if m.n > 0 && e.Seq <= m.at(m.n-1).Seq {
+			continue // published again after a failure
+		}
+		if m.n < len(m.buf) {
+			m.n++
+		} else {
+			m.start = (m.start + 1) % len(m.buf)
+		}
+		m.buf[(m.start+m.n-1)%len(m.buf)] = e
+	}
+	return nil
+}
+
+func (m *Memory) at(i int) Event {
+	return m.buf[(m.start+i)%len(m.buf)]
+}
+
+// Since returns the events kept after seq, oldest first. ok is false if
+// some events after seq are no longer kept.
+func (m *Memory) Since(seq int64) (events []Event, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := 0; i < m.n; i++ {
+		if e := m.at(i); e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events, m.n == 0 || m.at(0).Seq <= seq+1
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// File appends events to a file as JSON lines and syncs it after each
+// batch.
+type File struct {
+	f *os.File
+}
+
+// NewFile opens path for appending, creating it if needed.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f}, nil
+}
+
+func (p *File) Publish(ctx context.Context, events []Event) error {
+	w := bufio.NewWriter(p.f)
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+func (p *File) Close() error {
+	return p.f.Close()
+}
@@ -0,0 +1,109 @@
+//go:build unit
+
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/nats/natstest"
+)
+
+func events(seqs ...int64) []Event {
+	var es []Event
+	for _, s := range seqs {
+		es = append(es, Event{Seq: s, Type: ExpenseUpdated, ExpenseId: int(s), Data: []byte(`{}`)})
+	}
+	return es
+}
+
+func seqs(es []Event) []int64 {
+	var s []int64
+	for _, e := range es {
+		s = append(s, e.Seq)
+	}
+	return s
+}
+
+func TestMemory_KeepsLatestEvents(t *testing.T) {
+	m := NewMemory(3)
+	ctx := context.Background()
+
+	assert.NoError(t, m.Publish(ctx, events(1, 2)))
+	assert.NoError(t, m.Publish(ctx, events(2, 3, 4, 5)))
+
+	got, ok := m.Since(2)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{3, 4, 5}, seqs(got))
+
+	got, ok = m.Since(1)
+	assert.False(t, ok, "event 2 was dropped")
+	assert.Equal(t, []int64{3, 4, 5}, seqs(got))
+
+	got, ok = m.Since(5)
+	assert.True(t, ok)
+	assert.Empty(t, got)
+}
+
+func TestFile_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	ctx := context.Background()
+
+	f, err := NewFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, f.Publish(ctx, events(1, 2)))
+	assert.NoError(t, f.Close())
+	f, _ = NewFile(path)
+	assert.NoError(t, f.Publish(ctx, events(3)))
+	assert.NoError(t, f.Close())
+
+	r, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	var got []int64
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		e := Event{}
+		assert.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		got = append(got, e.Seq)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, got)
+}
+
+func TestNATS_PublishesBySubjectAndReconnects(t *testing.T) {
+	s := natstest.NewServer(t, "s3cr3t")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := NewNATS(s.URL(), "events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Close()
+
+	assert.NoError(t, p.Publish(ctx, events(1)))
+	s.Disconnect()
+	assert.Error(t, p.Publish(ctx, events(2)))
+	assert.NoError(t, p.Publish(ctx, events(2)))
+
+	msgs := s.Messages()
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, "events.expense.updated", msgs[0].Subject)
+		e := Event{}
+		assert.NoError(t, json.Unmarshal(msgs[1].Data, &e))
+		assert.Equal(t, int64(2), e.Seq)
+	}
+
+	_, err = NewNATS("localhost:4222", "events")
+	assert.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/umateedev/assessment/database"
+)
+
+const (
+	// batchSize limits how many events a single run publishes.
+	batchSize = 100
+	// lockKey is the advisory lock that lets one relay at a time number
+	// and publish events, keeping them in order across replicas.
+	lockKey = 0x6f7574626f78
+)
+
+// Relay periodically publishes new events and prunes old ones.
+type Relay struct {
+	pub       Publisher
+	interval  time.Duration
+	retention time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewRelay returns a relay that publishes through pub every interval and
+// deletes events published more than retention ago.
+func NewRelay(pub Publisher, interval, retention time.Duration) *Relay {
+	return &Relay{
+		pub:       pub,
+		interval:  interval,
+		retention: retention,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (r *Relay) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.run()
+
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// run publishes until there is nothing left, so that a backlog drains
+// without waiting an interval per batch, then prunes.
+func (r *Relay) run() {
+	for {
+		ctx, cancel := database.Context(context.Background())
+		n, err := Forward(ctx, r.pub)
+		cancel()
+		if err != nil {
+			log.Error("Outbox relay failed", "error", err)
+			return
+		}
+		if n > 0 {
+			log.Debug("Outbox relay published events", "count", n)
+		}
+		if n < batchSize {
+			break
+		}
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+	}
+
+	ctx, cancel := database.Context(context.Background())
+	defer cancel()
+	if n, err := Prune(ctx, time.Now().Add(-r.retention)); err != nil {
+		log.Error("Outbox prune failed", "error", err)
+	} else if n > 0 {
+		log.Debug("Outbox pruned events", "count", n)
+	}
+}
+
+// Stop waits for the current run to finish.
+func (r *Relay) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// Forward numbers the events added since the last run and publishes, in
+// order, up to batchSize of those not yet published. It returns how many
+// were published. If another replica is forwarding it does nothing.
+//
+// Numbers are assigned here rather than taken from the id, which comes
+// from a sequence when the row is inserted: transactions commit in a
+// different order than they start, and a consumer resuming after the
+// highest id it saw would miss a lower one committed later.
+func Forward(ctx context.Context, pub Publisher) (int, error) {
+	published := 0
+	var pubErr error
+	err := database.InTx(ctx, func(tx *sql.Tx) error {
+		published, pubErr = 0, nil
+
+		var locked bool
+		if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", lockKey).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE outbox o SET seq = last.seq + next.n
+		FROM (SELECT id, row_number() OVER (ORDER BY id) AS n FROM outbox WHERE seq IS NULL ORDER BY id LIMIT $1) next,
+			(SELECT COALESCE(max(seq), 0) AS seq FROM outbox) last
+		WHERE o.id = next.id`, batchSize)
+		if err != nil {
+			return err
+		}
+
+		events, err := pending(ctx, tx)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		// Keep the numbers assigned above even if publishing fails, so
+		// that the events go out with the same ones next time.
+		if pubErr = pub.Publish(ctx, events); pubErr != nil {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE outbox SET published_at = now() WHERE published_at IS NULL AND seq <= $1", events[len(events)-1].Seq)
+		published = len(events)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, pubErr
+}
+
+func pending(ctx context.Context, tx *sql.Tx) ([]Event, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT seq, type, expense_id, COALESCE(owner, ''), data, created_at FROM outbox WHERE published_at IS NULL AND seq IS NOT NULL ORDER BY seq LIMIT $1", batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e := Event{}
+		var data string
+		if err := rows.Scan(&e.Seq, &e.Type, &e.ExpenseId, &e.Owner, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = []byte(data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Prune deletes events published before t and returns how many it
+// deleted. The latest event is always kept so that numbering carries on
+// from it.
+func Prune(ctx context.Context, t time.Time) (int64, error) {
+	res, err := database.Db.ExecContext(ctx, "DELETE FROM outbox WHERE published_at < $1 AND seq < (SELECT max(seq) FROM outbox)", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
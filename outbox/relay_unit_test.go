@@ -0,0 +1,103 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+var eventColumns = []string{"seq", "type", "expense_id", "owner", "data", "created_at"}
+
+func mockDb(t *testing.T) sqlmock.Sqlmock {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.Db = db
+	return mock
+}
+
+type failing struct{ err error }
+
+func (f failing) Publish(context.Context, []Event) error { return f.err }
+func (f failing) Close() error                           { return nil }
+
+func TestForward_NumbersPublishesAndMarks(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	mock := mockDb(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WithArgs(lockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectExec("UPDATE outbox o SET seq").WithArgs(batchSize).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT (.+) FROM outbox WHERE published_at IS NULL AND seq IS NOT NULL ORDER BY seq").
+		WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(41, ExpenseCreated, 7, "", `{"id":7}`, at).
+			AddRow(42, ExpenseDeleted, 7, "", `{"id":7}`, at))
+	mock.ExpectExec("UPDATE outbox SET published_at").WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	pub := NewMemory(10)
+
+	n, err := Forward(context.Background(), pub)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	events, ok := pub.Since(40)
+	assert.True(t, ok)
+	assert.Equal(t, []Event{
+		{Seq: 41, Type: ExpenseCreated, ExpenseId: 7, Data: []byte(`{"id":7}`), CreatedAt: at},
+		{Seq: 42, Type: ExpenseDeleted, ExpenseId: 7, Data: []byte(`{"id":7}`), CreatedAt: at},
+	}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForward_SkipsWhileAnotherRelayRuns(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	mock.ExpectCommit()
+
+	n, err := Forward(context.Background(), failing{errors.New("must not publish")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForward_KeepsNumbersWhenPublishFails(t *testing.T) {
+	mock := mockDb(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectExec("UPDATE outbox o SET seq").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT (.+) FROM outbox").
+		WillReturnRows(sqlmock.NewRows(eventColumns).AddRow(1, ExpenseCreated, 7, "", `{}`, time.Now()))
+	mock.ExpectCommit()
+	down := errors.New("broker down")
+
+	n, err := Forward(context.Background(), failing{down})
+
+	assert.Equal(t, down, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrune_KeepsLatestEvent(t *testing.T) {
+	before := time.Now().Add(-time.Hour)
+	mock := mockDb(t)
+	mock.ExpectExec(`DELETE FROM outbox WHERE published_at < \$1 AND seq < \(SELECT max\(seq\) FROM outbox\)`).
+		WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := Prune(context.Background(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
@@ -0,0 +1,156 @@
+// Package problem writes RFC 7807 application/problem+json error responses.
+//
+// Every response carries a stable machine-readable Code that clients can
+// switch on; Title and Detail are for humans and may change. Internal errors
+// are logged in full with the request and trace ids and returned without
+// detail.
+package problem
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/telemetry"
+)
+
+var log = logging.For("problem")
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Stable error codes.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeInvalidFilter        = "invalid_filter"
+	CodeInvalidRule          = "invalid_rule"
+	CodeNotFound             = "not_found"
+	CodeCategoryNotFound     = "category_not_found"
+	CodeConflict             = "conflict"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+)
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	RequestId string       `json:"request_id,omitempty"`
+	TraceId   string       `json:"trace_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func New(c echo.Context, status int, code, detail string) Problem {
+	return Problem{
+		Type:      "/problems/" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestId: RequestId(c),
+		TraceId:   TraceId(c),
+	}
+}
+
+// Write sends p with the problem+json content type.
+func Write(c echo.Context, p Problem) error {
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(p.Status, p)
+}
+
+func BadRequest(c echo.Context, detail string) error {
+	return Write(c, New(c, http.StatusBadRequest, CodeInvalidRequest, detail))
+}
+
+func NotFound(c echo.Context, detail string) error {
+	return Write(c, New(c, http.StatusNotFound, CodeNotFound, detail))
+}
+
+// Invalid rejects the request with one entry per offending field.
+func Invalid(c echo.Context, errs ...FieldError) error {
+	p := New(c, http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid")
+	p.Errors = errs
+	return Write(c, p)
+}
+
+// Internal logs err and responds with a 500 that does not reveal it.
+func Internal(c echo.Context, err error) error {
+	log.ErrorContext(c.Request().Context(), "Internal error", "error", err)
+	return Write(c, New(c, http.StatusInternalServerError, CodeInternal, ""))
+}
+
+// RequestId returns the id assigned by middleware.RequestID, if any.
+func RequestId(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); len(id) > 0 {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// TraceId returns the id of the trace the request belongs to, if any.
+func TraceId(c echo.Context) string {
+	return telemetry.TraceID(c.Request().Context())
+}
+
+// ErrorHandler renders errors that escape handlers, such as unknown routes
+// and failed basic auth, as problems.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	he, ok := err.(*echo.HTTPError)
+	if !ok {
+		Internal(c, err)
+		return
+	}
+	if he.Internal != nil {
+		log.InfoContext(c.Request().Context(), "Request error", "status", he.Code, "error", he.Internal)
+	}
+
+	code := CodeInvalidRequest
+	switch he.Code {
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusForbidden:
+		code = CodeForbidden
+	case http.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	case http.StatusRequestEntityTooLarge:
+		code = CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		code = CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		code = CodeRateLimited
+	default:
+		if he.Code >= http.StatusInternalServerError {
+			code = CodeInternal
+		}
+	}
+
+	p := New(c, he.Code, code, "")
+	if msg, ok := he.Message.(string); ok && code != CodeInternal {
+		p.Detail = msg
+	}
+
+	if c.Request().Method == http.MethodHead {
+		c.NoContent(he.Code)
+		return
+	}
+	Write(c, p)
+}
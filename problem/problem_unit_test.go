@@ -0,0 +1,74 @@
+//go:build unit
+
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternal_HidesErrorDetail(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "abc")
+
+	err := Internal(c, errors.New(`pq: relation "expenses" does not exist`))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.NotContains(t, rec.Body.String(), "relation")
+
+		p := Problem{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		assert.Equal(t, Problem{Type: "/problems/internal_error", Title: "Internal Server Error", Status: 500, Code: CodeInternal, RequestId: "abc"}, p)
+	}
+}
+
+func TestInvalid_ReturnFieldErrors(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := Invalid(c, FieldError{Field: "name", Message: "is required"})
+
+	expected := `{"type":"/problems/validation_failed","title":"Unprocessable Entity","status":422,"detail":"One or more fields are invalid","code":"validation_failed","errors":[{"field":"name","message":"is required"}]}`
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, expected, rec.Body.String())
+	}
+}
+
+func TestErrorHandler_RendersHTTPErrors(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.GET("/boom", func(c echo.Context) error { return errors.New("secret") })
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, CodeNotFound},
+		{http.MethodPost, "/boom", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.MethodGet, "/boom", http.StatusInternalServerError, CodeInternal},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		p := Problem{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		assert.Equal(t, tc.status, rec.Code, tc.path)
+		assert.Equal(t, tc.code, p.Code, tc.path)
+		assert.NotContains(t, rec.Body.String(), "secret")
+	}
+}
@@ -0,0 +1,47 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	l := Limit{Rate: 2, Burst: 3}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take(context.Background(), "k", l, now)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, _ := s.Take(context.Background(), "k", l, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	res, _ = s.Take(context.Background(), "k", l, now.Add(500*time.Millisecond))
+	assert.True(t, res.Allowed)
+
+	res, _ = s.Take(context.Background(), "other", l, now)
+	assert.True(t, res.Allowed, "buckets are per key")
+}
+
+func TestMemoryStore_DropsRefilledBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	l := Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+
+	s.Take(context.Background(), "old", l, now)
+	s.Take(context.Background(), "new", l, now.Add(2*sweepInterval))
+
+	assert.Len(t, s.buckets, 1)
+	assert.Contains(t, s.buckets, "new")
+}
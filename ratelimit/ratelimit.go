@@ -0,0 +1,154 @@
+// Package ratelimit limits requests with token buckets, one per route group
+// and client. A client is the API key or user the request authenticated
+// as or, before authentication or without it, the remote IP. Buckets live
+// in a Store, either in memory for a single instance or in a
+// Redis-protocol server shared by several.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("ratelimit")
+
+// Limit is a token bucket that refills at Rate tokens per second up to
+// Burst. A zero Rate means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// AuthGroup is the group limited per IP in front of authentication, so
+// that guessing passwords is throttled before each guess costs a bcrypt
+// comparison.
+const AuthGroup = "auth"
+
+// Limits holds the limit of each route group. Groups without an entry use
+// Default.
+type Limits struct {
+	Default Limit
+	Groups  map[string]Limit
+}
+
+func (l Limits) For(group string) Limit {
+	if g, ok := l.Groups[group]; ok {
+		return g
+	}
+	return l.Default
+}
+
+// Result is the state of a bucket after taking a token from it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until a request would be allowed, when it
+	// was not.
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets by key.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+// take refills a bucket that held tokens at last and takes one token from
+// it if it can. It returns the tokens left.
+func take(l Limit, tokens float64, last, now time.Time) (float64, Result) {
+	burst := float64(l.Burst)
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed*l.Rate)
+	}
+
+	res := Result{Limit: l.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / l.Rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((burst - tokens) / l.Rate)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Limiter applies Limits, which can be replaced while serving, using a
+// Store.
+type Limiter struct {
+	store  Store
+	limits atomic.Pointer[Limits]
+	now    func() time.Time
+}
+
+func New(store Store, limits Limits) *Limiter {
+	l := &Limiter{store: store, now: time.Now}
+	l.SetLimits(limits)
+	return l
+}
+
+func (l *Limiter) SetLimits(limits Limits) {
+	l.limits.Store(&limits)
+}
+
+// Middleware limits the requests to group. After authentication clients
+// are limited by API key or user rather than by IP; in front of it, as
+// for AuthGroup, by IP. Every limited response
+// carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers;
+// a rejected one is a 429 with Retry-After. If the store fails the request
+// is let through.
+func (l *Limiter) Middleware(group string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			limit := l.limits.Load().For(group)
+			if limit.Rate <= 0 {
+				return next(c)
+			}
+
+			res, err := l.store.Take(c.Request().Context(), Key(c, group), limit, l.now())
+			if err != nil {
+				log.WarnContext(c.Request().Context(), "Rate limit store failed", "group", group, "error", err)
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			if !res.Allowed {
+				h.Set(echo.HeaderRetryAfter, ceilSeconds(res.RetryAfter))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+			}
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// Key names the bucket of the client making the request in group.
+func Key(c echo.Context, group string) string {
+	if id := auth.APIKey(c); id > 0 {
+		return "ratelimit:" + group + ":key:" + strconv.Itoa(id)
+	}
+	if user := auth.User(c); len(user) > 0 {
+		return "ratelimit:" + group + ":user:" + user
+	}
+	return "ratelimit:" + group + ":ip:" + c.RealIP()
+}
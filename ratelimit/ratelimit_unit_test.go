@@ -0,0 +1,93 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+)
+
+func serve(l *Limiter, group, user string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if len(user) > 0 {
+		auth.SetUser(c, user)
+	}
+
+	h := l.Middleware(group)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	if err := h(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func TestMiddleware_SetsHeadersAndRejects(t *testing.T) {
+	l := New(NewMemoryStore(), Limits{Default: Limit{Rate: 1, Burst: 2}})
+	l.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	rec := serve(l, "expenses", "alice")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+
+	serve(l, "expenses", "alice")
+	rec = serve(l, "expenses", "alice")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusNoContent, serve(l, "expenses", "bob").Code, "users have their own buckets")
+	assert.Equal(t, http.StatusNoContent, serve(l, "tags", "alice").Code, "groups have their own buckets")
+}
+
+func TestMiddleware_GroupLimits(t *testing.T) {
+	l := New(NewMemoryStore(), Limits{
+		Default: Limit{Rate: 1, Burst: 1},
+		Groups:  map[string]Limit{"admin": {}},
+	})
+
+	for i := 0; i < 3; i++ {
+		rec := serve(l, "admin", "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestMiddleware_LetsRequestsThroughWhenStoreFails(t *testing.T) {
+	l := New(failingStore{}, Limits{Default: Limit{Rate: 1, Burst: 1}})
+
+	assert.Equal(t, http.StatusNoContent, serve(l, "expenses", "").Code)
+}
+
+func TestKey(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	assert.Equal(t, "ratelimit:expenses:ip:192.0.2.1", Key(c, "expenses"))
+	auth.SetUser(c, "alice")
+	assert.Equal(t, "ratelimit:expenses:user:alice", Key(c, "expenses"))
+	auth.SetAPIKey(c, 7)
+	assert.Equal(t, "ratelimit:expenses:key:7", Key(c, "expenses"))
+}
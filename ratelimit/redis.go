@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/umateedev/assessment/resp"
+)
+
+// maxWatchAttempts bounds the retries when other instances keep changing a
+// bucket between our read and our write. A bucket that busy is being drained
+// faster than we can update it, so the request is then refused as if the
+// bucket were empty rather than let through.
+const maxWatchAttempts = 5
+
+// RedisStore keeps buckets in a server speaking the Redis protocol, so
+// that several instances share them. It needs only GET, SET and
+// WATCH/MULTI/EXEC, not scripting. Buckets expire once they have refilled.
+type RedisStore struct {
+	pool *resp.Pool
+}
+
+// NewRedisStore connects lazily to the server at url, in the form
+// redis://[user:password@]host[:port][/db].
+func NewRedisStore(url string) (*RedisStore, error) {
+	pool, err := resp.NewPool(url, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{pool: pool}, nil
+}
+
+func (s *RedisStore) Close() {
+	s.pool.Close()
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, l Limit, now time.Time) (res Result, err error) {
+	c, err := s.pool.Get(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() { s.pool.Put(c, err) }()
+
+	for i := 0; i < maxWatchAttempts; i++ {
+		reply, err := c.Do(ctx, []string{"WATCH", key}, []string{"GET", key})
+		if err != nil {
+			return Result{}, err
+		}
+
+		tokens, last := float64(l.Burst), now
+		if b, ok := reply.([]byte); ok {
+			tokens, last = decodeBucket(string(b), tokens, now)
+		}
+		tokens, res = take(l, tokens, last, now)
+
+		ttl := res.Reset + time.Second
+		reply, err = c.Do(ctx,
+			[]string{"MULTI"},
+			[]string{"SET", key, encodeBucket(tokens, now), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)},
+			[]string{"EXEC"})
+		if err != nil {
+			return Result{}, err
+		}
+		// EXEC answers nil when the watched key changed; try again.
+		if reply != nil {
+			return res, nil
+		}
+	}
+	_, res = take(l, 0, now, now)
+	return res, nil
+}
+
+// Buckets are stored as "tokens unix-microseconds".
+func encodeBucket(tokens float64, last time.Time) string {
+	return fmt.Sprintf("%s %d", strconv.FormatFloat(tokens, 'f', -1, 64), last.UnixMicro())
+}
+
+// decodeBucket returns a full bucket for a value it cannot read.
+func decodeBucket(s string, full float64, now time.Time) (float64, time.Time) {
+	t, us, ok := strings.Cut(s, " ")
+	if !ok {
+		return full, now
+	}
+	tokens, err1 := strconv.ParseFloat(t, 64)
+	micros, err2 := strconv.ParseInt(us, 10, 64)
+	if err1 != nil || err2 != nil {
+		return full, now
+	}
+	return tokens, time.UnixMicro(micros)
+}
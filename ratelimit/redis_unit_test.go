@@ -0,0 +1,73 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/resp/resptest"
+)
+
+func TestRedisStore_TokenBucket(t *testing.T) {
+	srv := resptest.NewServer(t, "s3cret")
+	s, err := NewRedisStore(srv.URL())
+	assert.NoError(t, err)
+	defer s.Close()
+	l := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, want := range []bool{true, true, false} {
+		res, err := s.Take(context.Background(), "k", l, now)
+		assert.NoError(t, err)
+		assert.Equal(t, want, res.Allowed)
+	}
+
+	res, err := s.Take(context.Background(), "k", l, now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	v, _ := srv.Get("k")
+	assert.Equal(t, encodeBucket(0, now.Add(time.Second)), v)
+}
+
+func TestRedisStore_RetriesWhenBucketChanges(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	s, _ := NewRedisStore(srv.URL())
+	defer s.Close()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv.BeforeExec = func(srv *resptest.Server) {
+		// Another instance takes the last token, once.
+		srv.BeforeExec = nil
+		srv.Set("k", encodeBucket(0, now))
+	}
+
+	res, err := s.Take(context.Background(), "k", Limit{Rate: 1, Burst: 1}, now)
+
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+}
+
+func TestRedisStore_RefusesWhenAlwaysContended(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	s, _ := NewRedisStore(srv.URL())
+	defer s.Close()
+	srv.BeforeExec = func(srv *resptest.Server) { srv.Set("k", "") }
+
+	res, err := s.Take(context.Background(), "k", Limit{Rate: 1, Burst: 5}, time.Now())
+
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+}
+
+func TestRedisStore_ReportsWrongPassword(t *testing.T) {
+	srv := resptest.NewServer(t, "s3cret")
+	s, _ := NewRedisStore("redis://:wrong@" + srv.Addr())
+	defer s.Close()
+
+	_, err := s.Take(context.Background(), "k", Limit{Rate: 1, Burst: 1}, time.Now())
+
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
@@ -0,0 +1,58 @@
+package recurring
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/tag"
+	"github.com/umateedev/assessment/validate"
+)
+
+func CreateRecurringHandler(c echo.Context) error {
+	r := Recurring{}
+	err := c.Bind(&r)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+	r.Tags = tag.Normalize(r.Tags)
+	if errs := validate.StructFor(auth.User(c), &r); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	loc, err := loadTimeZone(r.TimeZone)
+	if err != nil {
+		return problem.Invalid(c, problem.FieldError{Field: "time_zone", Message: err.Error()})
+	}
+	r.TimeZone = loc.String()
+	if r.StartsAt.IsZero() {
+		r.StartsAt = time.Now()
+	}
+	r.StartsAt = r.StartsAt.In(loc)
+
+	rule, err := ParseRule(r.Rule, r.StartsAt)
+	if err != nil {
+		return problem.Write(c, problem.New(c, http.StatusBadRequest, problem.CodeInvalidRule, err.Error()))
+	}
+
+	if next, ok := rule.Next(r.StartsAt.Add(-time.Nanosecond)); ok {
+		r.NextRun = &next
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	row := database.Db.QueryRowContext(ctx, "INSERT INTO recurring_expenses (title, amount, note, tags, rule, starts_at, time_zone, next_run, owner) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')) RETURNING id",
+		r.Title, r.Amount, r.Note, pq.Array(&r.Tags), r.Rule, r.StartsAt, r.TimeZone, r.NextRun, auth.User(c))
+	err = row.Scan(&r.Id)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, r)
+}
@@ -0,0 +1,147 @@
+//go:build unit
+
+package recurring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestCreateRecurring_ReturnBadRequest_WhenInvalidRule(t *testing.T) {
+	e := echo.New()
+	body := `{
+		"title": "rent",
+		"amount": 12000,
+		"rule": "FREQ=SOMETIMES"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/recurring", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CreateRecurringHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCreateRecurring_ReturnSuccess(t *testing.T) {
+	e := echo.New()
+	body := `{
+		"title": "rent",
+		"amount": 12000,
+		"note": "condo",
+		"tags": ["housing"],
+		"rule": "FREQ=MONTHLY;BYMONTHDAY=1",
+		"starts_at": "2023-01-15T00:00:00Z"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/recurring", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	nextRun := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO recurring_expenses").
+		WithArgs("rent", 12000.0, "condo", sqlmock.AnyArg(), "FREQ=MONTHLY;BYMONTHDAY=1", sqlmock.AnyArg(), "UTC", &nextRun, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	c := e.NewContext(req, rec)
+
+	err = CreateRecurringHandler(c)
+
+	expected := "{\"id\":1,\"title\":\"rent\",\"amount\":12000,\"note\":\"condo\",\"tags\":[\"housing\"],\"rule\":\"FREQ=MONTHLY;BYMONTHDAY=1\",\"starts_at\":\"2023-01-15T00:00:00Z\",\"time_zone\":\"UTC\",\"next_run\":\"2023-02-01T00:00:00Z\"}"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestCreateRecurring_ReturnUnprocessableEntity_WhenUnknownTimeZone(t *testing.T) {
+	body := `{"title": "rent", "amount": 12000, "rule": "FREQ=MONTHLY", "time_zone": "Mars/Olympus"}`
+	req := httptest.NewRequest(http.MethodPost, "/recurring", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	err := CreateRecurringHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "time_zone")
+	}
+}
+
+func TestCreateRecurring_StoresStartInTheTimeZone(t *testing.T) {
+	body := `{"title": "rent", "amount": 12000, "rule": "FREQ=MONTHLY;BYMONTHDAY=1", "starts_at": "2022-12-31T17:00:00Z", "time_zone": "Asia/Bangkok"}`
+	req := httptest.NewRequest(http.MethodPost, "/recurring", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+	mock.ExpectQuery("INSERT INTO recurring_expenses").
+		WithArgs("rent", 12000.0, "", sqlmock.AnyArg(), "FREQ=MONTHLY;BYMONTHDAY=1", sqlmock.AnyArg(), "Asia/Bangkok", sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	c := echo.New().NewContext(req, rec)
+
+	err = CreateRecurringHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"starts_at":"2023-01-01T00:00:00+07:00","time_zone":"Asia/Bangkok","next_run":"2023-01-01T00:00:00+07:00"`)
+	}
+}
+
+func TestCreateRecurring_ReturnUnprocessableEntity_WhenFieldsInvalid(t *testing.T) {
+	body := `{"title": " ", "amount": 0, "tags": [""], "rule": "FREQ=MONTHLY"}`
+	req := httptest.NewRequest(http.MethodPost, "/recurring", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	err := CreateRecurringHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		for _, field := range []string{"title", "amount"} {
+			assert.Contains(t, rec.Body.String(), `"field":"`+field+`"`)
+		}
+	}
+}
+
+func TestOverrideOccurrence_ReturnUnprocessableEntity_WhenFieldsInvalid(t *testing.T) {
+	body := `{"date": "2023-02-01", "amount": -5, "note": "` + strings.Repeat("n", 2001) + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	err := OverrideOccurrenceHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		for _, field := range []string{"amount", "note"} {
+			assert.Contains(t, rec.Body.String(), `"field":"`+field+`"`)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/tag"
+	"github.com/umateedev/assessment/validate"
+)
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getExceptions returns the exceptions of a template on or after from, keyed by date.
+func getExceptions(ctx context.Context, q querier, id int, from time.Time) (map[string]*Exception, error) {
+	rows, err := q.QueryContext(ctx, "SELECT occurs_on, skip, title, amount, note, tags FROM recurring_exceptions WHERE recurring_id=$1 AND occurs_on >= $2", id, from.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := map[string]*Exception{}
+	for rows.Next() {
+		var date time.Time
+		ex := Exception{}
+		err := rows.Scan(&date, &ex.Skip, &ex.Title, &ex.Amount, &ex.Note, pq.Array(&ex.Tags))
+		if err != nil {
+			return nil, err
+		}
+		ex.Date = date.Format(dateLayout)
+		exceptions[ex.Date] = &ex
+	}
+
+	return exceptions, rows.Err()
+}
+
+// SkipOccurrenceHandler prevents the template from creating an expense on the given date.
+func SkipOccurrenceHandler(c echo.Context) error {
+	ex := Exception{}
+	err := c.Bind(&ex)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	ex = Exception{Date: ex.Date, Skip: true}
+	return saveException(c, ex)
+}
+
+// OverrideOccurrenceHandler replaces the title, amount, note or tags of the
+// expense created on the given date. Omitted fields keep the template value.
+func OverrideOccurrenceHandler(c echo.Context) error {
+	ex := Exception{}
+	err := c.Bind(&ex)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	ex.Skip = false
+	if ex.Tags != nil {
+		ex.Tags = tag.Normalize(ex.Tags)
+	}
+	if errs := validate.StructFor(auth.User(c), &ex); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+	return saveException(c, ex)
+}
+
+func saveException(c echo.Context, ex Exception) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRecurring(ctx, c.Param("id"))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "recurring expense not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	// r.StartsAt is in the time zone of the template.
+	date, err := time.ParseInLocation(dateLayout, ex.Date, r.StartsAt.Location())
+	if err != nil {
+		return problem.BadRequest(c, "Invalid request, date must be YYYY-MM-DD")
+	}
+
+	if !isOccurrence(r, date) {
+		return problem.BadRequest(c, "Invalid request, no occurrence on "+ex.Date)
+	}
+
+	_, err = database.Db.ExecContext(ctx, `INSERT INTO recurring_exceptions (recurring_id, occurs_on, skip, title, amount, note, tags)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (recurring_id, occurs_on) DO UPDATE
+	SET skip = EXCLUDED.skip, title = EXCLUDED.title, amount = EXCLUDED.amount, note = EXCLUDED.note, tags = EXCLUDED.tags`,
+		r.Id, ex.Date, ex.Skip, ex.Title, ex.Amount, ex.Note, pq.Array(ex.Tags))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ex)
+}
+
+// isOccurrence reports whether the template has an occurrence on the day of date.
+func isOccurrence(r Recurring, date time.Time) bool {
+	rule, err := ParseRule(r.Rule, r.StartsAt)
+	if err != nil {
+		return false
+	}
+
+	next, ok := rule.Next(date.Add(-time.Nanosecond))
+	return ok && next.Format(dateLayout) == date.Format(dateLayout)
+}
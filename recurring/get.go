@@ -0,0 +1,84 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+const selectRecurring = "SELECT id, title, amount, note, tags, rule, starts_at, time_zone, next_run FROM recurring_expenses"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRecurring reads a row of selectRecurring, with its times in the time
+// zone of the template.
+func scanRecurring(s scanner) (Recurring, error) {
+	r := Recurring{}
+	err := s.Scan(&r.Id, &r.Title, &r.Amount, &r.Note, pq.Array(&r.Tags), &r.Rule, &r.StartsAt, &r.TimeZone, &r.NextRun)
+	if err != nil {
+		return r, err
+	}
+	loc, err := loadTimeZone(r.TimeZone)
+	if err != nil {
+		return r, err
+	}
+	r.StartsAt = r.StartsAt.In(loc)
+	if r.NextRun != nil {
+		next := r.NextRun.In(loc)
+		r.NextRun = &next
+	}
+	return r, nil
+}
+
+func getRecurring(ctx context.Context, id string) (Recurring, error) {
+	return scanRecurring(database.Db.QueryRowContext(ctx, selectRecurring+" WHERE id=$1", id))
+}
+
+func GetRecurringByIdHandler(c echo.Context) error {
+	id := c.Param("id")
+	if len(id) == 0 {
+		return problem.BadRequest(c, "Invalid request, missing param id")
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRecurring(ctx, id)
+	switch err {
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "recurring expense not found")
+	case nil:
+		return c.JSON(http.StatusOK, r)
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+func GetAllRecurringHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, selectRecurring+" ORDER BY id")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	templates := []Recurring{}
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		templates = append(templates, r)
+	}
+
+	return c.JSON(http.StatusOK, templates)
+}
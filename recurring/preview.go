@@ -0,0 +1,61 @@
+package recurring
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+const maxPreview = 100
+
+// PreviewRecurringHandler lists the next occurrences of a template with
+// skips and overrides applied. The number of occurrences is set by ?count=.
+func PreviewRecurringHandler(c echo.Context) error {
+	count := 10
+	if s := c.QueryParam("count"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPreview {
+			return problem.BadRequest(c, "Invalid request, count must be between 1 and 100")
+		}
+		count = n
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRecurring(ctx, c.Param("id"))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "recurring expense not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	rule, err := ParseRule(r.Rule, r.StartsAt)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	from := time.Now()
+	if r.NextRun != nil && r.NextRun.Before(from) {
+		from = *r.NextRun
+	}
+
+	exceptions, err := getExceptions(ctx, database.Db, r.Id, from)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	occurrences := []Occurrence{}
+	for _, t := range rule.Occurrences(from.Add(-time.Nanosecond), count) {
+		occurrences = append(occurrences, r.occurrence(t, exceptions[t.Format(dateLayout)]))
+	}
+
+	return c.JSON(http.StatusOK, occurrences)
+}
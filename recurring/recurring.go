@@ -0,0 +1,95 @@
+package recurring
+
+import (
+	"errors"
+	"time"
+	// Templates name IANA time zones, which must load without a system
+	// zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("recurring")
+
+// Recurring is a template for expenses that repeat by Rule. Occurrences and
+// exception dates are computed in TimeZone, an IANA name, so that a monthly
+// expense stays on the same local day and hour across offset changes.
+type Recurring struct {
+	Id       int        `json:"id"`
+	Title    string     `json:"title" validate:"required,max=title"`
+	Amount   float64    `json:"amount" validate:"gt=0,scale"`
+	Note     string     `json:"note" validate:"max=note"`
+	Tags     []string   `json:"tags" validate:"max=tags,each=required|max=tag"`
+	Rule     string     `json:"rule"`
+	StartsAt time.Time  `json:"starts_at"`
+	TimeZone string     `json:"time_zone"`
+	NextRun  *time.Time `json:"next_run"`
+}
+
+// Exception skips or overrides the occurrence of a template on a single date.
+type Exception struct {
+	Date   string   `json:"date"`
+	Skip   bool     `json:"skip"`
+	Title  *string  `json:"title,omitempty" validate:"max=title"`
+	Amount *float64 `json:"amount,omitempty" validate:"gt=0,scale"`
+	Note   *string  `json:"note,omitempty" validate:"max=note"`
+	Tags   []string `json:"tags,omitempty" validate:"max=tags,each=required|max=tag"`
+}
+
+type Occurrence struct {
+	Date       time.Time `json:"date"`
+	Title      string    `json:"title"`
+	Amount     float64   `json:"amount"`
+	Note       string    `json:"note"`
+	Tags       []string  `json:"tags"`
+	Skipped    bool      `json:"skipped"`
+	Overridden bool      `json:"overridden"`
+}
+
+const dateLayout = "2006-01-02"
+
+var errTimeZone = errors.New("time_zone must be an IANA time zone such as Asia/Bangkok")
+
+// loadTimeZone returns the location of an IANA time zone name; an empty
+// name is UTC.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, errTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errTimeZone
+	}
+	return loc, nil
+}
+
+// occurrence applies the exception, if any, to the template at t.
+func (r Recurring) occurrence(t time.Time, ex *Exception) Occurrence {
+	o := Occurrence{Date: t, Title: r.Title, Amount: r.Amount, Note: r.Note, Tags: r.Tags}
+	if ex == nil {
+		return o
+	}
+
+	if ex.Skip {
+		o.Skipped = true
+		return o
+	}
+	if ex.Title != nil {
+		o.Title = *ex.Title
+	}
+	if ex.Amount != nil {
+		o.Amount = *ex.Amount
+	}
+	if ex.Note != nil {
+		o.Note = *ex.Note
+	}
+	if ex.Tags != nil {
+		o.Tags = ex.Tags
+	}
+	o.Overridden = true
+	return o
+}
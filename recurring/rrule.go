@@ -0,0 +1,312 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxPeriods bounds how far ahead a rule is expanded so that a rule which
+// can never match (e.g. BYMONTHDAY=31;BYMONTH=2) does not loop forever.
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is the subset of RFC 5545 RRULE supported by the scheduler:
+// FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and BYSETPOS.
+//
+// Examples:
+//
+//	FREQ=MONTHLY;BYMONTHDAY=25                     monthly on the 25th
+//	FREQ=MONTHLY;BYMONTHDAY=-1                     last day of the month
+//	FREQ=WEEKLY;BYDAY=MO                           every Monday
+//	FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=31            every 31 March
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1  last business day
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	Start      time.Time
+}
+
+func ParseRule(s string, start time.Time) (Rule, error) {
+	r := Rule{Interval: 1, Start: start}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if len(s) == 0 {
+		return r, errors.New("empty rule")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = value
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return r, fmt.Errorf("invalid BYDAY %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(value, 1, 12)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(value, -366, 366)
+		default:
+			return r, fmt.Errorf("unsupported rule part %q", key)
+		}
+		if err != nil {
+			return r, fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+
+	if len(r.Freq) == 0 {
+		return r, errors.New("FREQ is required")
+	}
+
+	return r, nil
+}
+
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+func parseInts(s string, min, max int) ([]int, error) {
+	var values []int
+	for _, p := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("%d out of range", n)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// Next returns the first occurrence strictly after t. The second result is
+// false once the rule is exhausted by COUNT or UNTIL.
+func (r Rule) Next(after time.Time) (time.Time, bool) {
+	occurrences := r.Occurrences(after, 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
+
+// Occurrences returns up to n occurrences strictly after t.
+func (r Rule) Occurrences(after time.Time, n int) []time.Time {
+	var result []time.Time
+	count := 0
+
+	for period := 0; period < maxPeriods && len(result) < n; period++ {
+		for _, o := range r.expand(period) {
+			if o.Before(r.Start) {
+				continue
+			}
+			if !r.Until.IsZero() && o.After(r.Until) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if o.After(after) {
+				result = append(result, o)
+				if len(result) == n {
+					return result
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// expand returns the sorted candidates in the given period, where period 0
+// is the one containing Start.
+func (r Rule) expand(period int) []time.Time {
+	start := r.Start
+	step := period * r.Interval
+	var candidates []time.Time
+
+	switch r.Freq {
+	case Daily:
+		d := start.AddDate(0, 0, step)
+		if r.matchDay(d) && r.matchMonth(d.Month()) {
+			candidates = append(candidates, d)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, step*7-offset)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		for _, wd := range days {
+			candidates = append(candidates, monday.AddDate(0, 0, (int(wd)+6)%7))
+		}
+	case Monthly:
+		first := r.date(start.Year(), start.Month()+time.Month(step), 1)
+		if r.matchMonth(first.Month()) {
+			candidates = r.daysInMonth(first)
+		}
+	case Yearly:
+		year := start.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			candidates = append(candidates, r.daysInMonth(r.date(year, m, 1))...)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return r.setPos(candidates)
+}
+
+func (r Rule) daysInMonth(first time.Time) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = last + md + 1
+			}
+			if day < 1 || day > last {
+				continue
+			}
+			d := first.AddDate(0, 0, day-1)
+			if r.matchDay(d) {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for day := 0; day < last; day++ {
+			d := first.AddDate(0, 0, day)
+			if r.matchDay(d) {
+				days = append(days, d)
+			}
+		}
+	default:
+		if r.Start.Day() <= last {
+			days = append(days, first.AddDate(0, 0, r.Start.Day()-1))
+		}
+	}
+
+	return days
+}
+
+func (r Rule) setPos(candidates []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return candidates
+	}
+
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) {
+			selected = append(selected, candidates[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+func (r Rule) matchDay(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if d.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if m == bm {
+			return true
+		}
+	}
+	return false
+}
+
+// date builds a time on the given day using the time of day and location of Start.
+func (r Rule) date(year int, month time.Month, day int) time.Time {
+	s := r.Start
+	return time.Date(year, month, day, s.Hour(), s.Minute(), s.Second(), 0, s.Location())
+}
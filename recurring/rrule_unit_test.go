@@ -0,0 +1,87 @@
+//go:build unit
+
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dates(ts []time.Time) []string {
+	result := []string{}
+	for _, t := range ts {
+		result = append(result, t.Format(dateLayout))
+	}
+	return result
+}
+
+func TestParseRule_ReturnError_WhenInvalid(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, rule := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;FOO=1"} {
+		_, err := ParseRule(rule, start)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestRule_MonthlyOnDay(t *testing.T) {
+	start := time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC)
+	rule, err := ParseRule("FREQ=MONTHLY;BYMONTHDAY=25", start)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2023-01-25", "2023-02-25", "2023-03-25"}, dates(rule.Occurrences(start, 3)))
+	}
+}
+
+func TestRule_MonthlyOnLastDay(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rule, err := ParseRule("FREQ=MONTHLY;BYMONTHDAY=-1", start)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2024-01-31", "2024-02-29", "2024-03-31"}, dates(rule.Occurrences(start, 3)))
+	}
+}
+
+func TestRule_Weekly(t *testing.T) {
+	start := time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC) // Wednesday
+	rule, err := ParseRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", start)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2023-01-06", "2023-01-16", "2023-01-20"}, dates(rule.Occurrences(start, 3)))
+	}
+}
+
+func TestRule_Yearly(t *testing.T) {
+	start := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	rule, err := ParseRule("FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=31", start)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2024-03-31", "2025-03-31"}, dates(rule.Occurrences(start, 2)))
+	}
+}
+
+func TestRule_LastBusinessDay(t *testing.T) {
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	rule, err := ParseRule("FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", start)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2023-09-29", "2023-10-31", "2023-11-30", "2023-12-29"}, dates(rule.Occurrences(start, 4)))
+	}
+}
+
+func TestRule_StopsAtCountAndUntil(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rule, err := ParseRule("FREQ=DAILY;COUNT=3", start)
+	if assert.NoError(t, err) {
+		assert.Len(t, rule.Occurrences(start.Add(-time.Nanosecond), 10), 3)
+		_, ok := rule.Next(time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC))
+		assert.False(t, ok)
+	}
+
+	rule, err = ParseRule("FREQ=DAILY;UNTIL=20230105", start)
+	if assert.NoError(t, err) {
+		assert.Len(t, rule.Occurrences(start.Add(-time.Nanosecond), 10), 5)
+	}
+}
@@ -0,0 +1,169 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/category"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/search"
+	"github.com/umateedev/assessment/webhook"
+)
+
+// batchSize limits how many due templates a single run locks.
+const batchSize = 100
+
+// Scheduler periodically turns due recurring templates into expenses.
+//
+// Due templates are claimed with SELECT ... FOR UPDATE SKIP LOCKED and their
+// next_run is advanced in the same transaction as the expense inserts, so any
+// number of replicas can run a scheduler without creating duplicates.
+type Scheduler struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewScheduler(interval time.Duration) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := database.Context(context.Background())
+			n, err := RunDue(ctx, time.Now())
+			cancel()
+			if err != nil {
+				log.Error("Recurring scheduler failed", "error", err)
+			} else if n > 0 {
+				log.Info("Recurring scheduler created expenses", "count", n)
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the current run to finish.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// RunDue creates the expenses of every occurrence due at or before now and
+// returns how many were created. Skipped occurrences advance the schedule
+// without creating an expense.
+func RunDue(ctx context.Context, now time.Time) (int, error) {
+	created := 0
+	err := database.InTx(ctx, func(tx *sql.Tx) error {
+		created = 0
+		rows, err := tx.QueryContext(ctx, selectRecurring+" WHERE next_run <= $1 ORDER BY next_run LIMIT $2 FOR UPDATE SKIP LOCKED", now, batchSize)
+		if err != nil {
+			return err
+		}
+
+		due := []Recurring{}
+		for rows.Next() {
+			r, err := scanRecurring(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			due = append(due, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, r := range due {
+			n, err := run(ctx, tx, r, now)
+			if err != nil {
+				return err
+			}
+			created += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if created > 0 {
+		cache.ExpenseCreated(ctx)
+	}
+	return created, nil
+}
+
+func run(ctx context.Context, tx *sql.Tx, r Recurring, now time.Time) (int, error) {
+	rule, err := ParseRule(r.Rule, r.StartsAt)
+	if err != nil {
+		log.WarnContext(ctx, "Recurring expense has invalid rule", "id", r.Id, "error", err)
+		_, err = tx.ExecContext(ctx, "UPDATE recurring_expenses SET next_run = NULL WHERE id = $1", r.Id)
+		return 0, err
+	}
+
+	exceptions, err := getExceptions(ctx, tx, r.Id, *r.NextRun)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	next, ok := *r.NextRun, true
+	for ok && !next.After(now) {
+		o := r.occurrence(next, exceptions[next.Format(dateLayout)])
+		if !o.Skipped {
+			inserted, err := outbox.AddFrom(ctx, tx, outbox.ExpenseCreated, "INSERT INTO expenses (title, amount, note, tags, created_at, search, owner) VALUES ($1, $2, $3, $4, $5, $6::tsvector, (SELECT owner FROM recurring_expenses WHERE id = $7))"+outbox.Returning,
+				o.Title, o.Amount, o.Note, pq.Array(&o.Tags), o.Date, search.Vector(o.Title, o.Note), r.Id)
+			if err != nil {
+				return 0, err
+			}
+			if err := notify(ctx, tx, inserted); err != nil {
+				return 0, err
+			}
+			created++
+		}
+		next, ok = rule.Next(next)
+	}
+
+	var nextRun *time.Time
+	if ok {
+		nextRun = &next
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE recurring_expenses SET next_run = $1 WHERE id = $2", nextRun, r.Id)
+	return created, err
+}
+
+// notify queues, as part of tx, the webhook events of expenses created
+// from a template.
+func notify(ctx context.Context, tx *sql.Tx, inserted []outbox.Changed) error {
+	if err := webhook.EnqueueChanged(ctx, tx, webhook.ExpenseCreated, inserted); err != nil {
+		return err
+	}
+	for _, e := range inserted {
+		if e.CategoryId == nil {
+			continue
+		}
+		if err := category.NotifyBudgetsExceeded(ctx, tx, e.Owner, e.ExpenseId, nil, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
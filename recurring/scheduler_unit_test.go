@@ -0,0 +1,117 @@
+//go:build unit
+
+package recurring
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+// changedColumns are the columns outbox.AddFrom reads back.
+var changedColumns = []string{"id", "owner", "amount", "category_id", "data"}
+
+func TestRunDue_CreatesExpenses_AndHonoursExceptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nextRun := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM recurring_expenses WHERE next_run <= \\$1 (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(now, batchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "rule", "starts_at", "time_zone", "next_run"}).
+			AddRow(1, "rent", 12000.0, "condo", pq.Array([]string{"housing"}), "FREQ=MONTHLY;BYMONTHDAY=1", start, "UTC", nextRun))
+	mock.ExpectQuery("SELECT occurs_on, skip, title, amount, note, tags FROM recurring_exceptions").
+		WithArgs(1, "2023-01-01").
+		WillReturnRows(sqlmock.NewRows([]string{"occurs_on", "skip", "title", "amount", "note", "tags"}).
+			AddRow(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), true, nil, nil, nil, nil).
+			AddRow(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), false, nil, 13000.0, nil, nil))
+	mock.ExpectQuery("INSERT INTO expenses (.+)INSERT INTO outbox").
+		WithArgs("rent", 12000.0, "condo", sqlmock.AnyArg(), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), sqlmock.AnyArg(), 1, "expense.created").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(1, "alice", 12000.0, nil, `{"id":1}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.created", sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO expenses (.+)INSERT INTO outbox").
+		WithArgs("rent", 13000.0, "condo", sqlmock.AnyArg(), time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), sqlmock.AnyArg(), 1, "expense.created").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(2, "alice", 13000.0, nil, `{"id":2}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.created", sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	nextApril := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE recurring_expenses SET next_run").
+		WithArgs(&nextApril, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := RunDue(context.Background(), now)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+// instant matches a time.Time argument at the same instant in any location.
+type instant time.Time
+
+func (i instant) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Equal(time.Time(i))
+}
+
+func TestRunDue_FollowsTheTimeZoneOfTheTemplate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+	database.Db = db
+
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Midnight on the 1st in Bangkok is 17:00 UTC on the previous day.
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, bangkok).UTC()
+	nextRun := time.Date(2023, 2, 1, 0, 0, 0, 0, bangkok).UTC()
+	now := time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM recurring_expenses WHERE next_run").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "rule", "starts_at", "time_zone", "next_run"}).
+			AddRow(1, "rent", 12000.0, "condo", pq.Array([]string{"housing"}), "FREQ=MONTHLY;BYMONTHDAY=1", start, "Asia/Bangkok", nextRun))
+	mock.ExpectQuery("SELECT occurs_on, skip, title, amount, note, tags FROM recurring_exceptions").
+		WithArgs(1, "2023-02-01").
+		WillReturnRows(sqlmock.NewRows([]string{"occurs_on", "skip", "title", "amount", "note", "tags"}))
+	for i, month := range []time.Month{2, 3} {
+		mock.ExpectQuery("INSERT INTO expenses (.+)INSERT INTO outbox").
+			WithArgs("rent", 12000.0, "condo", sqlmock.AnyArg(), instant(time.Date(2023, month, 1, 0, 0, 0, 0, bangkok)), sqlmock.AnyArg(), 1, "expense.created").
+			WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(i+1, "", 12000.0, nil, `{}`))
+		mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("UPDATE recurring_expenses SET next_run").
+		WithArgs(instant(time.Date(2023, 4, 1, 0, 0, 0, 0, bangkok)), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := RunDue(context.Background(), now)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,256 @@
+// Package resp is a small client for servers speaking RESP2, the Redis
+// serialization protocol: Redis itself, Valkey, KeyDB and the like. It
+// covers what the rate limiter and the response cache need and no more.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is an error reply from the server. The connection stays usable
+// after one.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Conn speaks RESP2 over one connection. Replies are string (simple strings), int64, []byte (bulk
+// strings), []interface{} (arrays) or nil.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func (c *Conn) send(args ...string) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+}
+
+// Receive reads one reply. Servers can use it to read commands, which are
+// arrays of bulk strings.
+func (c *Conn) Receive() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			// An error inside an array, as EXEC returns for a failed
+			// command, is kept as the item.
+			items[i], err = c.Receive()
+			if e, ok := err.(Error); ok {
+				items[i], err = e, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
+
+// Do sends the commands in one write and returns the reply to the last, or
+// the first error reply.
+func (c *Conn) Do(ctx context.Context, cmds ...[]string) (interface{}, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+
+	for _, cmd := range cmds {
+		c.send(cmd...)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	var reply interface{}
+	var firstErr error
+	for range cmds {
+		r, err := c.Receive()
+		if _, ok := err.(Error); ok && firstErr == nil {
+			// Read the remaining replies so the connection stays in step.
+			firstErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reply = r
+	}
+	return reply, firstErr
+}
+
+// Pool hands out connections to one server, keeping a few idle ones.
+type Pool struct {
+	addr     string
+	username string
+	password string
+	db       int
+	idle     chan *Conn
+}
+
+// NewPool connects lazily to the server at rawURL, in the form
+// redis://[user:password@]host[:port][/db], keeping up to maxIdle idle
+// connections.
+func NewPool(rawURL string, maxIdle int) (*Pool, error) {
+	p, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	p.idle = make(chan *Conn, maxIdle)
+	return p, nil
+}
+
+// CheckURL reports whether rawURL can be passed to NewPool.
+func CheckURL(rawURL string) error {
+	_, err := parseURL(rawURL)
+	return err
+}
+
+func parseURL(rawURL string) (*Pool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("redis url must start with redis://, got %q", u.Scheme)
+	}
+	p := &Pool{addr: u.Host}
+	if len(u.Port()) == 0 {
+		p.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		p.username = u.User.Username()
+		p.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); len(db) > 0 {
+		if p.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis database must be a number, got %q", db)
+		}
+	}
+	return p, nil
+}
+
+// Get returns an idle connection or dials a new one.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := NewConn(nc)
+
+	var setup [][]string
+	switch {
+	case len(p.password) > 0 && len(p.username) > 0:
+		setup = append(setup, []string{"AUTH", p.username, p.password})
+	case len(p.password) > 0:
+		setup = append(setup, []string{"AUTH", p.password})
+	}
+	if p.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(p.db)})
+	}
+	if len(setup) > 0 {
+		if _, err := c.Do(ctx, setup...); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Put returns c to the pool unless err, the last error seen on it, shows
+// that it is broken.
+func (p *Pool) Put(c *Conn, err error) {
+	var re Error
+	if err != nil && !errors.As(err, &re) {
+		c.conn.Close()
+		return
+	}
+	select {
+	case p.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// Close closes the idle connections.
+func (p *Pool) Close() {
+	for {
+		select {
+		case c := <-p.idle:
+			c.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Do runs the commands on a pooled connection.
+func (p *Pool) Do(ctx context.Context, cmds ...[]string) (interface{}, error) {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.Do(ctx, cmds...)
+	p.Put(c, err)
+	return reply, err
+}
+
+// NewConn wraps an established connection.
+func NewConn(nc net.Conn) *Conn {
+	return &Conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+}
@@ -0,0 +1,46 @@
+//go:build unit
+
+package resp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPool_ParsesURL(t *testing.T) {
+	p, err := NewPool("redis://app:pw@cache/3", 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "cache:6379", p.addr)
+		assert.Equal(t, "app", p.username)
+		assert.Equal(t, "pw", p.password)
+		assert.Equal(t, 3, p.db)
+	}
+
+	_, err = NewPool("http://cache", 1)
+	assert.Error(t, err)
+	_, err = NewPool("redis://cache/zero", 1)
+	assert.Error(t, err)
+}
+
+func TestConn_Do(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		s := NewConn(server)
+		for i := 0; i < 3; i++ {
+			if _, err := s.Receive(); err != nil {
+				return
+			}
+		}
+		server.Write([]byte("+OK\r\n-ERR wrong type\r\n*3\r\n:1\r\n$3\r\nabc\r\n$-1\r\n"))
+	}()
+
+	reply, err := NewConn(client).Do(context.Background(), []string{"SET", "k", "v"}, []string{"INCR", "k"}, []string{"MGET", "a", "b", "c"})
+
+	assert.Equal(t, Error("ERR wrong type"), err)
+	assert.Equal(t, []interface{}{int64(1), []byte("abc"), nil}, reply)
+}
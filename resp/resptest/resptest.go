@@ -0,0 +1,217 @@
+// Package resptest runs a stand-in Redis server for tests. It keeps string
+// values in memory and understands AUTH, SELECT, PING, GET, MGET, SET (with
+// NX), DEL, INCR, PEXPIRE and WATCH/MULTI/EXEC. Expiry is accepted and
+// ignored.
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/umateedev/assessment/resp"
+)
+
+type Server struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	data    map[string]string
+	version map[string]int
+	// BeforeExec, if set, runs inside EXEC before the watched keys are
+	// checked, with the server locked. It can call Set to simulate
+	// another client.
+	BeforeExec func(s *Server)
+}
+
+// NewServer starts a server that requires password, if not empty, and
+// stops it when the test ends.
+func NewServer(t testing.TB, password string) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ln: ln, password: password, data: map[string]string{}, version: map[string]int{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Addr is the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// URL is a redis:// URL for the server, with its password.
+func (s *Server) URL() string {
+	if len(s.password) > 0 {
+		return fmt.Sprintf("redis://:%s@%s/2", s.password, s.Addr())
+	}
+	return "redis://" + s.Addr()
+}
+
+// Get returns the value of key, for assertions. Do not call it from
+// BeforeExec.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+// Set changes key as another client would. Call it from BeforeExec.
+func (s *Server) Set(key, value string) {
+	s.data[key] = value
+	s.version[key]++
+}
+
+func (s *Server) serve(nc net.Conn) {
+	defer nc.Close()
+	c := resp.NewConn(nc)
+	w := bufio.NewWriter(nc)
+	authed := len(s.password) == 0
+	watched := map[string]int{}
+	var queued [][]string
+	inMulti := false
+
+	for {
+		req, err := c.Receive()
+		if err != nil {
+			return
+		}
+		items, ok := req.([]interface{})
+		if !ok || len(items) == 0 {
+			return
+		}
+		var args []string
+		for _, a := range items {
+			b, _ := a.([]byte)
+			args = append(args, string(b))
+		}
+		cmd := strings.ToUpper(args[0])
+
+		s.mu.Lock()
+		switch {
+		case cmd == "AUTH":
+			authed = args[len(args)-1] == s.password
+			if authed {
+				fmt.Fprint(w, "+OK\r\n")
+			} else {
+				fmt.Fprint(w, "-WRONGPASS invalid username-password pair\r\n")
+			}
+		case !authed:
+			fmt.Fprint(w, "-NOAUTH Authentication required.\r\n")
+		case cmd == "SELECT":
+			fmt.Fprint(w, "+OK\r\n")
+		case cmd == "PING":
+			fmt.Fprint(w, "+PONG\r\n")
+		case cmd == "WATCH":
+			for _, k := range args[1:] {
+				watched[k] = s.version[k]
+			}
+			fmt.Fprint(w, "+OK\r\n")
+		case cmd == "MULTI":
+			inMulti = true
+			fmt.Fprint(w, "+OK\r\n")
+		case cmd == "EXEC":
+			if s.BeforeExec != nil {
+				s.BeforeExec(s)
+			}
+			changed := false
+			for k, v := range watched {
+				changed = changed || s.version[k] != v
+			}
+			if changed {
+				fmt.Fprint(w, "*-1\r\n")
+			} else {
+				fmt.Fprintf(w, "*%d\r\n", len(queued))
+				for _, q := range queued {
+					s.exec(w, q)
+				}
+			}
+			watched, queued, inMulti = map[string]int{}, nil, false
+		case inMulti:
+			queued = append(queued, args)
+			fmt.Fprint(w, "+QUEUED\r\n")
+		default:
+			s.exec(w, args)
+		}
+		s.mu.Unlock()
+		w.Flush()
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, args []string) {
+	switch cmd := strings.ToUpper(args[0]); {
+	case cmd == "GET" && len(args) == 2:
+		if v, ok := s.data[args[1]]; ok {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		} else {
+			fmt.Fprint(w, "$-1\r\n")
+		}
+	case cmd == "MGET" && len(args) >= 2:
+		fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+		for _, k := range args[1:] {
+			if v, ok := s.data[k]; ok {
+				fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(w, "$-1\r\n")
+			}
+		}
+	case cmd == "PEXPIRE" && len(args) == 3:
+		if _, ok := s.data[args[1]]; ok {
+			fmt.Fprint(w, ":1\r\n")
+		} else {
+			fmt.Fprint(w, ":0\r\n")
+		}
+	case cmd == "SET" && len(args) >= 3:
+		if _, ok := s.data[args[1]]; ok && hasOption(args[3:], "NX") {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		s.Set(args[1], args[2])
+		fmt.Fprint(w, "+OK\r\n")
+	case cmd == "DEL" && len(args) >= 2:
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := s.data[k]; ok {
+				delete(s.data, k)
+				s.version[k]++
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case cmd == "INCR" && len(args) == 2:
+		n, err := strconv.ParseInt(s.data[args[1]], 10, 64)
+		if _, ok := s.data[args[1]]; ok && err != nil {
+			fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+			return
+		}
+		s.Set(args[1], strconv.FormatInt(n+1, 10))
+		fmt.Fprintf(w, ":%d\r\n", n+1)
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", cmd)
+	}
+}
+
+func hasOption(args []string, option string) bool {
+	for _, a := range args {
+		if strings.EqualFold(a, option) {
+			return true
+		}
+	}
+	return false
+}
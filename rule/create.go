@@ -0,0 +1,57 @@
+package rule
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/tag"
+)
+
+// bindRule binds and validates a rule from the request body.
+func bindRule(c echo.Context) (*Rule, problem.Problem) {
+	r := &Rule{Enabled: true}
+	err := c.Bind(r)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return nil, problem.New(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request")
+	}
+
+	r.AddTags = tag.Normalize(r.AddTags)
+	if r.AddTags == nil {
+		r.AddTags = []string{}
+	}
+	if err := r.compile(); err != nil {
+		return nil, problem.New(c, http.StatusBadRequest, problem.CodeInvalidRule, err.Error())
+	}
+	return r, problem.Problem{}
+}
+
+func CreateRuleHandler(c echo.Context) error {
+	r, p := bindRule(c)
+	if r == nil {
+		return problem.Write(c, p)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	row := database.Db.QueryRowContext(ctx, "INSERT INTO rules (name, title, note, amount_gte, amount_lt, add_tags, category_id, priority, enabled) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		r.Name, r.Title, r.Note, r.AmountGte, r.AmountLt, pq.Array(r.AddTags), r.CategoryId, r.Priority, r.Enabled)
+	err := row.Scan(&r.Id)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	reload(ctx)
+	return c.JSON(http.StatusCreated, r)
+}
+
+func reload(ctx context.Context) {
+	if err := LoadRules(ctx); err != nil {
+		log.ErrorContext(ctx, "Reload rules failed", "error", err)
+	}
+}
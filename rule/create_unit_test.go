@@ -0,0 +1,58 @@
+//go:build unit
+
+package rule
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestCreateRule_ReturnBadRequest_WhenNoAction(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(`{"title": "starbucks"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CreateRuleHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCreateRule_ReturnSuccess_AndReloadsRules(t *testing.T) {
+	e := echo.New()
+	body := `{"name": "coffee", "title": "/starbucks/i", "amount_lt": 500, "add_tags": ["coffee", "food"]}`
+	req := httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("INSERT INTO rules").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE enabled").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "note", "amount_gte", "amount_lt", "add_tags", "category_id", "priority", "enabled"}))
+
+	err = CreateRuleHandler(c)
+
+	expected := "{\"id\":1,\"name\":\"coffee\",\"title\":\"/starbucks/i\",\"amount_lt\":500,\"add_tags\":[\"coffee\",\"food\"],\"priority\":0,\"enabled\":true}"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,197 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/category"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
+)
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// applyBatch is how many expenses ApplyRuleHandler locks at a time.
+var applyBatch = 500
+
+// changes evaluates r against the existing expenses with an id above after,
+// at most limit of them if limit > 0, and returns the ones it would modify
+// and the last id it read, or 0 if there were none. Disabled rules are
+// evaluated too, so a rule can be tried out before it is switched on.
+func changes(ctx context.Context, q querier, r *Rule, after, limit int, lock bool) ([]Change, int, error) {
+	conds, args := r.where(nil)
+	if after > 0 {
+		args = append(args, after)
+		conds = append(conds, "id > $"+strconv.Itoa(len(args)))
+	}
+	query := "SELECT id, title, amount, COALESCE(note, ''), tags, category_id FROM expenses"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id"
+	if limit > 0 {
+		args = append(args, limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := []Change{}
+	last := 0
+	for rows.Next() {
+		ch := Change{}
+		t := Target{}
+		err := rows.Scan(&ch.ExpenseId, &t.Title, &t.Amount, &t.Note, pq.Array(&t.Tags), &t.CategoryId)
+		if err != nil {
+			return nil, 0, err
+		}
+		last = ch.ExpenseId
+
+		ch.Title = t.Title
+		ch.TagsBefore = append([]string{}, t.Tags...)
+		ch.CategoryBefore = t.CategoryId
+		if r.Match(&t) && r.apply(&t) {
+			ch.TagsAfter = t.Tags
+			ch.CategoryAfter = t.CategoryId
+			result = append(result, ch)
+		}
+	}
+
+	return result, last, rows.Err()
+}
+
+// DryRunRuleHandler shows what a saved rule would change on existing expenses.
+func DryRunRuleHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRule(ctx, c.Param("id"))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "rule not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	return dryRun(ctx, c, r)
+}
+
+// DryRunNewRuleHandler shows what the rule in the request body would change
+// without saving it.
+func DryRunNewRuleHandler(c echo.Context) error {
+	r, p := bindRule(c)
+	if r == nil {
+		return problem.Write(c, p)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	return dryRun(ctx, c, r)
+}
+
+func dryRun(ctx context.Context, c echo.Context, r *Rule) error {
+	result, _, err := changes(ctx, database.Db, r, 0, 0, false)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ApplyRuleHandler applies a saved rule retroactively to existing expenses
+// and returns the changes it made.
+//
+// Expenses are locked and updated applyBatch at a time, in id order, each
+// batch in its own transaction, so that writers elsewhere in the table are
+// not held up. If a batch fails the earlier ones stay applied; applying the
+// rule again finishes the job, since expenses it already changed are left
+// alone.
+func ApplyRuleHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRule(ctx, c.Param("id"))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "rule not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	result := []Change{}
+	defer func() {
+		if len(result) > 0 {
+			cache.ExpensesChanged(c.Request().Context())
+		}
+	}()
+	for after := 0; ; {
+		batch, last, err := applyBatchAfter(c.Request().Context(), r, after)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		result = append(result, batch...)
+		if last == 0 {
+			break
+		}
+		after = last
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// applyBatchAfter applies r to the next batch of expenses after id after
+// and returns the changes and the last id read, 0 at the end.
+func applyBatchAfter(ctx context.Context, r *Rule, after int) ([]Change, int, error) {
+	ctx, cancel := database.Context(ctx)
+	defer cancel()
+
+	var result []Change
+	var last int
+	err := database.InTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		result, last, err = changes(ctx, tx, r, after, applyBatch, true)
+		if err != nil {
+			return err
+		}
+
+		for _, ch := range result {
+			changed, err := outbox.AddFrom(ctx, tx, outbox.ExpenseUpdated, "UPDATE expenses SET tags = $1, category_id = $2, rule_ids = array_append(rule_ids, $3) WHERE id = $4"+outbox.Returning,
+				pq.Array(ch.TagsAfter), ch.CategoryAfter, r.Id, ch.ExpenseId)
+			if err != nil {
+				return err
+			}
+			if err := webhook.EnqueueChanged(ctx, tx, webhook.ExpenseUpdated, changed); err != nil {
+				return err
+			}
+			if ch.CategoryAfter == nil || ch.CategoryBefore != nil && *ch.CategoryBefore == *ch.CategoryAfter {
+				continue
+			}
+			for _, e := range changed {
+				if err := category.NotifyBudgetsExceeded(ctx, tx, e.Owner, e.ExpenseId, ch.CategoryBefore, e.Amount); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return result, last, err
+}
@@ -0,0 +1,166 @@
+//go:build unit
+
+package rule
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+// changedColumns are the columns outbox.AddFrom reads back.
+var changedColumns = []string{"id", "owner", "amount", "category_id", "data"}
+
+func TestDryRunNewRule_ReturnChanges(t *testing.T) {
+	e := echo.New()
+	body := `{"title": "/starbucks/i", "amount_lt": 500, "add_tags": ["coffee"]}`
+	req := httptest.NewRequest(http.MethodPost, "/rules/dry-run", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery(`SELECT id, title, amount, COALESCE\(note, ''\), tags, category_id FROM expenses WHERE amount < \$1 AND title ILIKE \$2 AND (.+) ORDER BY id$`).
+		WithArgs(500.0, "%starbucks%", pq.Array([]string{"coffee"})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).
+			AddRow(1, "Starbucks", 120.0, "", pq.Array([]string{"food"}), nil).
+			AddRow(2, "Starbucks", 120.0, "", pq.Array([]string{"coffee"}), nil).
+			AddRow(3, "Amazon", 120.0, "", pq.Array([]string{}), nil))
+
+	err = DryRunNewRuleHandler(c)
+
+	expected := "[{\"expense_id\":1,\"title\":\"Starbucks\",\"tags_before\":[\"food\"],\"tags_after\":[\"food\",\"coffee\"],\"category_before\":null,\"category_after\":null}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestApplyRule_UpdatesMatchingExpensesInBatches(t *testing.T) {
+	defer func(n int) { applyBatch = n }(applyBatch)
+	applyBatch = 2
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/rules/:id/apply")
+	c.SetParamNames("id")
+	c.SetParamValues("5")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE id").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "note", "amount_gte", "amount_lt", "add_tags", "category_id", "priority", "enabled"}).
+			AddRow(5, "coffee", "(?i)starbucks", "", nil, nil, pq.Array([]string{"coffee"}), nil, 0, true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE title ILIKE \$1 AND \(NOT COALESCE\(tags, '{}'\) @> \$2::text\[\]\) ORDER BY id LIMIT \$3 FOR UPDATE`).
+		WithArgs("%starbucks%", pq.Array([]string{"coffee"}), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).
+			AddRow(1, "Starbucks", 120.0, "", pq.Array([]string{"food"}), nil).
+			AddRow(4, "Starbucks card top-up", 500.0, "", pq.Array([]string{}), nil))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), nil, 5, 1, "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(1, "", 120.0, nil, `{"id":1}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), nil, 5, 4, "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(4, "", 500.0, nil, `{"id":4}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE (.+) AND id > \$3 ORDER BY id LIMIT \$4 FOR UPDATE`).
+		WithArgs("%starbucks%", pq.Array([]string{"coffee"}), 4, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).
+			AddRow(9, "starbucks", 80.0, "", pq.Array([]string{}), nil))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), nil, 5, 9, "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(9, "", 80.0, nil, `{"id":9}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE (.+) AND id > \$3 ORDER BY id LIMIT \$4 FOR UPDATE`).
+		WithArgs("%starbucks%", pq.Array([]string{"coffee"}), 9, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}))
+	mock.ExpectCommit()
+
+	err = ApplyRuleHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 3, strings.Count(rec.Body.String(), "expense_id"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestApplyRule_NotifiesBudgetsOfTheNewCategory(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/rules/:id/apply")
+	c.SetParamNames("id")
+	c.SetParamValues("5")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE id").WithArgs("5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "note", "amount_gte", "amount_lt", "add_tags", "category_id", "priority", "enabled"}).
+			AddRow(5, "coffee", "(?i)starbucks", "", nil, nil, pq.Array([]string{}), 2, 0, true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE (.+) FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}).
+			AddRow(1, "Starbucks", 120.0, "", pq.Array([]string{}), nil))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), 2, 5, 1, "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(1, "alice", 120.0, 2, `{"id":1}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM expenses x").
+		WithArgs(1, nil, 120.0).
+		WillReturnRows(sqlmock.NewRows([]string{"category_id", "name", "amount", "spent", "month"}).AddRow(2, "Coffee", 100.0, 120.0, "2023-05"))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "budget.exceeded", sqlmock.AnyArg(), "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM expenses WHERE (.+) AND id > (.+) FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category_id"}))
+	mock.ExpectCommit()
+
+	err = ApplyRuleHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,74 @@
+package rule
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+)
+
+const selectRule = "SELECT id, name, title, note, amount_gte, amount_lt, add_tags, category_id, priority, enabled FROM rules"
+
+var (
+	mu    sync.RWMutex
+	rules []*Rule
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRule(s scanner) (*Rule, error) {
+	r := &Rule{}
+	err := s.Scan(&r.Id, &r.Name, &r.Title, &r.Note, &r.AmountGte, &r.AmountLt, pq.Array(&r.AddTags), &r.CategoryId, &r.Priority, &r.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	if r.AddTags == nil {
+		r.AddTags = []string{}
+	}
+	return r, r.compile()
+}
+
+// LoadRules replaces the in-memory rule set with the enabled rules in the
+// database. It runs at startup and after every change through the API.
+func LoadRules(ctx context.Context) error {
+	rows, err := database.Db.QueryContext(ctx, selectRule+" WHERE enabled ORDER BY priority, id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	loaded := []*Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	rules = loaded
+	return nil
+}
+
+// Apply runs every enabled rule against t in priority order and returns the
+// ids of the rules that fired.
+func Apply(t *Target) []int64 {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var fired []int64
+	for _, r := range rules {
+		if r.Match(t) && r.apply(t) {
+			fired = append(fired, int64(r.Id))
+		}
+	}
+	return fired
+}
@@ -0,0 +1,52 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func GetAllRuleHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, selectRule+" ORDER BY priority, id")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	result := []*Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		result = append(result, r)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func getRule(ctx context.Context, id string) (*Rule, error) {
+	return scanRule(database.Db.QueryRowContext(ctx, selectRule+" WHERE id = $1", id))
+}
+
+func GetRuleByIdHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	r, err := getRule(ctx, c.Param("id"))
+	switch err {
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "rule not found")
+	case nil:
+		return c.JSON(http.StatusOK, r)
+	default:
+		return problem.Internal(c, err)
+	}
+}
@@ -0,0 +1,137 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/umateedev/assessment/logging"
+)
+
+var log = logging.For("rule")
+
+// Rule adds tags and/or sets a category on expenses that match all of its
+// conditions. Title and Note are regular expressions, either in Go syntax or
+// written as /pattern/i.
+type Rule struct {
+	Id         int      `json:"id"`
+	Name       string   `json:"name"`
+	Title      string   `json:"title,omitempty"`
+	Note       string   `json:"note,omitempty"`
+	AmountGte  *float64 `json:"amount_gte,omitempty"`
+	AmountLt   *float64 `json:"amount_lt,omitempty"`
+	AddTags    []string `json:"add_tags"`
+	CategoryId *int     `json:"category_id,omitempty"`
+	Priority   int      `json:"priority"`
+	Enabled    bool     `json:"enabled"`
+
+	title *regexp.Regexp
+	note  *regexp.Regexp
+}
+
+// Target is the part of an expense rules read and change.
+type Target struct {
+	Title      string
+	Note       string
+	Amount     float64
+	Tags       []string
+	CategoryId *int
+}
+
+type Change struct {
+	ExpenseId      int      `json:"expense_id"`
+	Title          string   `json:"title"`
+	TagsBefore     []string `json:"tags_before"`
+	TagsAfter      []string `json:"tags_after"`
+	CategoryBefore *int     `json:"category_before"`
+	CategoryAfter  *int     `json:"category_after"`
+}
+
+// compile validates the rule and prepares its regular expressions.
+func (r *Rule) compile() error {
+	if !r.hasCondition() {
+		return errors.New("at least one condition is required")
+	}
+	if len(r.AddTags) == 0 && r.CategoryId == nil {
+		return errors.New("at least one action is required")
+	}
+
+	var err error
+	if r.title, err = compilePattern(r.Title); err != nil {
+		return fmt.Errorf("invalid title pattern: %v", err)
+	}
+	if r.note, err = compilePattern(r.Note); err != nil {
+		return fmt.Errorf("invalid note pattern: %v", err)
+	}
+	return nil
+}
+
+func (r *Rule) hasCondition() bool {
+	return len(r.Title) > 0 || len(r.Note) > 0 || r.AmountGte != nil || r.AmountLt != nil
+}
+
+// compilePattern accepts "/starbucks/i" as well as plain Go regular expressions.
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+
+	if end := strings.LastIndex(p, "/"); strings.HasPrefix(p, "/") && end > 0 {
+		flags := p[end+1:]
+		if strings.Trim(flags, "ims") == "" {
+			p = p[1:end]
+			if len(flags) > 0 {
+				p = "(?" + flags + ")" + p
+			}
+		}
+	}
+
+	return regexp.Compile(p)
+}
+
+func (r *Rule) Match(t *Target) bool {
+	if r.title != nil && !r.title.MatchString(t.Title) {
+		return false
+	}
+	if r.note != nil && !r.note.MatchString(t.Note) {
+		return false
+	}
+	if r.AmountGte != nil && t.Amount < *r.AmountGte {
+		return false
+	}
+	if r.AmountLt != nil && t.Amount >= *r.AmountLt {
+		return false
+	}
+	return true
+}
+
+// apply runs the actions of the rule on t and reports whether anything changed.
+// A category already set on the expense is never replaced.
+func (r *Rule) apply(t *Target) bool {
+	changed := false
+
+	for _, tag := range r.AddTags {
+		if !contains(t.Tags, tag) {
+			t.Tags = append(t.Tags, tag)
+			changed = true
+		}
+	}
+
+	if r.CategoryId != nil && t.CategoryId == nil {
+		id := *r.CategoryId
+		t.CategoryId = &id
+		changed = true
+	}
+
+	return changed
+}
+
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+//go:build unit
+
+package rule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func float(f float64) *float64 { return &f }
+
+func TestCompile_ReturnError_WhenInvalid(t *testing.T) {
+	for _, r := range []Rule{
+		{AddTags: []string{"coffee"}},
+		{Title: "starbucks"},
+		{Title: "/(unclosed/i", AddTags: []string{"coffee"}},
+	} {
+		assert.Error(t, r.compile())
+	}
+}
+
+func TestMatch_SlashPatternWithFlagsAndAmount(t *testing.T) {
+	r := Rule{Title: "/starbucks/i", AmountLt: float(500), AddTags: []string{"coffee", "food"}}
+	assert.NoError(t, r.compile())
+
+	assert.True(t, r.Match(&Target{Title: "STARBUCKS Siam", Amount: 145}))
+	assert.False(t, r.Match(&Target{Title: "Starbucks Siam", Amount: 500}))
+	assert.False(t, r.Match(&Target{Title: "Amazon", Amount: 145}))
+}
+
+func TestApply_AddsMissingTagsAndKeepsCategory(t *testing.T) {
+	category := 3
+	r := Rule{Title: "coffee", AddTags: []string{"coffee", "food"}, CategoryId: &category}
+	assert.NoError(t, r.compile())
+
+	target := &Target{Title: "coffee", Tags: []string{"food"}}
+	assert.True(t, r.apply(target))
+	assert.Equal(t, []string{"food", "coffee"}, target.Tags)
+	assert.Equal(t, 3, *target.CategoryId)
+
+	assert.False(t, r.apply(target))
+}
+
+func TestApply_ReturnsFiredRulesInPriorityOrder(t *testing.T) {
+	first := &Rule{Id: 2, Title: "(?i)grab", AddTags: []string{"transport"}}
+	second := &Rule{Id: 1, AmountGte: float(1000), AddTags: []string{"large"}}
+	unmatched := &Rule{Id: 3, Note: "refund", AddTags: []string{"refund"}}
+	for _, r := range []*Rule{first, second, unmatched} {
+		assert.NoError(t, r.compile())
+	}
+	mu.Lock()
+	rules = []*Rule{first, second, unmatched}
+	mu.Unlock()
+
+	target := &Target{Title: "Grab to airport", Amount: 1200}
+	fired := Apply(target)
+
+	assert.Equal(t, []int64{2, 1}, fired)
+	assert.Equal(t, []string{"transport", "large"}, target.Tags)
+}
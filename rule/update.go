@@ -0,0 +1,48 @@
+package rule
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func UpdateRuleHandler(c echo.Context) error {
+	r, p := bindRule(c)
+	if r == nil {
+		return problem.Write(c, p)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	row := database.Db.QueryRowContext(ctx, "UPDATE rules SET name = $1, title = $2, note = $3, amount_gte = $4, amount_lt = $5, add_tags = $6, category_id = $7, priority = $8, enabled = $9 WHERE id = $10 RETURNING id",
+		r.Name, r.Title, r.Note, r.AmountGte, r.AmountLt, pq.Array(r.AddTags), r.CategoryId, r.Priority, r.Enabled, c.Param("id"))
+	err := row.Scan(&r.Id)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "rule not found")
+	default:
+		return problem.Internal(c, err)
+	}
+
+	reload(ctx)
+	return c.JSON(http.StatusOK, r)
+}
+
+func DeleteRuleHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	_, err := database.Db.ExecContext(ctx, "DELETE FROM rules WHERE id = $1", c.Param("id"))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	reload(ctx)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package rule
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// where returns SQL conditions that hold for every expense r would change,
+// so that the database skips the rest. Amounts are compared exactly. A
+// pattern only contributes the literal text every match must contain, so
+// the conditions may let through expenses that Match then rejects, but
+// never drop one it accepts. Values are appended to args.
+func (r *Rule) where(args []interface{}) ([]string, []interface{}) {
+	var conds []string
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if r.AmountGte != nil {
+		conds = append(conds, "amount >= "+arg(*r.AmountGte))
+	}
+	if r.AmountLt != nil {
+		conds = append(conds, "amount < "+arg(*r.AmountLt))
+	}
+	for _, p := range []struct {
+		column string
+		re     *regexp.Regexp
+	}{{"title", r.title}, {"COALESCE(note, '')", r.note}} {
+		for _, lit := range requiredLiterals(p.re) {
+			op := " LIKE "
+			if lit.fold {
+				op = " ILIKE "
+			}
+			conds = append(conds, p.column+op+arg("%"+escapeLike(lit.text)+"%"))
+		}
+	}
+
+	// apply changes nothing on expenses that already have every tag and,
+	// if the rule sets one, a category.
+	var changed []string
+	if len(r.AddTags) > 0 {
+		changed = append(changed, "NOT COALESCE(tags, '{}') @> "+arg(pq.Array(r.AddTags))+"::text[]")
+	}
+	if r.CategoryId != nil {
+		changed = append(changed, "category_id IS NULL")
+	}
+	if len(changed) > 0 {
+		conds = append(conds, "("+strings.Join(changed, " OR ")+")")
+	}
+	return conds, args
+}
+
+// literal is text that a match must contain, in any case if fold is set.
+type literal struct {
+	text string
+	fold bool
+}
+
+// requiredLiterals returns the literal text that appears in every match of
+// re. Only ASCII is kept, since Go and PostgreSQL may fold the case of
+// other characters differently.
+func requiredLiterals(re *regexp.Regexp) []literal {
+	if re == nil {
+		return nil
+	}
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+
+	var lits []literal
+	var walk func(*syntax.Regexp)
+	walk = func(n *syntax.Regexp) {
+		switch n.Op {
+		case syntax.OpLiteral:
+			fold := n.Flags&syntax.FoldCase != 0
+			for _, text := range strings.FieldsFunc(string(n.Rune), func(r rune) bool { return r > unicode.MaxASCII }) {
+				if fold {
+					text = strings.ToLower(text)
+				}
+				lits = append(lits, literal{text, fold})
+			}
+		case syntax.OpCapture:
+			walk(n.Sub[0])
+		case syntax.OpConcat:
+			for _, sub := range n.Sub {
+				walk(sub)
+			}
+		}
+	}
+	walk(parsed.Simplify())
+	return lits
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,63 @@
+//go:build unit
+
+package rule
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhere(t *testing.T) {
+	gte, lt, category := 100.0, 500.0, 3
+	r := &Rule{Title: "/star(bucks)? siam/i", Note: `^50%_off\b|refund`, AmountGte: &gte, AmountLt: &lt, AddTags: []string{"coffee"}, CategoryId: &category}
+	if !assert.NoError(t, r.compile()) {
+		return
+	}
+
+	conds, args := r.where([]interface{}{"first"})
+
+	assert.Equal(t, []string{
+		"amount >= $2",
+		"amount < $3",
+		"title ILIKE $4",
+		"title ILIKE $5",
+		"(NOT COALESCE(tags, '{}') @> $6::text[] OR category_id IS NULL)",
+	}, conds)
+	assert.Equal(t, []interface{}{"first", 100.0, 500.0, "%star%", "% siam%", pq.Array([]string{"coffee"})}, args)
+}
+
+func TestWhere_EscapesLiterals(t *testing.T) {
+	r := &Rule{Note: `50%_off`, CategoryId: new(int)}
+	if !assert.NoError(t, r.compile()) {
+		return
+	}
+
+	conds, args := r.where(nil)
+
+	assert.Equal(t, []string{"COALESCE(note, '') LIKE $1", "(category_id IS NULL)"}, conds)
+	assert.Equal(t, []interface{}{`%50\%\_off%`}, args)
+}
+
+func TestRequiredLiterals_SkipsOptionalAndNonASCII(t *testing.T) {
+	for pattern, want := range map[string][]string{
+		"starbucks":      {"starbucks"},
+		"(?i)café latte": {"caf", " latte"},
+		"tea|coffee":     nil,
+		"^grab(food)?$":  {"grab"},
+		"(uber) eats":    {"uber", " eats"},
+		`amazon\.co\.jp`: {"amazon.co.jp"},
+		"[0-9]+ baht":    {" baht"},
+	} {
+		r := &Rule{Title: pattern, AddTags: []string{"x"}}
+		if !assert.NoError(t, r.compile(), pattern) {
+			continue
+		}
+		var got []string
+		for _, lit := range requiredLiterals(r.title) {
+			got = append(got, lit.text)
+		}
+		assert.Equal(t, want, got, pattern)
+	}
+}
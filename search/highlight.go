@@ -0,0 +1,134 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	HighlightStart = "<b>"
+	HighlightEnd   = "</b>"
+
+	// snippetRunes is roughly how much context a snippet keeps around the
+	// first match.
+	snippetRunes = 80
+)
+
+// Snippet returns an excerpt of text around the first match of q with every
+// matching word wrapped in HighlightStart and HighlightEnd. It returns ""
+// when nothing matches.
+func Snippet(text, q string) string {
+	spans := matches(text, q)
+	if len(spans) == 0 {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetRunes {
+		start = backRunes(text, spans[0][0], snippetRunes/4)
+		end = forwardRunes(text, start, snippetRunes)
+		if end < spans[0][1] {
+			end = spans[0][1]
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := start
+	for _, s := range spans {
+		if s[0] < last || s[1] > end {
+			continue
+		}
+		b.WriteString(text[last:s[0]])
+		b.WriteString(HighlightStart + text[s[0]:s[1]] + HighlightEnd)
+		last = s[1]
+	}
+	b.WriteString(text[last:end])
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// Score is the in-memory counterpart of the Postgres ranking: it reports
+// whether title or note contain every token of q and weighs title matches
+// above note matches.
+func Score(title, note, q string) (float64, bool) {
+	have := map[string]float64{}
+	for _, t := range Tokenize(note) {
+		have[t] = 0.4
+	}
+	for _, t := range Tokenize(title) {
+		have[t] = 1
+	}
+
+	tokens := Tokenize(q)
+	if len(tokens) == 0 {
+		return 0, false
+	}
+
+	score := 0.0
+	for _, t := range tokens {
+		w, ok := have[t]
+		if !ok {
+			return 0, false
+		}
+		score += w
+	}
+	return score / float64(len(tokens)), true
+}
+
+// matches returns the byte spans of the words of q in text, case
+// insensitively, sorted and without overlaps.
+func matches(text, q string) [][2]int {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Case folding changed byte offsets; fall back to exact matching.
+		lower = text
+	}
+
+	var spans [][2]int
+	for _, word := range strings.Fields(strings.ToLower(q)) {
+		for i := 0; i < len(lower); {
+			j := strings.Index(lower[i:], word)
+			if j < 0 {
+				break
+			}
+			spans = append(spans, [2]int{i + j, i + j + len(word)})
+			i += j + len(word)
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	merged := [][2]int{}
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s[0] <= merged[n-1][1] {
+			if s[1] > merged[n-1][1] {
+				merged[n-1][1] = s[1]
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
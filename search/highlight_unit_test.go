@@ -0,0 +1,43 @@
+//go:build unit
+
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippet_HighlightsEveryWord(t *testing.T) {
+	s := Snippet("night market promotion discount 10 bath", "Market discount")
+
+	assert.Equal(t, "night <b>market</b> promotion <b>discount</b> 10 bath", s)
+}
+
+func TestSnippet_TrimsLongTextAroundFirstMatch(t *testing.T) {
+	text := strings.Repeat("a ", 100) + "coffee" + strings.Repeat(" b", 100)
+
+	s := Snippet(text, "coffee")
+
+	assert.True(t, strings.HasPrefix(s, "…"))
+	assert.True(t, strings.HasSuffix(s, "…"))
+	assert.Contains(t, s, "<b>coffee</b>")
+}
+
+func TestSnippet_Thai(t *testing.T) {
+	assert.Equal(t, "ซื้อของที่<b>ตลาดนัด</b>", Snippet("ซื้อของที่ตลาดนัด", "ตลาดนัด"))
+	assert.Equal(t, "", Snippet("ซื้อของ", "ตลาด"))
+}
+
+func TestScore_MatchesLikePostgres(t *testing.T) {
+	title, ok := Score("Night market", "", "market")
+	assert.True(t, ok)
+
+	note, ok := Score("Dinner", "at the night market", "market")
+	assert.True(t, ok)
+	assert.Greater(t, title, note)
+
+	_, ok = Score("ชาเย็น", "ที่ตลาด", "ตลาดนัด")
+	assert.False(t, ok)
+}
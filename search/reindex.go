@@ -0,0 +1,42 @@
+package search
+
+import (
+	"context"
+
+	"github.com/umateedev/assessment/database"
+)
+
+// Reindex fills the search vector of expenses written before search existed
+// or by code paths that bypass the expense handlers.
+func Reindex(ctx context.Context) (int, error) {
+	rows, err := database.Db.QueryContext(ctx, "SELECT id, COALESCE(title, ''), COALESCE(note, '') FROM expenses WHERE search IS NULL")
+	if err != nil {
+		return 0, err
+	}
+
+	type doc struct {
+		id          int
+		title, note string
+	}
+	var docs []doc
+	for rows.Next() {
+		d := doc{}
+		if err := rows.Scan(&d.id, &d.title, &d.note); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		docs = append(docs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, d := range docs {
+		_, err := database.Db.ExecContext(ctx, "UPDATE expenses SET search = $1::tsvector WHERE id = $2", Vector(d.title, d.note), d.id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(docs), nil
+}
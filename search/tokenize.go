@@ -0,0 +1,70 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxLexeme is the longest lexeme, in bytes, that Postgres accepts in a
+// tsvector.
+const maxLexeme = 2046
+
+// Tokenize splits text into lowercase search tokens. Words are runs of
+// letters and digits. Thai, Chinese and Japanese have no spaces between
+// words, so their runs are split into overlapping character bigrams
+// instead; a query then matches when all of its bigrams are present, which
+// approximates a substring match without needing a dictionary. Longer
+// words are cut to maxLexeme bytes.
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	bigrams := false
+
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if bigrams && len(word) > 1 {
+			for i := 0; i+1 < len(word); i++ {
+				tokens = append(tokens, string(word[i:i+2]))
+			}
+		} else {
+			tokens = append(tokens, truncate(string(word)))
+		}
+		word = word[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		isBigram := inBigramScript(r)
+		isWord := isBigram || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if !isWord || (len(word) > 0 && isBigram != bigrams) {
+			flush()
+		}
+		if isWord {
+			bigrams = isBigram
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// inBigramScript reports whether r is written without spaces between words:
+// Thai, Han, kana and the kana prolonged sound mark.
+func inBigramScript(r rune) bool {
+	return unicode.In(r, unicode.Thai, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+// truncate cuts word to at most maxLexeme bytes without splitting a rune.
+func truncate(word string) string {
+	if len(word) <= maxLexeme {
+		return word
+	}
+	n := maxLexeme
+	for !utf8.RuneStart(word[n]) {
+		n--
+	}
+	return word[:n]
+}
@@ -0,0 +1,52 @@
+//go:build unit
+
+package search
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize_LatinWords(t *testing.T) {
+	assert.Equal(t, []string{"night", "market", "10", "bath"}, Tokenize("Night-market, 10 Bath!"))
+}
+
+func TestTokenize_ThaiBigrams(t *testing.T) {
+	assert.Equal(t, []string{"ตล", "ลา", "าด", "ชา", "าเ", "เย", "ย็", "็น"}, Tokenize("ตลาด ชาเย็น"))
+}
+
+func TestTokenize_MixedScripts(t *testing.T) {
+	assert.Equal(t, []string{"iphone", "ให", "หม", "ม่"}, Tokenize("iPhoneใหม่"))
+}
+
+func TestVector_WeighsTitleAboveNote(t *testing.T) {
+	assert.Equal(t, "'bath':4B 'it':2B 's':3B 'tea':1A,5B", Vector("Tea", "it's bath tea"))
+}
+
+func TestQuery_RequiresEveryToken(t *testing.T) {
+	assert.Equal(t, "'night' & 'market'", Query("night market"))
+	assert.Equal(t, "", Query("  !! "))
+}
+
+func TestTokenize_CJKBigrams(t *testing.T) {
+	assert.Equal(t, []string{"东京", "京タ", "タワ", "ワー"}, Tokenize("东京タワー"))
+}
+
+func TestTokenize_KeepsLexemesWithinThePostgresLimit(t *testing.T) {
+	for _, text := range []string{
+		strings.Repeat("中文", 350),
+		strings.Repeat("a", 3000),
+		strings.Repeat("ü", 1500),
+	} {
+		tokens := Tokenize(text)
+		if assert.NotEmpty(t, tokens) {
+			for _, tok := range tokens {
+				assert.LessOrEqual(t, len(tok), maxLexeme)
+				assert.True(t, utf8.ValidString(tok))
+			}
+		}
+	}
+}
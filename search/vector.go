@@ -0,0 +1,60 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Vector builds a Postgres tsvector literal from title and note. Tokens are
+// produced by Tokenize rather than a text search configuration so Thai is
+// indexed the same way it is queried. Title tokens carry weight A and note
+// tokens weight B, so title matches rank higher.
+func Vector(title, note string) string {
+	positions := map[string][]string{}
+	pos := 0
+	add := func(text, weight string) {
+		for _, t := range Tokenize(text) {
+			pos++
+			if pos > 16383 {
+				return
+			}
+			positions[t] = append(positions[t], strconv.Itoa(pos)+weight)
+		}
+	}
+	add(title, "A")
+	add(note, "B")
+
+	lexemes := make([]string, 0, len(positions))
+	for t := range positions {
+		lexemes = append(lexemes, t)
+	}
+	sort.Strings(lexemes)
+
+	var b strings.Builder
+	for i, t := range lexemes {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quote(t))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(positions[t], ","))
+	}
+	return b.String()
+}
+
+// Query builds a tsquery literal that matches documents containing every
+// token of q. It returns "" when q has no tokens.
+func Query(q string) string {
+	tokens := Tokenize(q)
+	quoted := make([]string, len(tokens))
+	for i, t := range tokens {
+		quoted[i] = quote(t)
+	}
+	return strings.Join(quoted, " & ")
+}
+
+func quote(lexeme string) string {
+	lexeme = strings.ReplaceAll(lexeme, `\`, `\\`)
+	return "'" + strings.ReplaceAll(lexeme, "'", "''") + "'"
+}
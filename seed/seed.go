@@ -0,0 +1,166 @@
+// Package seed generates realistic fake expenses for demos and load tests.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/search"
+)
+
+// batchSize keeps each INSERT well below Postgres' 65535 parameter limit.
+const batchSize = 500
+
+type Expense struct {
+	Title     string
+	Amount    float64
+	Note      string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+type item struct {
+	title    string
+	tags     []string
+	min, max float64
+	weight   int
+	notes    []string
+}
+
+// catalog lists everyday spending in baht; weight is how often an item
+// appears relative to the others.
+var catalog = []item{
+	{"iced coffee", []string{"beverage"}, 45, 95, 30, []string{"", "", "oat milk", "with a colleague"}},
+	{"bubble tea", []string{"beverage"}, 40, 80, 12, []string{"", "less sugar"}},
+	{"pad thai", []string{"food"}, 50, 120, 18, []string{"", "street stall", "extra shrimp"}},
+	{"khao man gai", []string{"food"}, 45, 70, 15, []string{""}},
+	{"som tam", []string{"food"}, 40, 90, 10, []string{"", "not spicy"}},
+	{"lunch set", []string{"food", "work"}, 80, 180, 20, []string{"", "team lunch"}},
+	{"dinner out", []string{"food", "dinner"}, 250, 1800, 8, []string{"", "birthday", "date night", "night market promotion discount 10 bath"}},
+	{"groceries", []string{"groceries"}, 150, 1500, 14, []string{"", "weekly shop", "Lotus's", "Big C"}},
+	{"7-Eleven", []string{"groceries", "snacks"}, 20, 250, 25, []string{""}},
+	{"BTS fare", []string{"transport"}, 17, 62, 28, []string{""}},
+	{"MRT fare", []string{"transport"}, 17, 45, 12, []string{""}},
+	{"taxi", []string{"transport"}, 60, 350, 10, []string{"", "rain", "airport run"}},
+	{"motorbike taxi", []string{"transport"}, 10, 50, 10, []string{""}},
+	{"fuel", []string{"transport", "car"}, 800, 1800, 4, []string{""}},
+	{"movie tickets", []string{"entertainment"}, 180, 600, 3, []string{"", "IMAX"}},
+	{"concert", []string{"entertainment"}, 1500, 6500, 1, []string{""}},
+	{"gym day pass", []string{"health"}, 150, 300, 3, []string{""}},
+	{"pharmacy", []string{"health"}, 60, 900, 3, []string{"", "cold medicine", "vitamins"}},
+	{"haircut", []string{"personal"}, 150, 600, 2, []string{""}},
+	{"clothes", []string{"shopping"}, 290, 2500, 3, []string{"", "sale"}},
+	{"online order", []string{"shopping"}, 99, 3500, 5, []string{"", "Shopee", "Lazada"}},
+	{"book", []string{"shopping", "education"}, 195, 850, 2, []string{""}},
+	{"gift", []string{"gift"}, 300, 3000, 2, []string{"", "wedding", "birthday"}},
+}
+
+// monthly lists bills that are paid once a month.
+var monthly = []item{
+	{"rent", []string{"housing"}, 12000, 12000, 0, []string{""}},
+	{"electricity", []string{"utilities"}, 800, 2600, 0, []string{""}},
+	{"water", []string{"utilities"}, 90, 250, 0, []string{""}},
+	{"internet", []string{"utilities"}, 599, 599, 0, []string{""}},
+	{"mobile plan", []string{"utilities"}, 399, 399, 0, []string{""}},
+	{"streaming subscription", []string{"entertainment", "subscription"}, 419, 419, 0, []string{""}},
+}
+
+// Generate returns n expenses spread over the given number of months before
+// now, oldest first. The monthly bills of every month are included and count
+// towards n.
+func Generate(r *rand.Rand, n, months int, now time.Time) []Expense {
+	if months < 1 {
+		months = 1
+	}
+	start := now.AddDate(0, -months, 0)
+
+	var expenses []Expense
+	for m := 0; m < months && len(expenses) < n; m++ {
+		due := time.Date(start.Year(), start.Month()+time.Month(m)+1, 1, 9, 0, 0, 0, now.Location())
+		for _, it := range monthly {
+			if len(expenses) == n || due.After(now) {
+				break
+			}
+			expenses = append(expenses, it.expense(r, due.Add(time.Duration(r.Intn(72))*time.Hour)))
+		}
+	}
+
+	total := 0
+	for _, it := range catalog {
+		total += it.weight
+	}
+	span := now.Sub(start)
+	for len(expenses) < n {
+		pick := r.Intn(total)
+		for _, it := range catalog {
+			if pick < it.weight {
+				at := start.Add(time.Duration(r.Int63n(int64(span))))
+				expenses = append(expenses, it.expense(r, at))
+				break
+			}
+			pick -= it.weight
+		}
+	}
+
+	sort.SliceStable(expenses, func(i, j int) bool { return expenses[i].CreatedAt.Before(expenses[j].CreatedAt) })
+	return expenses
+}
+
+func (it item) expense(r *rand.Rand, at time.Time) Expense {
+	amount := it.min + r.Float64()*(it.max-it.min)
+	if r.Intn(4) == 0 {
+		amount = math.Round(amount*4) / 4
+	} else {
+		amount = math.Round(amount)
+	}
+	return Expense{
+		Title:     it.title,
+		Amount:    amount,
+		Note:      it.notes[r.Intn(len(it.notes))],
+		Tags:      append([]string{}, it.tags...),
+		CreatedAt: at,
+	}
+}
+
+// Insert writes expenses in batches within a single transaction.
+func Insert(ctx context.Context, expenses []Expense) error {
+	tx, err := database.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(expenses); start += batchSize {
+		end := start + batchSize
+		if end > len(expenses) {
+			end = len(expenses)
+		}
+
+		var values []string
+		var args []interface{}
+		for _, e := range expenses[start:end] {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d::tsvector)", n+1, n+2, n+3, n+4, n+5, n+6))
+			args = append(args, e.Title, e.Amount, e.Note, pq.Array(e.Tags), e.CreatedAt, search.Vector(e.Title, e.Note))
+		}
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO expenses (title, amount, note, tags, created_at, search) VALUES "+strings.Join(values, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	cache.ExpensesChanged(ctx)
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build unit
+
+package seed
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+var now = time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func TestGenerate_IsRepeatable(t *testing.T) {
+	a := Generate(rand.New(rand.NewSource(1)), 200, 3, now)
+	b := Generate(rand.New(rand.NewSource(1)), 200, 3, now)
+
+	assert.Len(t, a, 200)
+	assert.Equal(t, a, b)
+}
+
+func TestGenerate_SpreadsOverMonthsOldestFirst(t *testing.T) {
+	expenses := Generate(rand.New(rand.NewSource(1)), 300, 3, now)
+
+	start := now.AddDate(0, -3, 0)
+	for i, e := range expenses {
+		assert.False(t, e.CreatedAt.Before(start), e.CreatedAt)
+		assert.False(t, e.CreatedAt.After(now), e.CreatedAt)
+		assert.Greater(t, e.Amount, 0.0)
+		assert.NotEmpty(t, e.Tags)
+		if i > 0 {
+			assert.False(t, e.CreatedAt.Before(expenses[i-1].CreatedAt))
+		}
+	}
+}
+
+func TestGenerate_IncludesMonthlyBills(t *testing.T) {
+	expenses := Generate(rand.New(rand.NewSource(1)), 300, 3, now)
+
+	rent := 0
+	for _, e := range expenses {
+		if e.Title == "rent" {
+			rent++
+			assert.Equal(t, 12000.0, e.Amount)
+		}
+	}
+	assert.Equal(t, 3, rent)
+}
+
+func TestInsert_BatchesRowsInOneTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	expenses := Generate(rand.New(rand.NewSource(1)), batchSize+1, 1, now)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO expenses \\(title, amount, note, tags, created_at, search\\) VALUES").
+		WillReturnResult(sqlmock.NewResult(0, batchSize))
+	mock.ExpectExec("INSERT INTO expenses .* VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6::tsvector\\)$").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = Insert(context.Background(), expenses)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,82 +2,409 @@ package main
 
 import (
 	"context"
-	"crypto/subtle"
-	"database/sql"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	_ "github.com/lib/pq"
+	"github.com/umateedev/assessment/attachment"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/category"
 	"github.com/umateedev/assessment/database"
 	"github.com/umateedev/assessment/expense"
 	"github.com/umateedev/assessment/health"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/logging/levels"
+	"github.com/umateedev/assessment/openapi"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/ratelimit"
+	"github.com/umateedev/assessment/recurring"
+	"github.com/umateedev/assessment/rule"
+	"github.com/umateedev/assessment/search"
+	"github.com/umateedev/assessment/tag"
+	"github.com/umateedev/assessment/telemetry"
+	"github.com/umateedev/assessment/validate"
+	"github.com/umateedev/assessment/view"
+	"github.com/umateedev/assessment/webhook"
 )
 
-var db *sql.DB
+// serve runs the HTTP API and the recurring expense scheduler until SIGINT
+// or SIGTERM. SIGHUP reloads the configuration.
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	loader := configFlags(fs)
+	migrate := fs.Bool("migrate", true, "create missing tables before serving")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := loader.load()
+	if err != nil {
+		return err
+	}
+	cfg.Log.apply()
+	log.Info("Config loaded", "config", cfg.String())
 
-func main() {
+	if err := connect(cfg, *migrate); err != nil {
+		return err
+	}
+
+	stopTracing, err := telemetry.Setup(context.Background(), cfg.Tracing.telemetry())
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
 
 	e := echo.New()
-	e.Logger.SetLevel(log.INFO)
+	e.HideBanner = true
+	e.HidePort = true
+	e.Server.ReadTimeout = cfg.Timeouts.Read
+	e.Server.WriteTimeout = cfg.Timeouts.Write
+	e.Server.IdleTimeout = cfg.Timeouts.Idle
 
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	e.HTTPErrorHandler = problem.ErrorHandler
+	e.IPExtractor, _ = cfg.ipExtractor() // checked by validate
 
-	database.InitDb()
+	e.Use(telemetry.Middleware())
+	e.Use(middleware.RequestID())
+	e.Use(logging.Middleware())
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableStackAll: true,
+		LogErrorFunc:    logPanic,
+	}))
 
-	port := os.Getenv("PORT")
-	log.Printf("PORT is %s", port)
+	if err := attachment.InitStorage(cfg.Attachments.attachment()); err != nil {
+		return err
+	}
+	if err := tag.InitNormalizer(cfg.Tags.Trim, cfg.Tags.FoldCase); err != nil {
+		return fmt.Errorf("cannot load tag aliases: %w", err)
+	}
+	if err := rule.LoadRules(context.Background()); err != nil {
+		return fmt.Errorf("cannot load rules: %w", err)
+	}
+	if cfg.Features.ReindexOnStart {
+		if n, err := search.Reindex(context.Background()); err != nil {
+			return fmt.Errorf("cannot build search index: %w", err)
+		} else if n > 0 {
+			log.Info("Indexed expenses for search", "count", n)
+		}
+	}
 
-	if len(port) == 0 {
-		port = ":2565"
+	store, err := cfg.RateLimit.store()
+	if err != nil {
+		return err
+	}
+	limiter := ratelimit.New(store, ratelimit.Limits{})
+	closeCache, err := cfg.Cache.setup()
+	if err != nil {
+		return err
 	}
 
-	e.GET("/", landingPage)
-	e.GET("/health", health.HealthCheck)
+	var cors, validator hotMiddleware
+	apply := func(cfg config) {
+		cfg.Log.apply()
+		auth.SetAdmins(cfg.Auth.Admins)
+		fieldLimits, workspaces, _ := cfg.Validation.limits() // checked by validate
+		validate.SetLimits(fieldLimits, workspaces)
+		validate.SetBaseCurrency(cfg.Currency.Base)
+		rates, _ := cfg.Currency.rates() // checked by validate
+		view.SetCurrencies(cfg.Currency.Base, rates)
+		cors.Set(corsMiddleware(cfg.CORS))
+		limits, _ := cfg.RateLimit.limits() // checked by validate
+		limiter.SetLimits(limits)
+		v, _ := openapi.Validator(cfg.OpenAPIValidation) // checked by validate
+		validator.Set(v)
+	}
+	apply(cfg)
+	e.Use(cors.Handle)
 
-	g := e.Group("expenses", middleware.BasicAuth(basicAuth))
-	g.POST("", expense.CreateExpenseHandler)
-	g.GET("/:id", expense.GetExpenseByIdHandler)
-	g.PUT("/:id", expense.UpdateExpenseHandler)
-	g.GET("", expense.GetAllExpenseHandler)
+	routes(e, authMiddleware(cfg.Auth), limiter.Middleware, validator.Handle)
 
-	log.Printf("Server start at port %s", port)
+	var scheduler *recurring.Scheduler
+	if cfg.Features.Scheduler {
+		scheduler = recurring.NewScheduler(time.Minute)
+		scheduler.Start()
+	}
+	pub, err := cfg.Outbox.publisher()
+	if err != nil {
+		return fmt.Errorf("cannot open outbox publisher: %w", err)
+	}
+	relay := outbox.NewRelay(pub, cfg.Outbox.Interval, cfg.Outbox.Retention)
+	relay.Start()
+	feed := outbox.NewFeed(cfg.Outbox.ReplaySize, cfg.Outbox.Interval)
+	feed.Start()
+	expense.SetFeed(feed)
+	// Event streams never end on their own; stopping the feed ends them
+	// so that the shutdown below does not wait for them.
+	e.Server.RegisterOnShutdown(feed.Stop)
+	var dispatcher *webhook.Dispatcher
+	if cfg.Features.Webhooks {
+		dispatcher = webhook.NewDispatcher(5 * time.Second)
+		dispatcher.Start()
+	}
+
+	log.Info("Server started", "listen", cfg.Listen)
 
 	go func() {
-		if err := e.Start(port); err != nil && err != http.ErrServerClosed {
-			e.Logger.Fatal("Shutting down server")
+		if err := e.Start(cfg.Listen); err != nil && err != http.ErrServerClosed {
+			log.Error("Server failed", "error", err)
+			os.Exit(1)
 		}
 	}()
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-	<-shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Other replicas change tag aliases and rules too; reading them again
+	// now and then picks those changes up.
+	refresh := time.NewTicker(cfg.RefreshInterval)
+	defer refresh.Stop()
+	for running := true; running; {
+		select {
+		case <-refresh.C:
+			refreshCaches()
+		case <-hup:
+			next, err := loader.load()
+			if err != nil {
+				log.Error("Config not reloaded", "error", err)
+				continue
+			}
+			var restart []string
+			cfg, restart = cfg.reload(next)
+			if len(restart) > 0 {
+				log.Warn("Restart to apply config changes", "fields", restart)
+			}
+			apply(cfg)
+			log.Info("Config reloaded")
+		case <-shutdown:
+			running = false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
 	defer cancel()
 	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Fatal((err))
+		return err
+	}
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+	feed.Stop()
+	relay.Stop()
+	if err := pub.Close(); err != nil {
+		log.Error("Cannot close outbox publisher", "error", err)
+	}
+	if c, ok := store.(interface{ Close() }); ok {
+		c.Close()
+	}
+	closeCache()
+	if err := stopTracing(ctx); err != nil {
+		log.Error("Cannot flush traces", "error", err)
+	}
+
+	log.Info("Server stopped")
+	return nil
+}
+
+// refreshCaches reads again what is kept in memory from the database.
+func refreshCaches() {
+	if err := tag.LoadAliases(context.Background()); err != nil {
+		log.Error("Cannot reload tag aliases", "error", err)
+	}
+	if err := rule.LoadRules(context.Background()); err != nil {
+		log.Error("Cannot reload rules", "error", err)
+	}
+}
+
+// routes registers every endpoint. Everything but the landing page, health
+// check and docs is behind the per-IP limit of ratelimit.AuthGroup, authn,
+// the rate limit of the group, for /admin a check for one of the configured
+// admins, and only then the body limit and validator, so that neither reads
+// requests that will be turned away. Keep openapi/openapi.json in step with
+// it.
+func routes(e *echo.Echo, authn echo.MiddlewareFunc, limit func(group string) echo.MiddlewareFunc, validator echo.MiddlewareFunc) {
+	e.GET("/", landingPage)
+	e.GET("/health", health.HealthCheck)
+	e.GET("/openapi.json", openapi.SpecHandler)
+	e.GET("/docs", openapi.DocsHandler)
+	e.GET("/docs/redoc.standalone.js", openapi.RedocHandler)
+
+	guard := func(group string, mw ...echo.MiddlewareFunc) []echo.MiddlewareFunc {
+		mw = append([]echo.MiddlewareFunc{limit(ratelimit.AuthGroup), authn, limit(group)}, mw...)
+		return append(mw, bodyLimit, validator)
 	}
 
-	log.Printf("Server stopped")
+	g := e.Group("expenses", guard("expenses")...)
+	g.POST("", expense.CreateExpenseHandler)
+	g.GET("/:id", expense.GetExpenseByIdHandler)
+	g.PUT("/:id", expense.UpdateExpenseHandler)
+	g.DELETE("/:id", expense.DeleteExpenseHandler)
+	g.GET("", expense.GetAllExpenseHandler)
+	g.GET("/search", expense.SearchExpenseHandler)
+	g.GET("/stream", expense.StreamExpenseHandler)
+	g.POST("/:id/attachments", attachment.UploadAttachmentHandler)
+	g.GET("/:id/attachments", attachment.GetAllAttachmentHandler)
+	g.GET("/:id/attachments/:attachmentId", attachment.DownloadAttachmentHandler)
+	g.GET("/:id/attachments/:attachmentId/thumbnail", attachment.ThumbnailHandler)
+	g.DELETE("/:id/attachments/:attachmentId", attachment.DeleteAttachmentHandler)
+
+	r := e.Group("recurring", guard("recurring")...)
+	r.POST("", recurring.CreateRecurringHandler)
+	r.GET("", recurring.GetAllRecurringHandler)
+	r.GET("/:id", recurring.GetRecurringByIdHandler)
+	r.GET("/:id/occurrences", recurring.PreviewRecurringHandler)
+	r.POST("/:id/skip", recurring.SkipOccurrenceHandler)
+	r.POST("/:id/override", recurring.OverrideOccurrenceHandler)
+
+	t := e.Group("tags", guard("tags")...)
+	t.GET("", tag.GetAllTagHandler)
+	t.POST("/rename", tag.RenameTagHandler)
+	t.POST("/merge", tag.MergeTagHandler)
+	t.GET("/aliases", tag.GetAllAliasHandler)
+	t.PUT("/aliases", tag.PutAliasHandler)
+	t.DELETE("/aliases/:alias", tag.DeleteAliasHandler)
+
+	cg := e.Group("categories", guard("categories")...)
+	cg.POST("", category.CreateCategoryHandler)
+	cg.GET("", category.GetAllCategoryHandler)
+	cg.GET("/summary", category.GetAllSummaryHandler)
+	cg.GET("/:id", category.GetCategoryByIdHandler)
+	cg.GET("/:id/summary", category.GetSummaryByIdHandler)
+	cg.PUT("/:id", category.UpdateCategoryHandler)
+	cg.DELETE("/:id", category.DeleteCategoryHandler)
+	cg.GET("/:id/budget", category.GetBudgetHandler)
+	cg.PUT("/:id/budget", category.PutBudgetHandler)
+	cg.DELETE("/:id/budget", category.DeleteBudgetHandler)
+
+	rg := e.Group("rules", guard("rules")...)
+	rg.POST("", rule.CreateRuleHandler)
+	rg.GET("", rule.GetAllRuleHandler)
+	rg.POST("/dry-run", rule.DryRunNewRuleHandler)
+	rg.GET("/:id", rule.GetRuleByIdHandler)
+	rg.PUT("/:id", rule.UpdateRuleHandler)
+	rg.DELETE("/:id", rule.DeleteRuleHandler)
+	rg.POST("/:id/dry-run", rule.DryRunRuleHandler)
+	rg.POST("/:id/apply", rule.ApplyRuleHandler)
+
+	vg := e.Group("views", guard("views")...)
+	vg.POST("", view.CreateViewHandler)
+	vg.GET("", view.GetAllViewHandler)
+	vg.GET("/:id", view.GetViewByIdHandler)
+	vg.PUT("/:id", view.UpdateViewHandler)
+	vg.DELETE("/:id", view.DeleteViewHandler)
+	vg.GET("/:id/expenses", expense.GetViewExpensesHandler)
+
+	wg := e.Group("webhooks", guard("webhooks")...)
+	wg.POST("", webhook.CreateWebhookHandler)
+	wg.GET("", webhook.GetAllWebhookHandler)
+	wg.GET("/dead-letters", webhook.GetDeadLettersHandler)
+	wg.GET("/:id", webhook.GetWebhookByIdHandler)
+	wg.DELETE("/:id", webhook.DeleteWebhookHandler)
+	wg.GET("/:id/deliveries", webhook.GetDeliveriesHandler)
+	wg.POST("/:id/deliveries/:deliveryId/redeliver", webhook.RedeliverHandler)
+
+	ag := e.Group("admin", guard("admin", auth.RequireAdmin)...)
+	ag.GET("/cache", cache.StatsHandler)
+	ag.GET("/log-levels", levels.GetLevelsHandler)
+	ag.PUT("/log-levels/:package", levels.PutLevelHandler)
+	ag.DELETE("/log-levels/:package", levels.DeleteLevelHandler)
 }
 
 func landingPage(c echo.Context) error {
 	return c.String(http.StatusOK, "Welcome to Expenses API")
 }
 
+// logPanic logs a recovered panic with the stack of the panicking goroutine.
+func logPanic(c echo.Context, err error, stack []byte) error {
+	log.ErrorContext(c.Request().Context(), "Recovered from panic", "error", err, "stack", string(stack))
+	return err
+}
+
+// bodyLimit rejects request bodies over 1 MB with 413. Attachment uploads,
+// the only multipart requests, are bounded by attachment.MaxSize instead.
+var bodyLimit = middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+	Limit: "1M",
+	Skipper: func(c echo.Context) bool {
+		return strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm)
+	},
+})
+
+// hotMiddleware is middleware that can be swapped while serving.
+type hotMiddleware struct {
+	mw atomic.Pointer[echo.MiddlewareFunc]
+}
+
+func (h *hotMiddleware) Set(mw echo.MiddlewareFunc) {
+	h.mw.Store(&mw)
+}
+
+func (h *hotMiddleware) Handle(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return (*h.mw.Load())(next)(c)
+	}
+}
+
+func passThrough(next echo.HandlerFunc) echo.HandlerFunc {
+	return next
+}
+
+func corsMiddleware(cfg corsConfig) echo.MiddlewareFunc {
+	if len(cfg.AllowOrigins) == 0 {
+		return passThrough
+	}
+	return middleware.CORSWithConfig(middleware.CORSConfig{AllowOrigins: cfg.AllowOrigins})
+}
+
+// authMiddleware accepts an API key in auth.APIKeyHeader or, without one,
+// basic authentication.
+func authMiddleware(cfg authConfig) echo.MiddlewareFunc {
+	if cfg.Mode == authNone {
+		return passThrough
+	}
+	basic := middleware.BasicAuth(basicAuth)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withBasic := basic(next)
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(auth.APIKeyHeader)
+			if len(key) == 0 {
+				return withBasic(c)
+			}
+
+			ctx, cancel := database.Context(c.Request().Context())
+			defer cancel()
+			username, id, ok, err := auth.VerifyAPIKey(ctx, key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+			auth.SetUser(c, username)
+			auth.SetAPIKey(c, id)
+			return next(c)
+		}
+	}
+}
+
 func basicAuth(username, password string, c echo.Context) (bool, error) {
-	// Be careful to use constant time comparison to prevent timing attacks
-	log.Printf(username)
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
 
-	if subtle.ConstantTimeCompare([]byte(username), []byte("November 10")) == 1 &&
-		subtle.ConstantTimeCompare([]byte(password), []byte("2009")) == 1 {
-		return true, nil
+	ok, err := auth.Verify(ctx, username, password)
+	if ok {
+		auth.SetUser(c, username)
 	}
-	return false, nil
+	return ok, err
 }
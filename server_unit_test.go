@@ -0,0 +1,135 @@
+//go:build unit
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/openapi"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/ratelimit"
+)
+
+func TestRoutes_MatchOpenAPI(t *testing.T) {
+	e := echo.New()
+	routes(e, passThrough, func(string) echo.MiddlewareFunc { return passThrough }, passThrough)
+
+	var registered []openapi.Route
+	for _, r := range e.Routes() {
+		// Groups with middleware add catch-all routes that only return 404.
+		if strings.HasPrefix(r.Name, "github.com/labstack/echo/v4.") {
+			continue
+		}
+		path := r.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		registered = append(registered, openapi.Route{Method: r.Method, Path: path})
+	}
+	sort.Slice(registered, func(i, j int) bool {
+		if registered[i].Path != registered[j].Path {
+			return registered[i].Path < registered[j].Path
+		}
+		return registered[i].Method < registered[j].Method
+	})
+
+	assert.Equal(t, openapi.Routes(), registered)
+}
+
+func TestRoutes_ThrottleBadCredentialsPerIP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.Db = db
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT password_hash FROM users").WithArgs("mallory").
+			WillReturnRows(sqlmock.NewRows([]string{"password_hash"}))
+		mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	}
+
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Limits{
+		Groups: map[string]ratelimit.Limit{ratelimit.AuthGroup: {Rate: 0.001, Burst: 2}},
+	})
+	e := echo.New()
+	e.IPExtractor, _ = config{}.ipExtractor()
+	routes(e, authMiddleware(authConfig{Mode: authBasic}), limiter.Middleware, passThrough)
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/expenses", nil)
+		req.SetBasicAuth("mallory", "guess")
+		// A new forwarded address on every guess does not make a new bucket.
+		req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("203.0.113.%d", i))
+		req.Header.Set(echo.HeaderXRealIP, fmt.Sprintf("198.51.100.%d", i))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	// The third guess is rejected before its password is checked.
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoutes_AdminRequiresConfiguredAdmin(t *testing.T) {
+	auth.SetAdmins([]string{"root"})
+	defer auth.SetAdmins(nil)
+	e := echo.New()
+	e.HTTPErrorHandler = problem.ErrorHandler
+	asUser := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, c.Request().Header.Get("X-User"))
+			return next(c)
+		}
+	}
+	routes(e, asUser, func(string) echo.MiddlewareFunc { return passThrough }, passThrough)
+
+	for user, want := range map[string]int{"": http.StatusForbidden, "alice": http.StatusForbidden, "root": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/log-levels", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, user)
+	}
+}
+
+func TestRoutes_ValidateOnlyAuthenticatedBoundedBodies(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.ErrorHandler
+	deny := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("X-User") == "" {
+				return problem.Write(c, problem.New(c, http.StatusUnauthorized, problem.CodeUnauthorized, ""))
+			}
+			return next(c)
+		}
+	}
+	validator, err := openapi.Validator(openapi.Requests)
+	assert.NoError(t, err)
+	routes(e, deny, func(string) echo.MiddlewareFunc { return passThrough }, validator)
+
+	post := func(user, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("", `{"title": 1}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, post("alice", `{"title": 1}`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("alice", `{"note": "`+strings.Repeat("a", 2<<20)+`"}`))
+}
@@ -0,0 +1,85 @@
+package tag
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func GetAllAliasHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, "SELECT alias, tag FROM tag_aliases ORDER BY alias")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	aliases := []Alias{}
+	for rows.Next() {
+		a := Alias{}
+		if err := rows.Scan(&a.Alias, &a.Tag); err != nil {
+			return problem.Internal(c, err)
+		}
+		aliases = append(aliases, a)
+	}
+
+	return c.JSON(http.StatusOK, aliases)
+}
+
+// PutAliasHandler maps an alias to a tag. Existing expenses are not
+// rewritten; use the rename endpoint for that.
+func PutAliasHandler(c echo.Context) error {
+	a := Alias{}
+	err := c.Bind(&a)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	a.Alias = normalizer.clean(a.Alias)
+	a.Tag = normalizer.clean(a.Tag)
+	var errs []problem.FieldError
+	if len(a.Alias) == 0 {
+		errs = append(errs, problem.FieldError{Field: "alias", Message: "is required"})
+	}
+	if len(a.Tag) == 0 {
+		errs = append(errs, problem.FieldError{Field: "tag", Message: "is required"})
+	} else if a.Alias == a.Tag {
+		errs = append(errs, problem.FieldError{Field: "tag", Message: "must differ from alias"})
+	}
+	if len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	_, err = database.Db.ExecContext(ctx, "INSERT INTO tag_aliases (alias, tag) VALUES ($1, $2) ON CONFLICT (alias) DO UPDATE SET tag = EXCLUDED.tag", a.Alias, a.Tag)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	if err := LoadAliases(ctx); err != nil {
+		log.ErrorContext(ctx, "Reload tag aliases failed", "error", err)
+	}
+	return c.JSON(http.StatusOK, a)
+}
+
+func DeleteAliasHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	_, err := database.Db.ExecContext(ctx, "DELETE FROM tag_aliases WHERE alias = $1", c.Param("alias"))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	if err := LoadAliases(ctx); err != nil {
+		log.ErrorContext(ctx, "Reload tag aliases failed", "error", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,32 @@
+package tag
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// GetAllTagHandler lists every tag in use with the number of expenses carrying it.
+func GetAllTagHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, "SELECT t, count(*) FROM expenses, unnest(tags) AS t GROUP BY t ORDER BY count(*) DESC, t")
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		t := Tag{}
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return problem.Internal(c, err)
+		}
+		tags = append(tags, t)
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
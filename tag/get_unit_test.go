@@ -0,0 +1,40 @@
+//go:build unit
+
+package tag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestGetAllTag_ReturnSuccess(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectQuery("SELECT t, count\\(\\*\\) FROM expenses, unnest\\(tags\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"t", "count"}).AddRow("food", 3).AddRow("gadget", 1))
+
+	err = GetAllTagHandler(c)
+
+	expected := "[{\"name\":\"food\",\"count\":3},{\"name\":\"gadget\",\"count\":1}]"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+	}
+}
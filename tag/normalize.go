@@ -0,0 +1,105 @@
+package tag
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/umateedev/assessment/database"
+)
+
+// Normalizer cleans up tags before they are stored.
+type Normalizer struct {
+	Trim     bool
+	FoldCase bool
+
+	mu      sync.RWMutex
+	aliases map[string]string
+}
+
+var normalizer = &Normalizer{Trim: true}
+
+// InitNormalizer sets whether tags are trimmed and case folded and loads
+// the alias table.
+func InitNormalizer(trim, foldCase bool) error {
+	normalizer.Trim = trim
+	normalizer.FoldCase = foldCase
+	return LoadAliases(context.Background())
+}
+
+// LoadAliases replaces the in-memory aliases with the tag_aliases table.
+func LoadAliases(ctx context.Context) error {
+	rows, err := database.Db.QueryContext(ctx, "SELECT alias, tag FROM tag_aliases")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	aliases := map[string]string{}
+	for rows.Next() {
+		a := Alias{}
+		if err := rows.Scan(&a.Alias, &a.Tag); err != nil {
+			return err
+		}
+		aliases[normalizer.clean(a.Alias)] = a.Tag
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	normalizer.setAliases(aliases)
+	return nil
+}
+
+func (n *Normalizer) setAliases(aliases map[string]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.aliases = aliases
+}
+
+func (n *Normalizer) clean(t string) string {
+	if n.Trim {
+		t = strings.Join(strings.Fields(t), " ")
+	}
+	if n.FoldCase {
+		t = strings.ToLower(t)
+	}
+	return t
+}
+
+// Normalize applies trimming, case folding and aliases to a single tag.
+func (n *Normalizer) Normalize(t string) string {
+	t = n.clean(t)
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if to, ok := n.aliases[t]; ok {
+		return n.clean(to)
+	}
+	return t
+}
+
+// NormalizeAll normalizes tags, dropping empty tags and duplicates while
+// keeping the original order.
+func (n *Normalizer) NormalizeAll(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	result := []string{}
+	for _, t := range tags {
+		t = n.Normalize(t)
+		if len(t) == 0 || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// Normalize is used by the expense handlers on create and update.
+func Normalize(tags []string) []string {
+	return normalizer.NormalizeAll(tags)
+}
@@ -0,0 +1,32 @@
+//go:build unit
+
+package tag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize_TrimsFoldsAndDedups(t *testing.T) {
+	n := &Normalizer{Trim: true, FoldCase: true}
+	n.setAliases(map[string]string{"foods": "food", "cafe": "Coffee"})
+
+	tags := n.NormalizeAll([]string{" Food ", "food", "FOODS", "", "Night  Market", "cafe"})
+
+	assert.Equal(t, []string{"food", "night market", "coffee"}, tags)
+}
+
+func TestNormalize_KeepsCase_WhenFoldingDisabled(t *testing.T) {
+	n := &Normalizer{Trim: true}
+
+	tags := n.NormalizeAll([]string{"Food", "food"})
+
+	assert.Equal(t, []string{"Food", "food"}, tags)
+}
+
+func TestNormalize_KeepsNil(t *testing.T) {
+	n := &Normalizer{Trim: true}
+
+	assert.Nil(t, n.NormalizeAll(nil))
+}
@@ -0,0 +1,93 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/cache"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/outbox"
+	"github.com/umateedev/assessment/problem"
+	"github.com/umateedev/assessment/webhook"
+)
+
+// replaceTag renames from to to on every expense, dropping the duplicate when
+// an expense already carries to and keeping the order of the other tags.
+const replaceTag = `UPDATE expenses SET tags = ARRAY(
+	SELECT t FROM unnest(array_replace(tags, $1, $2)) WITH ORDINALITY AS u(t, n)
+	GROUP BY t ORDER BY min(n)
+) WHERE $1 = ANY(tags)`
+
+func RenameTagHandler(c echo.Context) error {
+	r := RenameRequest{}
+	err := c.Bind(&r)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	return rewrite(c, []string{r.From}, r.To)
+}
+
+func MergeTagHandler(c echo.Context) error {
+	r := MergeRequest{}
+	err := c.Bind(&r)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	return rewrite(c, r.From, r.To)
+}
+
+// rewrite replaces every tag in from with to in a single transaction.
+func rewrite(c echo.Context, from []string, to string) error {
+	to = normalizer.Normalize(to)
+	var errs []problem.FieldError
+	if len(from) == 0 {
+		errs = append(errs, problem.FieldError{Field: "from", Message: "is required"})
+	}
+	if len(to) == 0 {
+		errs = append(errs, problem.FieldError{Field: "to", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	result := Result{}
+	err := database.InTx(ctx, func(tx *sql.Tx) error {
+		result = Result{}
+		for _, f := range from {
+			if f == to {
+				continue
+			}
+			n, err := exec(ctx, tx, f, to)
+			if err != nil {
+				return err
+			}
+			result.Updated += n
+		}
+		return nil
+	})
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	if result.Updated > 0 {
+		cache.ExpensesChanged(ctx)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func exec(ctx context.Context, tx *sql.Tx, from, to string) (int64, error) {
+	changed, err := outbox.AddFrom(ctx, tx, outbox.ExpenseUpdated, replaceTag+outbox.Returning, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(changed)), webhook.EnqueueChanged(ctx, tx, webhook.ExpenseUpdated, changed)
+}
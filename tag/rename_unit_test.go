@@ -0,0 +1,94 @@
+//go:build unit
+
+package tag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+// changedColumns are the columns outbox.AddFrom reads back.
+var changedColumns = []string{"id", "owner", "amount", "category_id", "data"}
+
+func TestRenameTag_ReturnUnprocessableEntity_WhenMissingTo(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tags/rename", strings.NewReader(`{"from": "Food"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := RenameTagHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestMergeTag_RewritesAllInOneTransaction(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tags/merge", strings.NewReader(`{"from": ["Food", "foods", "food"], "to": "food"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").WithArgs("Food", "food", "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(1, "alice", 50.0, nil, `{"id":1}`).AddRow(2, "bob", 80.0, nil, `{"id":2}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "bob").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").WithArgs("foods", "food", "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(3, "", 20.0, nil, `{"id":3}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WithArgs(sqlmock.AnyArg(), "expense.updated", sqlmock.AnyArg(), "").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = MergeTagHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "{\"updated\":3}", strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestMergeTag_RollsBack_WhenUpdateFails(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tags/merge", strings.NewReader(`{"from": ["Food", "foods"], "to": "food"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").WithArgs("Food", "food", "expense.updated").
+		WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(1, "", 50.0, nil, `{"id":1}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE expenses SET tags (.+)INSERT INTO outbox").WithArgs("foods", "food", "expense.updated").WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	err = MergeTagHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
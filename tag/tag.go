@@ -0,0 +1,29 @@
+package tag
+
+import "github.com/umateedev/assessment/logging"
+
+var log = logging.For("tag")
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type Alias struct {
+	Alias string `json:"alias"`
+	Tag   string `json:"tag"`
+}
+
+type RenameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type MergeRequest struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+}
+
+type Result struct {
+	Updated int64 `json:"updated"`
+}
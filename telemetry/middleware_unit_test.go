@@ -0,0 +1,84 @@
+//go:build unit
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	_, err := Setup(context.Background(), Config{Exporter: Off})
+	assert.NoError(t, err)
+	return sr
+}
+
+func serve(req *http.Request, h echo.HandlerFunc) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/expenses/:id", h)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	sr := record(t)
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	var traceID string
+	serve(req, func(c echo.Context) error {
+		traceID = TraceID(c.Request().Context())
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	spans := sr.Ended()
+	if assert.Len(t, spans, 1) {
+		s := spans[0]
+		assert.Equal(t, "GET /expenses/:id", s.Name())
+		assert.Equal(t, "00f067aa0ba902b7", s.Parent().SpanID().String())
+		assert.Contains(t, s.Attributes(), attribute.Int("http.status_code", http.StatusNoContent))
+	}
+}
+
+func TestMiddleware_MarksServerErrors(t *testing.T) {
+	sr := record(t)
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+
+	rec := serve(req, func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	spans := sr.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.False(t, spans[0].Parent().IsValid())
+		assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", http.StatusInternalServerError))
+	}
+}
+
+func TestSetup_RejectsUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+
+	assert.Error(t, err)
+}
+
+func TestTraceID_OutsideTrace(t *testing.T) {
+	assert.Equal(t, "", TraceID(context.Background()))
+}
@@ -0,0 +1,349 @@
+// Package validate checks request structs against rules declared in
+// `validate` struct tags:
+//
+//	required     strings must not be blank, slices must not be empty and
+//	             pointers must not be nil
+//	max=N        at most N characters, N items or a value of N
+//	min=N        at least N characters, N items or a value of N
+//	gt=N         a number strictly greater than N
+//	scale        a number with no more decimals than the base currency has
+//	each=r1|r2   applies r1 and r2 to every element of a slice
+//
+// N is either a number or the name of a limit, as in max=title. Named limits
+// default to the values in defaults. Limits.Tighten lowers them, and
+// SetLimits installs the result for everyone and for single workspaces,
+// which StructFor applies. Tags are parsed once per type.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/umateedev/assessment/problem"
+)
+
+// Limits holds the value of every named limit.
+type Limits map[string]float64
+
+// DefaultLimits returns the limits before any are tightened.
+func DefaultLimits() Limits {
+	l := make(Limits, len(defaults))
+	for k, v := range defaults {
+		l[k] = v
+	}
+	return l
+}
+
+// Tighten returns a copy of l with the overrides, each "name=value",
+// applied. It fails on unknown names, values that are not numbers and
+// values above the limit they override, since limits cannot be relaxed.
+func (l Limits) Tighten(overrides []string) (Limits, error) {
+	res := make(Limits, len(l))
+	for k, v := range l {
+		res[k] = v
+	}
+	for _, o := range overrides {
+		name, value, ok := strings.Cut(o, "=")
+		name = strings.TrimSpace(name)
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || n < 0 || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("%q is not name=value", o)
+		}
+		current, ok := res[name]
+		if !ok {
+			return nil, fmt.Errorf("%q: unknown limit %q", o, name)
+		}
+		if n > current {
+			return nil, fmt.Errorf("%q: %s can only be lowered from %g", o, name, current)
+		}
+		res[name] = n
+	}
+	return res, nil
+}
+
+type settings struct {
+	limits     Limits
+	workspaces map[string]Limits
+	currency   string
+	digits     int
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	current.Store(&settings{limits: DefaultLimits(), currency: "THB", digits: 2})
+}
+
+// SetLimits replaces the limits for everyone and those of single
+// workspaces, which StructFor uses instead.
+func SetLimits(limits Limits, workspaces map[string]Limits) {
+	s := *current.Load()
+	s.limits, s.workspaces = limits, workspaces
+	current.Store(&s)
+}
+
+// SetBaseCurrency sets the currency amounts are stored in, THB if empty.
+func SetBaseCurrency(code string) {
+	s := *current.Load()
+	s.currency = strings.ToUpper(code)
+	if len(s.currency) == 0 {
+		s.currency = "THB"
+	}
+	s.digits = Digits(s.currency)
+	current.Store(&s)
+}
+
+// Digits returns how many decimal places amounts in the currency with the
+// upper case code have, which is 2 unless its minor unit differs.
+func Digits(code string) int {
+	if digits, ok := scales[code]; ok {
+		return digits
+	}
+	return 2
+}
+
+var defaults = map[string]float64{
+	"title": 200,
+	"note":  2000,
+	"tags":  20,
+	"tag":   50,
+}
+
+// scales lists currencies whose minor unit is not 1/100.
+var scales = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// Struct validates the exported fields of the struct v points to and returns
+// one error per failed rule, named by the field's JSON name.
+func Struct(v interface{}) []problem.FieldError {
+	return StructFor("", v)
+}
+
+// StructFor is Struct with the limits set for workspace, if it has any.
+func StructFor(workspace string, v interface{}) []problem.FieldError {
+	s := current.Load()
+	limits := s.limits
+	if l, ok := s.workspaces[workspace]; ok && len(workspace) > 0 {
+		limits = l
+	}
+	c := checker{limits: limits, currency: s.currency, digits: s.digits}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var errs []problem.FieldError
+	for _, f := range fieldsOf(rv.Type()) {
+		for _, msg := range c.check(rv.Field(f.index), f.rules) {
+			errs = append(errs, problem.FieldError{Field: f.name, Message: msg})
+		}
+	}
+	return errs
+}
+
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+// rule is one parsed rule. Its argument is either the number n or the
+// name of a limit.
+type rule struct {
+	name  string
+	n     float64
+	limit string
+	each  []rule
+}
+
+var fields sync.Map // reflect.Type to []field
+
+func fieldsOf(rt reflect.Type) []field {
+	if fs, ok := fields.Load(rt); ok {
+		return fs.([]field)
+	}
+	var fs []field
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("validate")
+		if !ok || !f.IsExported() {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if len(name) == 0 {
+			name = f.Name
+		}
+		fs = append(fs, field{index: i, name: name, rules: parseRules(strings.Split(tag, ","))})
+	}
+	fields.Store(rt, fs)
+	return fs
+}
+
+func parseRules(tags []string) []rule {
+	rules := make([]rule, 0, len(tags))
+	for _, t := range tags {
+		name, arg, _ := strings.Cut(t, "=")
+		r := rule{name: name}
+		switch name {
+		case "required", "scale":
+		case "each":
+			r.each = parseRules(strings.Split(arg, "|"))
+		case "max", "min", "gt":
+			if n, err := strconv.ParseFloat(arg, 64); err == nil {
+				r.n = n
+			} else if _, ok := defaults[arg]; ok {
+				r.limit = arg
+			} else {
+				panic("validate: unknown limit " + arg)
+			}
+		default:
+			panic("validate: unknown rule " + name)
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// checker applies rules with one set of limits.
+type checker struct {
+	limits   Limits
+	currency string
+	digits   int
+}
+
+func (c checker) check(v reflect.Value, rules []rule) []string {
+	present := false
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			for _, r := range rules {
+				if r.name == "required" {
+					return []string{"is required"}
+				}
+			}
+			return nil
+		}
+		v, present = v.Elem(), true
+	}
+
+	var msgs []string
+	for _, r := range rules {
+		if r.name == "required" && present {
+			continue
+		}
+		if r.name == "each" {
+			msgs = append(msgs, c.each(v, r.each)...)
+			continue
+		}
+		if msg := c.apply(v, r); len(msg) > 0 {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (c checker) each(v reflect.Value, rules []rule) []string {
+	if v.Kind() != reflect.Slice {
+		panic("validate: each on non-slice")
+	}
+	var msgs []string
+	for i := 0; i < v.Len(); i++ {
+		for _, msg := range c.check(v.Index(i), rules) {
+			msgs = append(msgs, fmt.Sprintf("item %d %s", i, msg))
+		}
+	}
+	return msgs
+}
+
+// value resolves the argument of r.
+func (c checker) value(r rule) float64 {
+	if len(r.limit) > 0 {
+		return c.limits[r.limit]
+	}
+	return r.n
+}
+
+func (c checker) apply(v reflect.Value, r rule) string {
+	switch r.name {
+	case "required":
+		if size(v) == 0 {
+			return "is required"
+		}
+	case "max":
+		n := c.value(r)
+		if v.Kind() == reflect.Float64 || v.Kind() == reflect.Int {
+			if number(v) > n {
+				return fmt.Sprintf("must be at most %g", n)
+			}
+		} else if float64(size(v)) > n {
+			return fmt.Sprintf("must have at most %g %s", n, unit(v))
+		}
+	case "min":
+		n := c.value(r)
+		if v.Kind() == reflect.Float64 || v.Kind() == reflect.Int {
+			if number(v) < n {
+				return fmt.Sprintf("must be at least %g", n)
+			}
+		} else if float64(size(v)) < n {
+			return fmt.Sprintf("must have at least %g %s", n, unit(v))
+		}
+	case "gt":
+		if n := c.value(r); number(v) <= n {
+			return fmt.Sprintf("must be greater than %g", n)
+		}
+	case "scale":
+		if scaled := number(v) * math.Pow10(c.digits); math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+			return fmt.Sprintf("must have at most %d decimal places for %s", c.digits, c.currency)
+		}
+	}
+	return ""
+}
+func size(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(strings.TrimSpace(v.String()))
+	case reflect.Slice, reflect.Map:
+		return v.Len()
+	case reflect.Float64, reflect.Int:
+		if number(v) == 0 {
+			return 0
+		}
+		return 1
+	}
+	panic("validate: unsupported kind " + v.Kind().String())
+}
+
+func number(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float64:
+		return v.Float()
+	case reflect.Int:
+		return float64(v.Int())
+	}
+	panic("validate: not a number " + v.Kind().String())
+}
+
+func unit(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+// Scale returns the base currency (THB unless SetBaseCurrency changed it)
+// and how many decimal places its amounts may have.
+func Scale() (string, int) {
+	s := current.Load()
+	return s.currency, s.digits
+}
@@ -0,0 +1,82 @@
+//go:build unit
+
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/problem"
+)
+
+type payload struct {
+	Name   string   `json:"name" validate:"required,max=5"`
+	Amount float64  `json:"amount" validate:"gt=0,scale"`
+	Count  *int     `json:"count" validate:"required,min=1"`
+	Tags   []string `json:"tags" validate:"max=tags,each=required|max=3"`
+	Note   string
+}
+
+func TestStruct(t *testing.T) {
+	zero := 0
+	errs := Struct(&payload{Name: "ยาวเกินไป", Amount: 0.001, Count: &zero, Tags: []string{"ok", "", "long"}})
+
+	assert.Equal(t, []problem.FieldError{
+		{Field: "name", Message: "must have at most 5 characters"},
+		{Field: "amount", Message: "must have at most 2 decimal places for THB"},
+		{Field: "count", Message: "must be at least 1"},
+		{Field: "tags", Message: "item 1 is required"},
+		{Field: "tags", Message: "item 2 must have at most 3 characters"},
+	}, errs)
+}
+
+func TestStruct_Valid(t *testing.T) {
+	one := 1
+	assert.Empty(t, Struct(&payload{Name: "ชา", Amount: 12.5, Count: &one, Tags: []string{"tea"}}))
+}
+
+func TestStruct_RequiredPointer(t *testing.T) {
+	errs := Struct(&payload{Name: "a", Amount: 1})
+
+	assert.Equal(t, []problem.FieldError{{Field: "count", Message: "is required"}}, errs)
+}
+
+func TestLimits_Tighten(t *testing.T) {
+	l, err := DefaultLimits().Tighten([]string{"title=100", " tags = 5"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, l["title"])
+	assert.Equal(t, 5.0, l["tags"])
+	assert.Equal(t, 2000.0, l["note"])
+	assert.Equal(t, 200.0, DefaultLimits()["title"])
+
+	for _, bad := range []string{"note=abc", "title", "colour=5", "title=300", "tags=-1"} {
+		_, err := DefaultLimits().Tighten([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestStructFor_UsesWorkspaceLimits(t *testing.T) {
+	global, _ := DefaultLimits().Tighten([]string{"tags=2"})
+	alice, _ := global.Tighten([]string{"tags=1"})
+	SetLimits(global, map[string]Limits{"alice": alice})
+	t.Cleanup(func() { SetLimits(DefaultLimits(), nil) })
+	one := 1
+	p := &payload{Name: "a", Amount: 1, Count: &one, Tags: []string{"a", "b"}}
+
+	assert.Empty(t, StructFor("bob", p))
+	assert.Equal(t, []problem.FieldError{{Field: "tags", Message: "must have at most 1 items"}}, StructFor("alice", p))
+}
+
+func TestScale(t *testing.T) {
+	SetBaseCurrency("jpy")
+	t.Cleanup(func() { SetBaseCurrency("") })
+
+	currency, digits := Scale()
+	assert.Equal(t, "JPY", currency)
+	assert.Equal(t, 0, digits)
+	assert.Equal(t, []problem.FieldError{{Field: "amount", Message: "must have at most 0 decimal places for JPY"}},
+		Struct(&struct {
+			Amount float64 `json:"amount" validate:"scale"`
+		}{Amount: 1.5}))
+}
@@ -0,0 +1,46 @@
+package view
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+func CreateViewHandler(c echo.Context) error {
+	v := View{}
+	err := c.Bind(&v)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	v.Owner = auth.User(c)
+	if errs := v.validate(); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if v.Pinned {
+			if _, err := tx.ExecContext(ctx, "UPDATE views SET pinned = false WHERE owner = $1 AND pinned", v.Owner); err != nil {
+				return err
+			}
+		}
+
+		row := tx.QueryRowContext(ctx, "INSERT INTO views (owner, name, filter, sort, columns, currency, shared, pinned) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+			v.Owner, v.Name, v.Filter, v.Sort, pq.Array(v.Columns), v.Currency, v.Shared, v.Pinned)
+		return row.Scan(&v.Id)
+	})
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, v)
+}
@@ -0,0 +1,61 @@
+//go:build unit
+
+package view
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestCreateView_ReturnUnprocessableEntity_WhenFilterInvalid(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "food", "filter": "amount >"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CreateViewHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestCreateView_UnpinsOtherViews_WhenPinned(t *testing.T) {
+	e := echo.New()
+	body := `{"name": "food", "filter": "tag:food", "sort": "-amount", "pinned": true}`
+	req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, "alice")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	defer db.Close()
+
+	database.Db = db
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE views SET pinned = false").WithArgs("alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO views").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	err = CreateViewHandler(c)
+
+	expected := "{\"id\":2,\"owner\":\"alice\",\"name\":\"food\",\"filter\":\"tag:food\",\"sort\":\"-amount\",\"columns\":[],\"currency\":\"\",\"shared\":false,\"pinned\":true}"
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
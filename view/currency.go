@@ -0,0 +1,54 @@
+package view
+
+import (
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/umateedev/assessment/validate"
+)
+
+type currencies struct {
+	base  string
+	rates map[string]float64
+}
+
+var current atomic.Pointer[currencies]
+
+// SetCurrencies sets the base currency amounts are stored in, THB if
+// empty, and how much one unit of it is worth in other currencies, keyed
+// by upper case code, e.g. {"USD": 0.028, "EUR": 0.026}.
+func SetCurrencies(base string, rates map[string]float64) {
+	if len(base) == 0 {
+		base = "THB"
+	}
+	current.Store(&currencies{base: base, rates: rates})
+}
+
+func init() {
+	SetCurrencies("", nil)
+}
+
+func rate(currency string) (float64, bool) {
+	c := current.Load()
+	if strings.EqualFold(currency, c.base) {
+		return 1, true
+	}
+	r, ok := c.rates[strings.ToUpper(currency)]
+	return r, ok
+}
+
+// Convert converts an amount in the base currency to currency and rounds
+// it to the minor unit of currency, e.g. whole yen. It reports false, and
+// returns amount unchanged, when currency is empty or has no rate.
+func Convert(amount float64, currency string) (float64, bool) {
+	if len(currency) == 0 {
+		return amount, false
+	}
+	r, ok := rate(currency)
+	if !ok {
+		return amount, false
+	}
+	scale := math.Pow10(validate.Digits(strings.ToUpper(currency)))
+	return math.Round(amount*r*scale) / scale, true
+}
@@ -0,0 +1,49 @@
+package view
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// GetAllViewHandler lists the caller's views and the views shared with them.
+func GetAllViewHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, selectView+" WHERE owner = $1 OR shared ORDER BY id", auth.User(c))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	views := []View{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		views = append(views, v)
+	}
+
+	return c.JSON(http.StatusOK, views)
+}
+
+func GetViewByIdHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	v, err := Get(ctx, c.Param("id"), auth.User(c))
+	switch err {
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "view not found")
+	case nil:
+		return c.JSON(http.StatusOK, v)
+	default:
+		return problem.Internal(c, err)
+	}
+}
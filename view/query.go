@@ -0,0 +1,82 @@
+package view
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/filter"
+)
+
+const selectView = "SELECT id, owner, name, filter, sort, columns, currency, shared, pinned FROM views"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanView(s scanner) (View, error) {
+	v := View{}
+	err := s.Scan(&v.Id, &v.Owner, &v.Name, &v.Filter, &v.Sort, pq.Array(&v.Columns), &v.Currency, &v.Shared, &v.Pinned)
+	if v.Columns == nil {
+		v.Columns = []string{}
+	}
+	return v, err
+}
+
+// Get returns a view the user owns or that is shared.
+func Get(ctx context.Context, id, user string) (View, error) {
+	return scanView(database.Db.QueryRowContext(ctx, selectView+" WHERE id = $1 AND (owner = $2 OR shared)", id, user))
+}
+
+// Pinned returns the pinned view of user, if any.
+func Pinned(ctx context.Context, user string) (*View, error) {
+	rows, err := database.Db.QueryContext(ctx, selectView+" WHERE owner = $1 AND pinned", user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	v, err := scanView(rows)
+	return &v, err
+}
+
+// Query returns the expense query of the view, selecting the given columns.
+func (v View) Query(selectColumns string) (string, []interface{}, error) {
+	query := "SELECT " + selectColumns + " FROM expenses"
+	var args []interface{}
+
+	if len(v.Filter) > 0 {
+		n, err := filter.Parse(v.Filter)
+		if err != nil {
+			return "", nil, err
+		}
+		var where string
+		where, args = filter.Compile(n, "", nil)
+		query += " WHERE " + where
+	}
+
+	order, err := orderBy(v.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+	return query + " ORDER BY " + order, args, nil
+}
+
+// Project keeps only the view's columns of an expense encoded as a map.
+// A view without columns keeps everything.
+func (v View) Project(row map[string]interface{}) map[string]interface{} {
+	if len(v.Columns) == 0 {
+		return row
+	}
+
+	projected := map[string]interface{}{}
+	for _, col := range v.Columns {
+		if value, ok := row[col]; ok {
+			projected[col] = value
+		}
+	}
+	return projected
+}
@@ -0,0 +1,65 @@
+package view
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+// UpdateViewHandler replaces a view. Only its owner can change it.
+func UpdateViewHandler(c echo.Context) error {
+	v := View{}
+	err := c.Bind(&v)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+
+	v.Owner = auth.User(c)
+	if errs := v.validate(); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	err = database.InTx(ctx, func(tx *sql.Tx) error {
+		if v.Pinned {
+			if _, err := tx.ExecContext(ctx, "UPDATE views SET pinned = false WHERE owner = $1 AND pinned AND id <> $2", v.Owner, c.Param("id")); err != nil {
+				return err
+			}
+		}
+
+		row := tx.QueryRowContext(ctx, "UPDATE views SET name = $1, filter = $2, sort = $3, columns = $4, currency = $5, shared = $6, pinned = $7 WHERE id = $8 AND owner = $9 RETURNING id",
+			v.Name, v.Filter, v.Sort, pq.Array(v.Columns), v.Currency, v.Shared, v.Pinned, c.Param("id"), v.Owner)
+		return row.Scan(&v.Id)
+	})
+	switch err {
+	case nil:
+		return c.JSON(http.StatusOK, v)
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "view not found")
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+func DeleteViewHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	res, err := database.Db.ExecContext(ctx, "DELETE FROM views WHERE id = $1 AND owner = $2", c.Param("id"), auth.User(c))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return problem.NotFound(c, "view not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
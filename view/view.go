@@ -0,0 +1,116 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/umateedev/assessment/filter"
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/problem"
+)
+
+var log = logging.For("view")
+
+// View is a saved list query. Shared views are visible to every user; at
+// most one view per user is pinned and applies to GET /expenses when it is
+// called without parameters.
+type View struct {
+	Id       int      `json:"id"`
+	Owner    string   `json:"owner"`
+	Name     string   `json:"name"`
+	Filter   string   `json:"filter"`
+	Sort     string   `json:"sort"`
+	Columns  []string `json:"columns"`
+	Currency string   `json:"currency"`
+	Shared   bool     `json:"shared"`
+	Pinned   bool     `json:"pinned"`
+}
+
+// Columns that can be selected, in the JSON names of an expense.
+var columns = map[string]bool{
+	"id":          true,
+	"title":       true,
+	"amount":      true,
+	"note":        true,
+	"tags":        true,
+	"category_id": true,
+	"rule_ids":    true,
+}
+
+// Sort keys and the column each orders by.
+var sortKeys = map[string]string{
+	"id":       "id",
+	"title":    "title",
+	"amount":   "amount",
+	"note":     "note",
+	"date":     "created_at",
+	"category": "category_id",
+}
+
+// validate normalizes v and reports every field that is invalid.
+func (v *View) validate() []problem.FieldError {
+	var errs []problem.FieldError
+
+	v.Name = strings.TrimSpace(v.Name)
+	if len(v.Name) == 0 {
+		errs = append(errs, problem.FieldError{Field: "name", Message: "is required"})
+	}
+
+	if len(v.Filter) > 0 {
+		if _, err := filter.Parse(v.Filter); err != nil {
+			errs = append(errs, problem.FieldError{Field: "filter", Message: err.Error()})
+		}
+	}
+
+	if _, err := orderBy(v.Sort); err != nil {
+		errs = append(errs, problem.FieldError{Field: "sort", Message: err.Error()})
+	}
+
+	if v.Columns == nil {
+		v.Columns = []string{}
+	}
+	for _, col := range v.Columns {
+		if !columns[col] {
+			errs = append(errs, problem.FieldError{Field: "columns", Message: fmt.Sprintf("unknown column %q", col)})
+		}
+	}
+
+	v.Currency = strings.ToUpper(v.Currency)
+	if len(v.Currency) > 0 {
+		if _, ok := rate(v.Currency); !ok {
+			errs = append(errs, problem.FieldError{Field: "currency", Message: fmt.Sprintf("unknown currency %q", v.Currency)})
+		}
+	}
+	return errs
+}
+
+// orderBy turns "-amount,title" into "amount DESC, title ASC". Ties are
+// always broken by id so pages are stable.
+func orderBy(sort string) (string, error) {
+	var terms []string
+	hasId := false
+
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if len(key) == 0 {
+			continue
+		}
+
+		dir := "ASC"
+		if strings.HasPrefix(key, "-") {
+			dir = "DESC"
+			key = key[1:]
+		}
+		col, ok := sortKeys[key]
+		if !ok {
+			return "", fmt.Errorf("unknown sort key %q", key)
+		}
+		hasId = hasId || col == "id"
+		terms = append(terms, col+" "+dir)
+	}
+
+	if !hasId {
+		terms = append(terms, "id ASC")
+	}
+	return strings.Join(terms, ", "), nil
+}
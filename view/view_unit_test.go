@@ -0,0 +1,82 @@
+//go:build unit
+
+package view
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBy(t *testing.T) {
+	order, err := orderBy("-amount, date")
+	assert.NoError(t, err)
+	assert.Equal(t, "amount DESC, created_at ASC, id ASC", order)
+
+	order, err = orderBy("")
+	assert.NoError(t, err)
+	assert.Equal(t, "id ASC", order)
+
+	_, err = orderBy("amount; DROP TABLE expenses")
+	assert.Error(t, err)
+}
+
+func setRates(t *testing.T, rates map[string]float64) {
+	SetCurrencies("", rates)
+	t.Cleanup(func() { SetCurrencies("", nil) })
+}
+
+func TestValidate(t *testing.T) {
+	setRates(t, map[string]float64{"USD": 0.028})
+
+	for _, v := range []View{
+		{Name: ""},
+		{Name: "food", Filter: "tag:"},
+		{Name: "food", Columns: []string{"password"}},
+		{Name: "food", Currency: "XYZ"},
+		{Name: "food", Sort: "price"},
+	} {
+		assert.NotEmpty(t, v.validate(), v)
+	}
+
+	v := View{Name: " food ", Filter: "tag:food", Sort: "-amount", Columns: []string{"title", "amount"}, Currency: "usd"}
+	assert.Empty(t, v.validate())
+	assert.Equal(t, "food", v.Name)
+	assert.Equal(t, "USD", v.Currency)
+}
+
+func TestConvert(t *testing.T) {
+	setRates(t, map[string]float64{"USD": 0.028, "EUR": 0.026})
+
+	for _, tc := range []struct {
+		currency  string
+		amount    float64
+		converted bool
+	}{
+		{"USD", 2.8, true},
+		{"THB", 100, true},
+		{"", 100, false},
+		{"GBP", 100, false},
+	} {
+		amount, converted := Convert(100, tc.currency)
+		assert.Equal(t, tc.amount, amount, tc.currency)
+		assert.Equal(t, tc.converted, converted, tc.currency)
+	}
+}
+
+func TestConvert_RoundsToTheMinorUnitOfTheCurrency(t *testing.T) {
+	setRates(t, map[string]float64{"JPY": 4.1234, "KWD": 0.0087654})
+
+	amount, _ := Convert(100, "JPY")
+	assert.Equal(t, 412.0, amount)
+	amount, _ = Convert(100, "KWD")
+	assert.Equal(t, 0.877, amount)
+}
+
+func TestProject(t *testing.T) {
+	v := View{Columns: []string{"title", "amount"}}
+
+	row := v.Project(map[string]interface{}{"id": 1, "title": "tea", "amount": 10.0, "note": ""})
+
+	assert.Equal(t, map[string]interface{}{"title": "tea", "amount": 10.0}, row)
+}
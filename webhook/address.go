@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// errPrivateHost is returned for endpoints on loopback, link-local or
+// private networks, which would let anyone who can create a webhook make
+// the server send requests into its own network.
+var errPrivateHost = errors.New("must not be a loopback, link-local or private address")
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// allowed reports whether deliveries may be sent to addr. Tests replace
+// it to deliver to local servers.
+var allowed = publicAddr
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// checkHost rejects a URL host that is a non-public IP address or a name
+// for the local machine. Other names are only resolved when a delivery is
+// sent, and dialControl checks the address they resolve to then.
+func checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateHost
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && !allowed(addr) {
+		return errPrivateHost
+	}
+	return nil
+}
+
+// dialControl runs before every connection the Client makes, after the
+// host was resolved, so a name that resolves to a private address, or is
+// changed to after the webhook was created, is still refused.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !allowed(addrPort.Addr()) {
+		return fmt.Errorf("webhook endpoint %s: %w", addrPort.Addr(), errPrivateHost)
+	}
+	return nil
+}
+
+// dialer connects deliveries, refusing non-public addresses.
+var dialer = &net.Dialer{Control: dialControl}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/umateedev/assessment/database"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is dead.
+	MaxAttempts = 10
+	// batchSize limits how many due deliveries a single run claims.
+	batchSize = 50
+	// lease is how long a claimed delivery is hidden from other
+	// dispatchers; it must exceed the time to send a batch.
+	lease = 10 * time.Minute
+	// firstRetry and maxRetry bound the wait between attempts, which
+	// doubles after each failure.
+	firstRetry = 30 * time.Second
+	maxRetry   = 6 * time.Hour
+)
+
+// Client sends deliveries. Redirects are not followed, so that an endpoint
+// cannot send the signed body elsewhere, and it connects directly, never
+// through a proxy, and only to public addresses.
+var Client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Dispatcher periodically sends due deliveries.
+//
+// Due deliveries are claimed by pushing their next attempt a lease into
+// the future, with SELECT ... FOR UPDATE SKIP LOCKED, so any number of
+// replicas can run a dispatcher without sending an attempt twice.
+type Dispatcher struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewDispatcher(interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			n, err := Deliver(context.Background(), time.Now())
+			if err != nil {
+				log.Error("Webhook dispatcher failed", "error", err)
+			} else if n > 0 {
+				log.Debug("Webhook dispatcher sent deliveries", "count", n)
+			}
+
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the current run to finish.
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+type claimed struct {
+	id       int64
+	attempts int
+	eventId  string
+	event    string
+	payload  string
+	url      string
+	secret   string
+}
+
+// Deliver makes one attempt at every delivery due at now and returns how
+// many it attempted.
+func Deliver(ctx context.Context, now time.Time) (int, error) {
+	due, err := claim(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range due {
+		status, err := send(ctx, d, now)
+		if err := record(ctx, d, now, status, err); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+func claim(ctx context.Context, now time.Time) ([]claimed, error) {
+	ctx, cancel := database.Context(ctx)
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, `UPDATE webhook_deliveries d SET next_attempt_at = $2
+	FROM webhooks w
+	WHERE w.id = d.webhook_id AND d.id IN (
+		SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING d.id, d.attempts, d.event_id, d.event, d.payload, w.url, w.secret`, now, now.Add(lease), batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []claimed
+	for rows.Next() {
+		d := claimed{}
+		if err := rows.Scan(&d.id, &d.attempts, &d.eventId, &d.event, &d.payload, &d.url, &d.secret); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// send posts d and returns the response status.
+func send(ctx context.Context, d claimed, now time.Time) (int, error) {
+	body := []byte(d.payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "expenses-webhook")
+	req.Header.Set("Webhook-Id", d.eventId)
+	req.Header.Set("Webhook-Event", d.event)
+	req.Header.Set("Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("Webhook-Signature", Sign(d.secret, ts, body))
+
+	res, err := Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// record stores the outcome of an attempt and schedules the next one.
+func record(ctx context.Context, d claimed, now time.Time, status int, sendErr error) error {
+	ctx, cancel := database.Context(ctx)
+	defer cancel()
+
+	var responseStatus *int
+	if status > 0 {
+		responseStatus = &status
+	}
+	attempts := d.attempts + 1
+
+	var err error
+	switch {
+	case sendErr == nil:
+		_, err = database.Db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'delivered', attempts = $1, next_attempt_at = NULL,
+		response_status = $2, error = '', delivered_at = $3 WHERE id = $4`, attempts, responseStatus, now, d.id)
+	case attempts >= MaxAttempts:
+		log.WarnContext(ctx, "Webhook delivery is dead", "delivery", d.id, "url", d.url, "error", sendErr)
+		_, err = database.Db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'dead', attempts = $1, next_attempt_at = NULL,
+		response_status = $2, error = $3 WHERE id = $4`, attempts, responseStatus, sendErr.Error(), d.id)
+	default:
+		_, err = database.Db.ExecContext(ctx, `UPDATE webhook_deliveries SET attempts = $1, next_attempt_at = $2,
+		response_status = $3, error = $4 WHERE id = $5`, attempts, now.Add(backoff(attempts)), responseStatus, sendErr.Error(), d.id)
+	}
+	return err
+}
+
+// backoff is the wait after the nth failed attempt.
+func backoff(n int) time.Duration {
+	d := firstRetry
+	for i := 1; i < n && d < maxRetry; i++ {
+		d *= 2
+	}
+	if d > maxRetry {
+		d = maxRetry
+	}
+	return d
+}
@@ -0,0 +1,132 @@
+//go:build unit
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+var claimColumns = []string{"id", "attempts", "event_id", "event", "payload", "url", "secret"}
+
+func mockDb(t *testing.T) sqlmock.Sqlmock {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Open sqlmock error '%s'", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.Db = db
+	return mock
+}
+
+// allowLocal lets deliveries reach httptest servers on loopback.
+func allowLocal(t *testing.T) {
+	allowed = func(netip.Addr) bool { return true }
+	t.Cleanup(func() { allowed = publicAddr })
+}
+
+func TestDeliver_SignsAndRecordsSuccess(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	allowLocal(t)
+
+	mock := mockDb(t)
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET next_attempt_at").
+		WithArgs(now, now.Add(lease), batchSize).
+		WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(7, 0, "e1", ExpenseCreated, `{"id":"e1"}`, srv.URL, "0123456789abcdef"))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = 'delivered'").
+		WithArgs(1, http.StatusNoContent, now, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := Deliver(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, `{"id":"e1"}`, string(body))
+	assert.Equal(t, "e1", got.Header.Get("Webhook-Id"))
+	assert.Equal(t, ExpenseCreated, got.Header.Get("Webhook-Event"))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), got.Header.Get("Webhook-Timestamp"))
+	assert.Equal(t, Sign("0123456789abcdef", now.Unix(), body), got.Header.Get("Webhook-Signature"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliver_RetriesWithBackoff(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	allowLocal(t)
+
+	mock := mockDb(t)
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET next_attempt_at").
+		WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(7, 2, "e1", ExpenseCreated, `{}`, srv.URL, "0123456789abcdef"))
+	mock.ExpectExec("UPDATE webhook_deliveries SET attempts").
+		WithArgs(3, now.Add(2*time.Minute), http.StatusServiceUnavailable, "endpoint answered 503", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := Deliver(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliver_GivesUpAfterMaxAttempts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://elsewhere.example", http.StatusFound)
+	}))
+	defer srv.Close()
+	allowLocal(t)
+
+	mock := mockDb(t)
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET next_attempt_at").
+		WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(7, MaxAttempts-1, "e1", ExpenseCreated, `{}`, srv.URL, "0123456789abcdef"))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = 'dead'").
+		WithArgs(MaxAttempts, http.StatusFound, "endpoint answered 302", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := Deliver(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliver_RefusesPrivateAddresses(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var hit bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	mock := mockDb(t)
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET next_attempt_at").
+		WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(7, 0, "e1", ExpenseCreated, `{}`, srv.URL, "0123456789abcdef"))
+	mock.ExpectExec("UPDATE webhook_deliveries SET attempts").
+		WithArgs(1, now.Add(firstRetry), nil, sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := Deliver(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.False(t, hit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
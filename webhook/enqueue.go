@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/umateedev/assessment/outbox"
+)
+
+// Enqueue queues event with data for every webhook of owner subscribed to
+// it as part of tx, so that deliveries exist exactly when the write that
+// raised the event is committed. An empty owner is a shared expense, whose
+// events go to every subscribed webhook.
+func Enqueue(ctx context.Context, tx *sql.Tx, owner, event string, data interface{}) error {
+	id, err := newId()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Event{Id: id, Type: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, next_attempt_at)
+	SELECT id, $1, $2, $3, now() FROM webhooks WHERE $2 = ANY(events) AND ($4 = '' OR owner = $4)`, id, event, string(payload), owner)
+	return err
+}
+
+// EnqueueChanged queues event, as part of tx, for every expense changed by
+// an outbox.AddFrom statement, with the data of its outbox event.
+func EnqueueChanged(ctx context.Context, tx *sql.Tx, event string, changed []outbox.Changed) error {
+	for _, ch := range changed {
+		if err := Enqueue(ctx, tx, ch.Owner, event, ch.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,37 @@
+//go:build unit
+
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/database"
+)
+
+func TestEnqueue_OnlyReachesWebhooksOfTheOwner(t *testing.T) {
+	mock := mockDb(t)
+	ctx := context.Background()
+	mock.ExpectBegin()
+	for _, owner := range []string{"alice", "bob", ""} {
+		mock.ExpectExec(`INSERT INTO webhook_deliveries (.+) WHERE \$2 = ANY\(events\) AND \(\$4 = '' OR owner = \$4\)`).
+			WithArgs(sqlmock.AnyArg(), ExpenseCreated, sqlmock.AnyArg(), owner).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	err := database.InTx(ctx, func(tx *sql.Tx) error {
+		for _, owner := range []string{"alice", "bob", ""} {
+			if err := Enqueue(ctx, tx, owner, ExpenseCreated, map[string]int{"id": 1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
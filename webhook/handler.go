@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/umateedev/assessment/auth"
+	"github.com/umateedev/assessment/database"
+	"github.com/umateedev/assessment/problem"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+const selectDelivery = `SELECT d.id, d.webhook_id, d.event_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+d.response_status, d.error, d.created_at, d.delivered_at
+FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDelivery(s scanner) (Delivery, error) {
+	d := Delivery{}
+	var payload string
+	err := s.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.Error, &d.CreatedAt, &d.DeliveredAt)
+	d.Payload = []byte(payload)
+	return d, err
+}
+
+// CreateWebhookHandler registers a webhook for the caller. Without a
+// secret one is generated; either way it is only returned here.
+func CreateWebhookHandler(c echo.Context) error {
+	w := Webhook{}
+	err := c.Bind(&w)
+	if err != nil {
+		log.InfoContext(c.Request().Context(), "Invalid request", "error", err)
+		return problem.BadRequest(c, "Invalid request")
+	}
+	if errs := w.validate(); len(errs) > 0 {
+		return problem.Invalid(c, errs...)
+	}
+	if len(w.Secret) == 0 {
+		if w.Secret, err = newId(); err != nil {
+			return problem.Internal(c, err)
+		}
+	}
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	row := database.Db.QueryRowContext(ctx, "INSERT INTO webhooks (owner, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		auth.User(c), w.URL, w.Secret, pq.Array(w.Events))
+	if err := row.Scan(&w.Id, &w.CreatedAt); err != nil {
+		return problem.Internal(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, w)
+}
+
+func GetAllWebhookHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, "SELECT id, url, events, created_at FROM webhooks WHERE owner = $1 ORDER BY id", auth.User(c))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		w := Webhook{}
+		if err := rows.Scan(&w.Id, &w.URL, pq.Array(&w.Events), &w.CreatedAt); err != nil {
+			return problem.Internal(c, err)
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+func GetWebhookByIdHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	w := Webhook{}
+	row := database.Db.QueryRowContext(ctx, "SELECT id, url, events, created_at FROM webhooks WHERE id = $1 AND owner = $2", c.Param("id"), auth.User(c))
+	err := row.Scan(&w.Id, &w.URL, pq.Array(&w.Events), &w.CreatedAt)
+	switch err {
+	case nil:
+		return c.JSON(http.StatusOK, w)
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "webhook not found")
+	default:
+		return problem.Internal(c, err)
+	}
+}
+
+// DeleteWebhookHandler removes a webhook together with its deliveries.
+func DeleteWebhookHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	res, err := database.Db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND owner = $2", c.Param("id"), auth.User(c))
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return problem.Internal(c, err)
+	} else if n == 0 {
+		return problem.NotFound(c, "webhook not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetDeliveriesHandler lists the deliveries of a webhook, newest first,
+// optionally only those with the ?status= given. ?limit= and ?before= page
+// through them by id.
+func GetDeliveriesHandler(c echo.Context) error {
+	return listDeliveries(c, "d.webhook_id = $2", c.Param("id"))
+}
+
+// GetDeadLettersHandler lists the dead deliveries of all of the caller's
+// webhooks, newest first.
+func GetDeadLettersHandler(c echo.Context) error {
+	return listDeliveries(c, "d.status = $2", Dead)
+}
+
+func listDeliveries(c echo.Context, cond string, arg interface{}) error {
+	limit := defaultPageSize
+	if s := c.QueryParam("limit"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			return problem.BadRequest(c, fmt.Sprintf("Invalid request, limit must be between 1 and %d", maxPageSize))
+		}
+		limit = n
+	}
+
+	query := selectDelivery + " WHERE w.owner = $1 AND " + cond
+	args := []interface{}{auth.User(c), arg}
+	switch status := c.QueryParam("status"); status {
+	case "":
+	case Pending, Delivered, Dead:
+		args = append(args, status)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	default:
+		return problem.BadRequest(c, fmt.Sprintf("Invalid request, status must be %s, %s or %s", Pending, Delivered, Dead))
+	}
+	if s := c.QueryParam("before"); len(s) > 0 {
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return problem.BadRequest(c, "Invalid request, before must be a delivery id")
+		}
+		args = append(args, before)
+		query += fmt.Sprintf(" AND d.id < $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d", len(args))
+
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	rows, err := database.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return problem.Internal(c, err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return problem.Internal(c, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverHandler queues a delivery to be sent again right away with a
+// fresh set of attempts, whatever its status. A dead delivery leaves the
+// dead-letter list.
+func RedeliverHandler(c echo.Context) error {
+	ctx, cancel := database.Context(c.Request().Context())
+	defer cancel()
+
+	row := database.Db.QueryRowContext(ctx, `UPDATE webhook_deliveries d SET status = 'pending', attempts = 0, next_attempt_at = now(), error = ''
+	FROM webhooks w
+	WHERE w.id = d.webhook_id AND d.id = $1 AND d.webhook_id = $2 AND w.owner = $3
+	RETURNING d.id, d.webhook_id, d.event_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.response_status, d.error, d.created_at, d.delivered_at`, c.Param("deliveryId"), c.Param("id"), auth.User(c))
+	d, err := scanDelivery(row)
+	switch err {
+	case nil:
+		return c.JSON(http.StatusAccepted, d)
+	case sql.ErrNoRows:
+		return problem.NotFound(c, "delivery not found")
+	default:
+		return problem.Internal(c, err)
+	}
+}
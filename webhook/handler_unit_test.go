@@ -0,0 +1,107 @@
+//go:build unit
+
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/auth"
+)
+
+func TestCreateWebhook_ReturnUnprocessableEntity_WhenInvalid(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "hooks.example", "events": []}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := CreateWebhookHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestCreateWebhook_GeneratesSecret(t *testing.T) {
+	e := echo.New()
+	body := `{"url": "https://hooks.example/expenses", "events": ["expense.created"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, "alice")
+
+	mock := mockDb(t)
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("INSERT INTO webhooks").
+		WithArgs("alice", "https://hooks.example/expenses", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, created))
+
+	err := CreateWebhookHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		w := Webhook{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &w))
+		assert.Equal(t, 3, w.Id)
+		assert.Len(t, w.Secret, 32)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetDeliveries_FiltersByStatus(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/3/deliveries?status=dead&limit=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+	auth.SetUser(c, "alice")
+
+	mock := mockDb(t)
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WHERE w.owner = \$1 AND d.webhook_id = \$2 AND d.status = \$3 ORDER BY d.id DESC LIMIT \$4`).
+		WithArgs("alice", "3", Dead, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event", "payload", "status", "attempts", "next_attempt_at",
+			"response_status", "error", "created_at", "delivered_at"}).
+			AddRow(9, 3, "e1", ExpenseDeleted, `{"id":"e1"}`, Dead, MaxAttempts, nil, 500, "endpoint answered 500", created, nil))
+
+	err := GetDeliveriesHandler(c)
+
+	expected := `[{"id":9,"webhook_id":3,"event_id":"e1","event":"expense.deleted","payload":{"id":"e1"},"status":"dead","attempts":10,"response_status":500,"error":"endpoint answered 500","created_at":"2023-01-01T00:00:00Z"}]`
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, strings.TrimSpace(rec.Body.String()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestRedeliver_ReturnNotFound_WhenNotOwned(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "deliveryId")
+	c.SetParamValues("3", "9")
+	auth.SetUser(c, "bob")
+
+	mock := mockDb(t)
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET status = 'pending'").
+		WithArgs("9", "3", "bob").
+		WillReturnError(sql.ErrNoRows)
+
+	err := RedeliverHandler(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,128 @@
+// Package webhook notifies registered endpoints of expense and budget
+// events.
+//
+// Events are written to webhook_deliveries, one row per subscribed
+// webhook, in the transaction of the change that raised them, and a
+// Dispatcher posts them. Each request carries the headers
+//
+//	Webhook-Id:        the event id, the same for every attempt
+//	Webhook-Event:     the event type, e.g. expense.created
+//	Webhook-Timestamp: the Unix time of the attempt
+//	Webhook-Signature: sha256=<hex HMAC-SHA256 of "timestamp.body" keyed by the secret>
+//
+// so that receivers can check the request came from us and is recent.
+// Endpoints must be on public addresses; loopback, link-local and private
+// ones are refused both when a webhook is created and when it is called.
+// Failed attempts are retried with exponential backoff; after MaxAttempts
+// the delivery is dead and stays in the dead-letter list until it is
+// redelivered.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/umateedev/assessment/logging"
+	"github.com/umateedev/assessment/problem"
+)
+
+var log = logging.For("webhook")
+
+// Events.
+const (
+	ExpenseCreated = "expense.created"
+	ExpenseUpdated = "expense.updated"
+	ExpenseDeleted = "expense.deleted"
+	BudgetExceeded = "budget.exceeded"
+)
+
+var events = map[string]bool{
+	ExpenseCreated: true,
+	ExpenseUpdated: true,
+	ExpenseDeleted: true,
+	BudgetExceeded: true,
+}
+
+// Delivery statuses.
+const (
+	Pending   = "pending"
+	Delivered = "delivered"
+	Dead      = "dead"
+)
+
+// minSecretLength is the shortest secret accepted from a client.
+const minSecretLength = 16
+
+// Webhook is an endpoint subscribed to some events. The secret is only
+// returned when the webhook is created.
+type Webhook struct {
+	Id        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery is one event on its way to one webhook.
+type Delivery struct {
+	Id             int64           `json:"id"`
+	WebhookId      int             `json:"webhook_id"`
+	EventId        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Event is the body of a delivery.
+type Event struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// validate reports every field of w that is invalid.
+func (w *Webhook) validate() []problem.FieldError {
+	var errs []problem.FieldError
+
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errs = append(errs, problem.FieldError{Field: "url", Message: "must be an http or https URL"})
+	} else if err := checkHost(u.Hostname()); err != nil {
+		errs = append(errs, problem.FieldError{Field: "url", Message: err.Error()})
+	}
+
+	if len(w.Events) == 0 {
+		errs = append(errs, problem.FieldError{Field: "events", Message: "is required"})
+	}
+	for _, e := range w.Events {
+		if !events[e] {
+			errs = append(errs, problem.FieldError{Field: "events", Message: "unknown event " + strconv.Quote(e)})
+		}
+	}
+
+	if len(w.Secret) > 0 && len(w.Secret) < minSecretLength {
+		errs = append(errs, problem.FieldError{Field: "secret", Message: "must be at least " + strconv.Itoa(minSecretLength) + " characters"})
+	}
+	return errs
+}
+
+// Sign returns the Webhook-Signature of body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,58 @@
+//go:build unit
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umateedev/assessment/problem"
+)
+
+func TestSign(t *testing.T) {
+	sig := Sign("0123456789abcdef", 1700000000, []byte(`{"id":"e1"}`))
+
+	assert.Equal(t, "sha256=35709ca2ecce8f3d15c806503243fd398425fec4a2629cd90ef9a7a8e7ce56b3", sig)
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	w := Webhook{URL: "https://hooks.example/expenses", Events: []string{ExpenseCreated, BudgetExceeded}}
+	assert.Empty(t, w.validate())
+
+	w = Webhook{URL: "ftp://hooks.example", Events: []string{"expense.archived"}, Secret: "short"}
+	fields := []string{}
+	for _, e := range w.validate() {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"url", "events", "secret"}, fields)
+}
+
+func TestWebhook_ValidateRejectsPrivateHosts(t *testing.T) {
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://api.localhost./hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://100.64.0.1/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		w := Webhook{URL: u, Events: []string{ExpenseCreated}}
+		assert.Equal(t, []problem.FieldError{{Field: "url", Message: errPrivateHost.Error()}}, w.validate(), u)
+	}
+
+	w := Webhook{URL: "http://93.184.216.34/hook", Events: []string{ExpenseCreated}}
+	assert.Empty(t, w.validate())
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 4*time.Minute, backoff(4))
+	assert.Equal(t, maxRetry, backoff(20))
+}